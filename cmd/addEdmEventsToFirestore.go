@@ -2,7 +2,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"slices"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"google.golang.org/api/iterator"
@@ -11,6 +15,7 @@ import (
 type SnippetModelInterface interface {
 	InsertMany(edmEvents []EdmEvent) error
 	DeleteMany(edmEvents []EdmEvent) error
+	GetAll() ([]EdmEvent, error)
 }
 
 // SnippetModel Define a SnippetModel type which wraps a Firestore client.
@@ -55,6 +60,32 @@ func (m *SnippetModel) DeleteMany(edmEvents []EdmEvent) error {
 	return nil
 }
 
+func (m *SnippetModel) GetAll() ([]EdmEvent, error) {
+	ctx := context.Background()
+	edmEvents := []EdmEvent{}
+
+	iter := m.Client.Collection(m.Collection).Documents(ctx)
+	defer iter.Stop()
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate documents: %v", err)
+		}
+
+		var edmEvent EdmEvent
+		if err := doc.DataTo(&edmEvent); err != nil {
+			return nil, fmt.Errorf("failed to decode document %s: %v", doc.Ref.ID, err)
+		}
+		edmEvents = append(edmEvents, edmEvent)
+	}
+
+	return edmEvents, nil
+}
+
 func (m *SnippetModel) InsertMany(edmEvents []EdmEvent) error {
 	ctx := context.Background()
 
@@ -74,18 +105,119 @@ func (m *SnippetModel) InsertMany(edmEvents []EdmEvent) error {
 	return nil
 }
 
-func (app *application) addEdmEventsToFirestore(ScrapingURLs ScrapingURLs) {
-	edmEvents := getEdmEventsFromAllLasVegas(ScrapingURLs)
+// addEdmEventsToFirestore scrapes every source as the scheduled job, records the run in the
+// run history and writes its report out. It fails when the sync does, sources that can't be
+// scraped are only reported.
+func (app *application) addEdmEventsToFirestore(out io.Writer) error {
+	run, err := app.runs.start(allSources, runTriggerJob, time.Now())
+	if err != nil {
+		return err
+	}
+
+	app.executeRun(run)
+
+	finished, err := app.findRun(run.ID)
+	if err != nil {
+		return err
+	}
+	writeRunReport(out, finished)
+
+	if finished.Status == runStatusFailed {
+		return errors.New(finished.Error)
+	}
+
+	app.logger.Print("Successfully scraped data and updated Firestore")
+	return nil
+}
+
+// syncSources scrapes the given sources and replaces their stored events with the result.
+// The events of every other source, and of sources that could not be scraped at all, are
+// kept as they are. onScraped is passed on to the scrapers to report progress.
+func (app *application) syncSources(ScrapingURLs ScrapingURLs, sources []string, onScraped func(source string, edmEvents []EdmEvent, stats scrapeStats, err error)) (syncChanges, error) {
+	edmEvents, scrapeErrors := getEdmEventsFromSources(ScrapingURLs, sources, onScraped)
+	for source, err := range scrapeErrors {
+		app.logger.Printf("Source %s could not be scraped, keeping its stored events: %v", source, err)
+	}
 
-	err := app.dbSnippets.DeleteMany(edmEvents)
+	// Keep the ids and sequence numbers of events we already know about, calendar
+	// subscribers rely on them to recognise an event they have seen before.
+	existingEdmEvents, err := app.dbSnippets.GetAll()
 	if err != nil {
-		app.logger.Fatalf("Error deleting documents from Firestore: %v", err)
+		return syncChanges{}, fmt.Errorf("error reading documents from Firestore: %v", err)
+	}
+
+	previousSourceSyncs, err := app.dbSyncStates.GetAll()
+	if err != nil {
+		return syncChanges{}, fmt.Errorf("error reading the sync states from Firestore: %v", err)
+	}
+
+	// A source whose selectors broke returns few or no events rather than an error, it is
+	// treated as a failed scrape so that its events aren't wiped either, until it has been
+	// flagged enough runs in a row for its new count to be accepted.
+	anomalies := detectSourceAnomalies(previousSourceSyncs, edmEvents, scrapeErrors, sources, app.config.anomaly.threshold, app.config.anomaly.acceptAfter)
+	for _, anomaly := range anomalies {
+		if anomaly.Accepted {
+			continue
+		}
+		scrapeErrors[anomaly.Source] = anomaly
+		edmEvents = slices.DeleteFunc(edmEvents, func(e EdmEvent) bool { return e.Source == anomaly.Source })
+	}
+
+	// A source that could not be scraped at all would otherwise have its events wiped.
+	keptSources := make(map[string]bool)
+	for source := range scrapeErrors {
+		keptSources[source] = true
+	}
+	for _, source := range allSources {
+		if !slices.Contains(sources, source) {
+			// Events stored before they were tagged with a source may belong to any of them.
+			keptSources[source] = true
+			keptSources[""] = true
+		}
+	}
+	edmEvents = append(edmEvents, edmEventsFromSources(existingEdmEvents, keptSources)...)
+
+	syncedAt := time.Now()
+	edmEvents, changes := mergeEdmEvents(existingEdmEvents, edmEvents, syncedAt)
+	changes.Anomalies = anomalies
+
+	err = app.dbSnippets.DeleteMany(edmEvents)
+	if err != nil {
+		return syncChanges{}, fmt.Errorf("error deleting documents from Firestore: %v", err)
 	}
 
 	err = app.dbSnippets.InsertMany(edmEvents)
 	if err != nil {
-		app.logger.Fatalf("Error inserting documents to Firestore: %v", err)
+		return syncChanges{}, fmt.Errorf("error inserting documents to Firestore: %v", err)
 	}
 
-	app.logger.Print("Successfully scraped data and updated Firestore")
+	// Recording the sync is what tells the API's HTTP caches that the data changed.
+	sourceSyncs := sourceSyncsAfterRun(previousSourceSyncs, edmEvents, scrapeErrors, anomalies, sources, syncedAt.UTC().Format(time.RFC3339))
+	err = app.dbSyncStates.Upsert(sourceSyncs)
+	if err != nil {
+		return syncChanges{}, fmt.Errorf("error recording the sync in Firestore: %v", err)
+	}
+
+	// The sync itself succeeded, failing to alert or notify is logged rather than failing the run.
+	app.alertOps(changes.Anomalies)
+
+	alerts, err := app.alertWatchlists(changes.Created, changes.SoldOut)
+	if err != nil {
+		app.logger.Printf("error alerting watchlists: %v", err)
+	}
+	if len(alerts) > 0 {
+		app.logger.Printf("Raised %d watchlist alerts", len(alerts))
+	}
+
+	err = app.notifyWebhooks(changes)
+	if err != nil {
+		app.logger.Printf("error notifying webhooks: %v", err)
+	}
+
+	err = app.notifyChatChannels(changes.Created)
+	if err != nil {
+		app.logger.Printf("error posting to chat channels: %v", err)
+	}
+
+	return changes, nil
 }
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// The states of a scrape run and of each source within it.
+const (
+	runStatusRunning   = "running"
+	runStatusSucceeded = "succeeded"
+	runStatusFailed    = "failed"
+
+	sourceRunPending = "pending"
+	sourceRunScraped = "scraped"
+	sourceRunFailed  = "failed"
+	sourceRunFlagged = "flagged"
+
+	runTriggerJob   = "job"
+	runTriggerAdmin = "admin"
+)
+
+// Runs are kept in memory, the oldest finished runs are forgotten past this many.
+const maxRunHistory = 50
+
+var errRunInProgress = errors.New("a scrape run is already in progress")
+
+// scrapeRun is a scrape run by the scheduled job or started from the admin API, along with its
+// progress. Finished runs are stored as the run history. ExitStatus is set once the run has
+// finished, 0 when it synced and 1 when it failed, as the job exits with.
+type scrapeRun struct {
+	ID         string            `json:"id"`
+	Trigger    string            `json:"trigger"`
+	Status     string            `json:"status"`
+	ExitStatus *int              `json:"exit_status,omitempty"`
+	StartedAt  string            `json:"started_at"`
+	FinishedAt string            `json:"finished_at,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	Sources    []sourceRunReport `json:"sources"`
+	HTTPErrors map[string]int    `json:"http_errors,omitempty"`
+	Created    int               `json:"created"`
+	Updated    int               `json:"updated"`
+	Removed    int               `json:"removed"`
+
+	done chan struct{}
+}
+
+// sourceRunReport is the progress of one source within a run, and what its scraper counted
+// once it has been scraped.
+type sourceRunReport struct {
+	Source     string      `json:"source"`
+	Status     string      `json:"status"`
+	EventCount int         `json:"event_count"`
+	Stats      scrapeStats `json:"stats"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// runManager keeps the recent runs and makes sure only one of them syncs at a time.
+type runManager struct {
+	mu      sync.Mutex
+	running string
+	runs    map[string]*scrapeRun
+	order   []string
+}
+
+func newRunManager() *runManager {
+	return &runManager{runs: make(map[string]*scrapeRun)}
+}
+
+// start registers a new run of the sources, unless another one is still running.
+func (m *runManager) start(sources []string, trigger string, now time.Time) (scrapeRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.running != "" {
+		return m.snapshot(m.runs[m.running]), errRunInProgress
+	}
+
+	run := &scrapeRun{
+		ID:        getGUID(),
+		Trigger:   trigger,
+		Status:    runStatusRunning,
+		StartedAt: now.UTC().Format(time.RFC3339),
+		Sources:   make([]sourceRunReport, 0, len(sources)),
+		done:      make(chan struct{}),
+	}
+	for _, source := range sources {
+		run.Sources = append(run.Sources, sourceRunReport{Source: source, Status: sourceRunPending})
+	}
+
+	m.running = run.ID
+	m.runs[run.ID] = run
+	m.order = append(m.order, run.ID)
+
+	for len(m.order) > maxRunHistory {
+		delete(m.runs, m.order[0])
+		m.order = m.order[1:]
+	}
+
+	return m.snapshot(run), nil
+}
+
+// update applies fn to the run while holding the lock.
+func (m *runManager) update(id string, fn func(run *scrapeRun)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if run, ok := m.runs[id]; ok {
+		fn(run)
+	}
+}
+
+// finish records the outcome of the run, hands the finished run to record when it isn't nil,
+// and then lets the next one start.
+func (m *runManager) finish(id string, changes syncChanges, err error, now time.Time, record func(run scrapeRun)) {
+	m.mu.Lock()
+	run, ok := m.runs[id]
+	if !ok {
+		m.mu.Unlock()
+		return
+	}
+
+	run.FinishedAt = now.UTC().Format(time.RFC3339)
+	run.Created, run.Updated, run.Removed = len(changes.Created), len(changes.Updated), len(changes.Removed)
+	run.Status = runStatusSucceeded
+	exitStatus := 0
+	if err != nil {
+		run.Status = runStatusFailed
+		run.Error = err.Error()
+		exitStatus = 1
+	}
+	run.ExitStatus = &exitStatus
+
+	for i, report := range run.Sources {
+		for name, count := range report.Stats.HTTPErrors {
+			if run.HTTPErrors == nil {
+				run.HTTPErrors = make(map[string]int)
+			}
+			run.HTTPErrors[name] += count
+		}
+		for _, anomaly := range changes.Anomalies {
+			if anomaly.Source == report.Source && !anomaly.Accepted {
+				run.Sources[i].Status = sourceRunFlagged
+				run.Sources[i].Error = anomaly.Error()
+			}
+		}
+	}
+	finished := m.snapshot(run)
+	m.mu.Unlock()
+
+	// Recording outside the lock keeps the run readable meanwhile, the next run still waits.
+	if record != nil {
+		record(finished)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.running == id {
+		m.running = ""
+	}
+	close(run.done)
+}
+
+func (m *runManager) get(id string) (scrapeRun, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	run, ok := m.runs[id]
+	if !ok {
+		return scrapeRun{}, false
+	}
+	return m.snapshot(run), true
+}
+
+// list returns the recent runs, most recent first.
+func (m *runManager) list() []scrapeRun {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	runs := make([]scrapeRun, 0, len(m.order))
+	for i := len(m.order) - 1; i >= 0; i-- {
+		runs = append(runs, m.snapshot(m.runs[m.order[i]]))
+	}
+	return runs
+}
+
+// snapshot copies the run so that it can be encoded without holding the lock.
+func (m *runManager) snapshot(run *scrapeRun) scrapeRun {
+	s := *run
+	s.Sources = slices.Clone(run.Sources)
+	s.HTTPErrors = maps.Clone(run.HTTPErrors)
+	return s
+}
+
+// executeRun scrapes and syncs the run's sources, recording the progress of each of them, and
+// stores the finished run in the run history.
+func (app *application) executeRun(run scrapeRun) {
+	sources := make([]string, 0, len(run.Sources))
+	for _, report := range run.Sources {
+		sources = append(sources, report.Source)
+	}
+
+	onScraped := func(source string, edmEvents []EdmEvent, stats scrapeStats, err error) {
+		app.runs.update(run.ID, func(r *scrapeRun) {
+			for i := range r.Sources {
+				if r.Sources[i].Source != source {
+					continue
+				}
+				r.Sources[i].Status = sourceRunScraped
+				r.Sources[i].EventCount = len(edmEvents)
+				r.Sources[i].Stats = stats
+				if err != nil {
+					r.Sources[i].Status = sourceRunFailed
+					r.Sources[i].Error = err.Error()
+				}
+			}
+		})
+	}
+
+	changes, err := app.syncSources(app.scrapingURLs, sources, onScraped)
+	if err != nil {
+		app.logger.Printf("scrape run %s failed: %v", run.ID, err)
+	} else {
+		app.responseCache.invalidate()
+		app.notifySync()
+	}
+
+	app.runs.finish(run.ID, changes, err, time.Now(), func(finished scrapeRun) {
+		err := app.dbRuns.Insert(finished)
+		if err != nil {
+			app.logger.Printf("error recording scrape run %s: %v", run.ID, err)
+		}
+	})
+}
+
+func (app *application) createRunHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Sources []string `json:"sources"`
+	}
+
+	if r.ContentLength != 0 {
+		err := app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	v := newValidator()
+	for _, source := range input.Sources {
+		v.Check(slices.Contains(allSources, source), "sources", fmt.Sprintf("%q is not one of %v", source, allSources))
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	sources := allSources
+	if len(input.Sources) > 0 {
+		sources = []string{}
+		for _, source := range allSources {
+			if slices.Contains(input.Sources, source) {
+				sources = append(sources, source)
+			}
+		}
+	}
+
+	run, err := app.runs.start(sources, runTriggerAdmin, time.Now())
+	if errors.Is(err, errRunInProgress) {
+		app.runInProgressResponse(w, r, run)
+		return
+	}
+
+	app.background(func() {
+		app.executeRun(run)
+	})
+
+	headers := make(http.Header)
+	headers.Set("Location", "/v1/admin/runs/"+run.ID)
+
+	err = app.writeJSON(w, http.StatusAccepted, envelope{"run": run}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showRunHandler(w http.ResponseWriter, r *http.Request) {
+	run, err := app.findRun(r.PathValue("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, errRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"run": run}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listRunsHandler lists the run history, most recent first, for inspecting how the counts of
+// each source trend over time.
+func (app *application) listRunsHandler(w http.ResponseWriter, r *http.Request) {
+	v := newValidator()
+	limit := app.readInt(r.URL.Query(), "limit", defaultRunHistoryLimit, v)
+	v.Check(limit >= 1 && limit <= maxRunHistoryLimit, "limit", fmt.Sprintf("must be between 1 and %d", maxRunHistoryLimit))
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	runs, err := app.runHistory(limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"runs": runs}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newAdminRunsTestApplication returns a test application with an admin and a regular key,
+// whose sources all answer 503.
+func newAdminRunsTestApplication(t *testing.T) (app *application, adminKey string, regularKey string) {
+	t.Helper()
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(down.Close)
+
+	now := time.Now()
+	adminKey, admin, err := generateAPIKey("admin", 5, 20, true, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	regularKey, regular, err := generateAPIKey("tester", 5, 20, false, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app = newTestApplication(t, calendarTestEvents)
+	app.dbAPIKeys = &mockAPIKeyModel{apiKeys: []APIKey{admin, regular}}
+	app.scrapingURLs = ScrapingURLs{
+		Wynn:                down.URL,
+		Zouk:                down.URL + "?date=",
+		TaoGroupHospitality: down.URL + "?",
+		Liv:                 down.URL + "?date=",
+	}
+
+	return app, adminKey, regularKey
+}
+
+func adminRequest(t *testing.T, app *application, method, path, apiKey, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if apiKey != "" {
+		r.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	app.routes().ServeHTTP(rr, r)
+
+	return rr
+}
+
+// waitForRun blocks until the run has finished.
+func waitForRun(t *testing.T, app *application, id string) {
+	t.Helper()
+
+	app.runs.mu.Lock()
+	run, ok := app.runs.runs[id]
+	app.runs.mu.Unlock()
+	if !ok {
+		t.Fatalf("Expected run %s to exist", id)
+	}
+
+	select {
+	case <-run.done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Expected run %s to finish", id)
+	}
+}
+
+// TestAdminRuns_Permissions tests that only admin keys can use the admin endpoints
+func TestAdminRuns_Permissions(t *testing.T) {
+	app, adminKey, regularKey := newAdminRunsTestApplication(t)
+
+	tests := []struct {
+		name               string
+		method             string
+		apiKey             string
+		expectedStatusCode int
+	}{
+		{name: "No key", method: http.MethodPost, expectedStatusCode: http.StatusUnauthorized},
+		{name: "Invalid key", method: http.MethodPost, apiKey: "edm_unknown", expectedStatusCode: http.StatusUnauthorized},
+		{name: "Regular key", method: http.MethodPost, apiKey: regularKey, expectedStatusCode: http.StatusForbidden},
+		{name: "Regular key listing", method: http.MethodGet, apiKey: regularKey, expectedStatusCode: http.StatusForbidden},
+		{name: "Admin key listing", method: http.MethodGet, apiKey: adminKey, expectedStatusCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := adminRequest(t, app, tt.method, "/v1/admin/runs", tt.apiKey, "")
+			if rr.Code != tt.expectedStatusCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatusCode, rr.Code)
+			}
+		})
+	}
+}
+
+// TestAdminRuns_Create tests starting a run and following its progress
+func TestAdminRuns_Create(t *testing.T) {
+	app, adminKey, _ := newAdminRunsTestApplication(t)
+
+	rr := adminRequest(t, app, http.MethodPost, "/v1/admin/runs", adminKey, `{"sources": ["zouk", "wynn"]}`)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusAccepted, rr.Code, rr.Body.String())
+	}
+
+	var created struct {
+		Run scrapeRun `json:"run"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if rr.Header().Get("Location") != "/v1/admin/runs/"+created.Run.ID {
+		t.Errorf("Expected the Location of run %s, got '%s'", created.Run.ID, rr.Header().Get("Location"))
+	}
+	if len(created.Run.Sources) != 2 || created.Run.Sources[0].Source != sourceWynn || created.Run.Sources[1].Source != sourceZouk {
+		t.Errorf("Expected wynn and zouk to be scraped, got %+v", created.Run.Sources)
+	}
+
+	waitForRun(t, app, created.Run.ID)
+
+	rr = adminRequest(t, app, http.MethodGet, "/v1/admin/runs/"+created.Run.ID, adminKey, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var shown struct {
+		Run scrapeRun `json:"run"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &shown); err != nil {
+		t.Fatal(err)
+	}
+	if shown.Run.Status != runStatusSucceeded || shown.Run.FinishedAt == "" {
+		t.Errorf("Expected the run to have succeeded, got %+v", shown.Run)
+	}
+	for _, report := range shown.Run.Sources {
+		if report.Status != sourceRunFailed || report.Error == "" {
+			t.Errorf("Expected %s to report its scrape error, got %+v", report.Source, report)
+		}
+	}
+
+	model := app.dbSnippets.(*mockSnippetModel)
+	if len(model.edmEvents) != len(calendarTestEvents) {
+		t.Errorf("Expected the stored events to be kept, got %d", len(model.edmEvents))
+	}
+}
+
+// TestAdminRuns_Negative tests requests the admin endpoints reject
+func TestAdminRuns_Negative(t *testing.T) {
+	tests := []struct {
+		name               string
+		method             string
+		path               string
+		body               string
+		expectedStatusCode int
+	}{
+		{name: "Unknown source", method: http.MethodPost, path: "/v1/admin/runs", body: `{"sources": ["hakkasan"]}`, expectedStatusCode: http.StatusUnprocessableEntity},
+		{name: "Malformed body", method: http.MethodPost, path: "/v1/admin/runs", body: `{"sources": "wynn"}`, expectedStatusCode: http.StatusBadRequest},
+		{name: "Unknown run", method: http.MethodGet, path: "/v1/admin/runs/unknown", expectedStatusCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, adminKey, _ := newAdminRunsTestApplication(t)
+
+			rr := adminRequest(t, app, tt.method, tt.path, adminKey, tt.body)
+			if rr.Code != tt.expectedStatusCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatusCode, rr.Code)
+			}
+		})
+	}
+}
+
+// TestAdminRuns_InProgress tests that a second run is refused while one is running
+func TestAdminRuns_InProgress(t *testing.T) {
+	app, adminKey, _ := newAdminRunsTestApplication(t)
+
+	running, err := app.runs.start(allSources, runTriggerAdmin, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := adminRequest(t, app, http.MethodPost, "/v1/admin/runs", adminKey, "")
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("Expected status %d, got %d", http.StatusConflict, rr.Code)
+	}
+	if err := loadOpenAPIDocument(t).validateResponse("/v1/admin/runs", http.MethodPost, rr); err != nil {
+		t.Error(err)
+	}
+	if !bytes.Contains(rr.Body.Bytes(), []byte(running.ID)) {
+		t.Errorf("Expected the response to name run %s, got %s", running.ID, rr.Body.String())
+	}
+
+	app.runs.finish(running.ID, syncChanges{}, nil, time.Now(), nil)
+
+	_, err = app.runs.start(allSources, runTriggerAdmin, time.Now())
+	if err != nil {
+		t.Errorf("Expected a run to start once the previous one finished, got %v", err)
+	}
+}
+
+// TestRunManager_History tests that only the most recent runs are kept
+func TestRunManager_History(t *testing.T) {
+	m := newRunManager()
+
+	var ids []string
+	for range maxRunHistory + 5 {
+		run, err := m.start(allSources, runTriggerAdmin, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.finish(run.ID, syncChanges{}, nil, time.Now(), nil)
+		ids = append(ids, run.ID)
+	}
+
+	if runs := m.list(); len(runs) != maxRunHistory || runs[0].ID != ids[len(ids)-1] {
+		t.Errorf("Expected the %d most recent runs, newest first, got %d", maxRunHistory, len(runs))
+	}
+	if _, ok := m.get(ids[0]); ok {
+		t.Error("Expected the oldest run to be forgotten")
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"slices"
+	"time"
+)
+
+// alertRecipient is an email address along with the alerts of the watchlists that email it.
+type alertRecipient struct {
+	email  string
+	alerts []Alert
+}
+
+// alertRecipients groups the alerts by the email addresses of their watchlists. An alert of
+// two watchlists with the same address is only listed once for it.
+func alertRecipients(alerts []Alert, watchlists []Watchlist) []alertRecipient {
+	watchlistByID := make(map[string]Watchlist, len(watchlists))
+	for _, watchlist := range watchlists {
+		watchlistByID[watchlist.ID] = watchlist
+	}
+
+	recipients := []alertRecipient{}
+	recipientIndex := make(map[string]int)
+
+	for _, alert := range alerts {
+		for _, watchlistID := range alert.WatchlistIDs {
+			email := watchlistByID[watchlistID].Email
+			if email == "" {
+				continue
+			}
+
+			i, ok := recipientIndex[email]
+			if !ok {
+				i = len(recipients)
+				recipientIndex[email] = i
+				recipients = append(recipients, alertRecipient{email: email})
+			}
+
+			if !slices.ContainsFunc(recipients[i].alerts, func(a Alert) bool { return a.ID == alert.ID }) {
+				recipients[i].alerts = append(recipients[i].alerts, alert)
+			}
+		}
+	}
+
+	return recipients
+}
+
+// alertTemplates are the email templates of each type of alert.
+var alertTemplates = map[string]string{
+	alertTypeAnnounced: "alerts.tmpl",
+	alertTypeSoldOut:   "soldOut.tmpl",
+}
+
+// emailAlerts sends every recipient a single email per type listing their new alerts, and
+// records each email in the send log whether it went out or not. Without an SMTP server
+// configured alerts are only kept for /v1/alerts.
+func (app *application) emailAlerts(alerts []Alert, watchlists []Watchlist) {
+	if app.mailer == nil {
+		return
+	}
+
+	for _, alertType := range alertTypes {
+		typed := []Alert{}
+		for _, alert := range alerts {
+			if alert.Type == alertType {
+				typed = append(typed, alert)
+			}
+		}
+		app.emailAlertsWithTemplate(typed, watchlists, alertTemplates[alertType])
+	}
+}
+
+func (app *application) emailAlertsWithTemplate(alerts []Alert, watchlists []Watchlist, file string) {
+	for _, recipient := range alertRecipients(alerts, watchlists) {
+		attempts, err := app.mailer.Send(recipient.email, file, map[string]any{
+			"Alerts": recipient.alerts,
+		})
+
+		entry := SendLogEntry{
+			ID:        getGUID(),
+			Channel:   channelEmail,
+			Recipient: recipient.email,
+			Status:    sendStatusSent,
+			Attempts:  attempts,
+			CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		}
+		for _, alert := range recipient.alerts {
+			entry.AlertIDs = append(entry.AlertIDs, alert.ID)
+		}
+		if err != nil {
+			app.logger.Printf("failed to email %d alerts to %s after %d attempts: %v", len(recipient.alerts), recipient.email, attempts, err)
+			entry.Status = sendStatusFailed
+			entry.Error = err.Error()
+		}
+
+		err = app.dbSendLog.Insert(entry)
+		if err != nil {
+			app.logger.Printf("failed to record email to %s: %v", recipient.email, err)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// The number of successful syncs whose event counts a source's new count is compared with.
+const maxCountHistory = 10
+
+// sourceAnomaly is a source whose scrape came back with far fewer events than it usually has,
+// most likely because its page changed and our selectors no longer match. It is recorded as
+// the source's scrape error so that its previous events are kept. A source that keeps coming
+// back with the lower count most likely did list fewer events, once it has been flagged
+// enough runs in a row the new count is accepted as its usual count instead.
+type sourceAnomaly struct {
+	Source     string `json:"source"`
+	EventCount int    `json:"event_count"`
+	UsualCount int    `json:"usual_count"`
+	Accepted   bool   `json:"accepted,omitempty"`
+}
+
+func (a sourceAnomaly) Error() string {
+	if a.Accepted {
+		return fmt.Sprintf("scraped %d events where it usually has %d, accepted as its new usual count", a.EventCount, a.UsualCount)
+	}
+	return fmt.Sprintf("scraped %d events where it usually has %d, kept its previous events", a.EventCount, a.UsualCount)
+}
+
+// usualEventCount is the median of a source's recent event counts, which a single odd run
+// doesn't move. Sync states recorded before the history was kept only have their last count.
+func usualEventCount(sourceSync SourceSync) int {
+	history := sourceSync.CountHistory
+	if len(history) == 0 {
+		return sourceSync.EventCount
+	}
+
+	sorted := slices.Clone(history)
+	slices.Sort(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+// detectSourceAnomalies compares the number of events scraped from each source with its usual
+// count, and flags the sources that dropped below the threshold, a fraction of the usual
+// count. A drop to zero is always below it. Sources that failed to scrape or never had any
+// events aren't flagged, and a threshold of 0 turns the check off. A source already flagged
+// acceptAfter runs in a row has its anomaly accepted, 0 never accepts one.
+func detectSourceAnomalies(previous []SourceSync, edmEvents []EdmEvent, scrapeErrors map[string]error, sources []string, threshold float64, acceptAfter int) []sourceAnomaly {
+	anomalies := []sourceAnomaly{}
+	if threshold <= 0 {
+		return anomalies
+	}
+
+	counts := make(map[string]int)
+	for _, edmEvent := range edmEvents {
+		counts[edmEvent.Source]++
+	}
+
+	for _, sourceSync := range previous {
+		if !slices.Contains(sources, sourceSync.Source) || scrapeErrors[sourceSync.Source] != nil {
+			continue
+		}
+
+		usual := usualEventCount(sourceSync)
+		count := counts[sourceSync.Source]
+		if usual > 0 && float64(count) < float64(usual)*threshold {
+			accepted := acceptAfter > 0 && sourceSync.FlaggedRuns >= acceptAfter
+			anomalies = append(anomalies, sourceAnomaly{Source: sourceSync.Source, EventCount: count, UsualCount: usual, Accepted: accepted})
+		}
+	}
+
+	return anomalies
+}
+
+// withCountHistory adds the count of a successful sync to the source's history, keeping the
+// most recent ones.
+func withCountHistory(history []int, count int) []int {
+	history = append(slices.Clone(history), count)
+	if len(history) > maxCountHistory {
+		history = history[len(history)-maxCountHistory:]
+	}
+	return history
+}
+
+// alertOps logs the anomalies of a run and emails them to the ops addresses, recording the
+// email in the send log. Without an SMTP server or ops addresses they are only logged and
+// reported in the sources' last error.
+func (app *application) alertOps(anomalies []sourceAnomaly) {
+	if len(anomalies) == 0 {
+		return
+	}
+
+	for _, anomaly := range anomalies {
+		if anomaly.Accepted {
+			app.logger.Printf("Source %s kept scraping fewer events, accepting its new count: %v", anomaly.Source, anomaly)
+			continue
+		}
+		app.logger.Printf("Source %s looks broken, keeping its stored events: %v", anomaly.Source, anomaly)
+	}
+
+	if app.mailer == nil {
+		return
+	}
+
+	for _, recipient := range app.config.ops.emails {
+		attempts, err := app.mailer.Send(recipient, "anomaly.tmpl", map[string]any{
+			"Anomalies":   anomalies,
+			"Percent":     int(app.config.anomaly.threshold * 100),
+			"AcceptAfter": app.config.anomaly.acceptAfter,
+		})
+
+		entry := SendLogEntry{
+			ID:        getGUID(),
+			Channel:   channelEmail,
+			Recipient: recipient,
+			Status:    sendStatusSent,
+			Attempts:  attempts,
+			CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		}
+		if err != nil {
+			app.logger.Printf("failed to email the scrape anomalies to %s after %d attempts: %v", recipient, attempts, err)
+			entry.Status = sendStatusFailed
+			entry.Error = err.Error()
+		}
+
+		err = app.dbSendLog.Insert(entry)
+		if err != nil {
+			app.logger.Printf("failed to record email to %s: %v", recipient, err)
+		}
+	}
+}
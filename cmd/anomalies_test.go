@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// scrapedEvents returns count events scraped from the source.
+func scrapedEvents(source string, count int) []EdmEvent {
+	edmEvents := make([]EdmEvent, 0, count)
+	for i := 0; i < count; i++ {
+		edmEvents = append(edmEvents, EdmEvent{Id: fmt.Sprintf("%s-%d", source, i), ClubName: "xs nightclub", EventDate: fmt.Sprintf("2026-11-%02dT06:00:00Z", i+1), Source: source})
+	}
+	return edmEvents
+}
+
+// TestDetectSourceAnomalies tests flagging sources that scraped far fewer events than usual
+func TestDetectSourceAnomalies(t *testing.T) {
+	tests := []struct {
+		name         string
+		previous     SourceSync
+		scraped      int
+		scrapeErrors map[string]error
+		threshold    float64
+		acceptAfter  int
+		expected     int
+		accepted     bool
+	}{
+		{name: "Usual count", previous: SourceSync{Source: sourceWynn, CountHistory: []int{40, 42, 41}}, scraped: 39, threshold: 0.5, expected: -1},
+		{name: "Drop to zero", previous: SourceSync{Source: sourceWynn, CountHistory: []int{40, 42, 41}}, scraped: 0, threshold: 0.5, expected: 41},
+		{name: "Below the threshold", previous: SourceSync{Source: sourceWynn, CountHistory: []int{40, 42, 41}}, scraped: 12, threshold: 0.5, expected: 41},
+		{name: "Median ignores an odd run", previous: SourceSync{Source: sourceWynn, CountHistory: []int{40, 2, 42, 44}}, scraped: 30, threshold: 0.5, expected: -1},
+		{name: "Last count without a history", previous: SourceSync{Source: sourceWynn, EventCount: 30}, scraped: 0, threshold: 0.5, expected: 30},
+		{name: "Never had events", previous: SourceSync{Source: sourceWynn, CountHistory: []int{0, 0}}, scraped: 0, threshold: 0.5, expected: -1},
+		{name: "Failed scrape", previous: SourceSync{Source: sourceWynn, CountHistory: []int{40}}, scraped: 0, scrapeErrors: map[string]error{sourceWynn: errors.New("Not Found")}, threshold: 0.5, expected: -1},
+		{name: "Not scraped", previous: SourceSync{Source: sourceLiv, CountHistory: []int{40}}, scraped: 0, threshold: 0.5, expected: -1},
+		{name: "Turned off", previous: SourceSync{Source: sourceWynn, CountHistory: []int{40}}, scraped: 0, threshold: 0, expected: -1},
+		{name: "Flagged fewer runs than accepted after", previous: SourceSync{Source: sourceWynn, CountHistory: []int{40}, FlaggedRuns: 2}, scraped: 10, threshold: 0.5, acceptAfter: 3, expected: 40},
+		{name: "Accepted after flagged runs", previous: SourceSync{Source: sourceWynn, CountHistory: []int{40}, FlaggedRuns: 3}, scraped: 10, threshold: 0.5, acceptAfter: 3, expected: 40, accepted: true},
+		{name: "Never accepted", previous: SourceSync{Source: sourceWynn, CountHistory: []int{40}, FlaggedRuns: 30}, scraped: 10, threshold: 0.5, expected: 40},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edmEvents := scrapedEvents(sourceWynn, tt.scraped)
+			anomalies := detectSourceAnomalies([]SourceSync{tt.previous}, edmEvents, tt.scrapeErrors, []string{sourceWynn}, tt.threshold, tt.acceptAfter)
+
+			if tt.expected < 0 {
+				if len(anomalies) != 0 {
+					t.Errorf("Expected no anomaly, got %+v", anomalies)
+				}
+				return
+			}
+			if len(anomalies) != 1 {
+				t.Fatalf("Expected 1 anomaly, got %+v", anomalies)
+			}
+			if anomalies[0].Source != sourceWynn || anomalies[0].EventCount != tt.scraped || anomalies[0].UsualCount != tt.expected {
+				t.Errorf("Expected wynn to have %d events where it usually has %d, got %+v", tt.scraped, tt.expected, anomalies[0])
+			}
+			if anomalies[0].Accepted != tt.accepted {
+				t.Errorf("Expected accepted to be %t, got %t", tt.accepted, anomalies[0].Accepted)
+			}
+		})
+	}
+}
+
+// TestWithCountHistory tests that only the most recent counts are kept
+func TestWithCountHistory(t *testing.T) {
+	history := []int{}
+	for count := 1; count <= maxCountHistory+2; count++ {
+		history = withCountHistory(history, count)
+	}
+
+	if len(history) != maxCountHistory || history[0] != 3 || history[len(history)-1] != maxCountHistory+2 {
+		t.Errorf("Expected the last %d counts, got %v", maxCountHistory, history)
+	}
+}
+
+// TestSyncSources_Anomaly tests that a source whose page comes back empty keeps its events
+// and is reported as flagged
+func TestSyncSources_Anomaly(t *testing.T) {
+	app, adminKey, _ := newAdminRunsTestApplication(t)
+	app.config.anomaly.threshold = 0.5
+
+	// Wynn answers, but with a page our selectors find nothing in.
+	empty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html><body><p>We moved things around</p></body></html>")
+	}))
+	defer empty.Close()
+	app.scrapingURLs.Wynn = empty.URL
+
+	stored := scrapedEvents(sourceWynn, 3)
+	app.dbSnippets = &mockSnippetModel{edmEvents: stored}
+	syncStates := &mockSyncStateModel{sourceSyncs: []SourceSync{
+		{Source: sourceWynn, LastSuccessfulSync: "2026-10-18T06:00:00Z", EventCount: 3, CountHistory: []int{3, 3, 4}},
+	}}
+	app.dbSyncStates = syncStates
+
+	rr := adminRequest(t, app, http.MethodPost, "/v1/admin/runs", adminKey, `{"sources": ["wynn"]}`)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusAccepted, rr.Code, rr.Body.String())
+	}
+
+	var created struct {
+		Run scrapeRun `json:"run"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	waitForRun(t, app, created.Run.ID)
+
+	run, _ := app.runs.get(created.Run.ID)
+	if run.Status != runStatusSucceeded || run.Sources[0].Status != sourceRunFlagged || run.Removed != 0 {
+		t.Errorf("Expected wynn to be flagged without removing events, got %+v", run)
+	}
+
+	model := app.dbSnippets.(*mockSnippetModel)
+	if len(model.edmEvents) != len(stored) {
+		t.Errorf("Expected wynn's %d events to be kept, got %d", len(stored), len(model.edmEvents))
+	}
+
+	wynn := syncStates.sourceSyncs[0]
+	if wynn.LastSuccessfulSync != "2026-10-18T06:00:00Z" || fmt.Sprint(wynn.CountHistory) != "[3 3 4]" {
+		t.Errorf("Expected wynn to keep its previous sync and history, got %+v", wynn)
+	}
+	if wynn.LastError != "scraped 0 events where it usually has 3, kept its previous events" {
+		t.Errorf("Expected the anomaly as wynn's last error, got '%s'", wynn.LastError)
+	}
+}
+
+// TestSyncSources_AnomalyRecovers tests that a source that keeps scraping fewer events has
+// its new count accepted once it has been flagged enough runs in a row
+func TestSyncSources_AnomalyRecovers(t *testing.T) {
+	app, adminKey, _ := newAdminRunsTestApplication(t)
+	app.config.anomaly.threshold = 0.5
+	app.config.anomaly.acceptAfter = 2
+
+	// Wynn cut its listings, its page is there but lists nothing.
+	empty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html><body><p>No upcoming events</p></body></html>")
+	}))
+	defer empty.Close()
+	app.scrapingURLs.Wynn = empty.URL
+
+	app.dbSnippets = &mockSnippetModel{edmEvents: scrapedEvents(sourceWynn, 3)}
+	syncStates := &mockSyncStateModel{sourceSyncs: []SourceSync{
+		{Source: sourceWynn, LastSuccessfulSync: "2026-10-18T06:00:00Z", EventCount: 3, CountHistory: []int{3, 3, 4}},
+	}}
+	app.dbSyncStates = syncStates
+
+	run := func() scrapeRun {
+		t.Helper()
+
+		rr := adminRequest(t, app, http.MethodPost, "/v1/admin/runs", adminKey, `{"sources": ["wynn"]}`)
+		if rr.Code != http.StatusAccepted {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusAccepted, rr.Code, rr.Body.String())
+		}
+		var created struct {
+			Run scrapeRun `json:"run"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+			t.Fatal(err)
+		}
+		waitForRun(t, app, created.Run.ID)
+
+		finished, _ := app.runs.get(created.Run.ID)
+		return finished
+	}
+
+	for i := 1; i <= 2; i++ {
+		finished := run()
+		if finished.Sources[0].Status != sourceRunFlagged {
+			t.Fatalf("Expected run %d to flag wynn, got %+v", i, finished.Sources[0])
+		}
+		if syncStates.sourceSyncs[0].FlaggedRuns != i {
+			t.Errorf("Expected wynn to be flagged %d runs in a row, got %d", i, syncStates.sourceSyncs[0].FlaggedRuns)
+		}
+	}
+
+	finished := run()
+	if finished.Sources[0].Status != sourceRunScraped {
+		t.Errorf("Expected wynn's new count to be accepted on the third run, got %+v", finished.Sources[0])
+	}
+	wynn := syncStates.sourceSyncs[0]
+	if wynn.FlaggedRuns != 0 || fmt.Sprint(wynn.CountHistory) != "[0]" || wynn.LastSuccessfulSync == "2026-10-18T06:00:00Z" {
+		t.Errorf("Expected wynn to sync and start a new history, got %+v", wynn)
+	}
+	if wynn.LastError != "scraped 0 events where it usually has 3, accepted as its new usual count" {
+		t.Errorf("Expected the accepted anomaly as wynn's last error, got '%s'", wynn.LastError)
+	}
+	if edmEvents := app.dbSnippets.(*mockSnippetModel).edmEvents; len(edmEvents) != 0 {
+		t.Errorf("Expected wynn's stale events to be removed, got %d", len(edmEvents))
+	}
+
+	// The new count is now the usual one.
+	finished = run()
+	if finished.Sources[0].Status != sourceRunScraped {
+		t.Errorf("Expected wynn not to be flagged again, got %+v", finished.Sources[0])
+	}
+}
+
+// TestAlertOps tests emailing the anomalies of a run to the ops addresses
+func TestAlertOps(t *testing.T) {
+	server := newFakeSMTPServer(t, false)
+
+	app := newTestApplication(t, nil)
+	app.mailer = server.mailer(t, smtpTLSNone, "", "")
+	app.config.anomaly.threshold = 0.5
+	app.config.ops.emails = []string{"ops@example.com", "oncall@example.com"}
+
+	app.config.anomaly.acceptAfter = 3
+	app.alertOps([]sourceAnomaly{{Source: sourceWynn, EventCount: 0, UsualCount: 41}})
+
+	messages := server.received()
+	if len(messages) != 2 {
+		t.Fatalf("Expected 2 emails, got %d", len(messages))
+	}
+	email := parseEmail(t, messages[0].data)
+	if email.subject != "Scrape of wynn returned 0 events, usually 41" {
+		t.Errorf("Expected the anomaly subject, got '%s'", email.subject)
+	}
+	for _, expected := range []string{"fewer than 50% of their usual events", "flagged 3 runs in a row"} {
+		if !strings.Contains(email.plainBody, expected) {
+			t.Errorf("Expected %q in the email, got:\n%s", expected, email.plainBody)
+		}
+	}
+
+	entries := app.dbSendLog.(*mockSendLogModel).entries
+	if len(entries) != 2 || entries[0].Recipient != "ops@example.com" || entries[0].Status != sendStatusSent {
+		t.Errorf("Expected both emails in the send log, got %+v", entries)
+	}
+
+	// No anomalies, no email.
+	app.alertOps(nil)
+	if len(server.received()) != 2 {
+		t.Errorf("Expected no email without anomalies")
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"golang.org/x/time/rate"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const apiKeyPrefix = "edm_"
+
+var errRecordNotFound = errors.New("record not found")
+
+// APIKey is stored without the key itself, only its sha256 hash. Prefix keeps the first
+// characters of the key so that its owner can recognise it in `keys list`.
+type APIKey struct {
+	ID            string  `json:"id"`
+	Name          string  `json:"name"`
+	Hash          string  `json:"-"`
+	Prefix        string  `json:"prefix"`
+	RatePerSecond float64 `json:"rate_per_second"`
+	Burst         int     `json:"burst"`
+	Admin         bool    `json:"admin"`
+	CreatedAt     string  `json:"created_at"`
+	RevokedAt     string  `json:"revoked_at,omitempty"`
+	RequestCount  int64   `json:"request_count"`
+	LastUsedAt    string  `json:"last_used_at,omitempty"`
+}
+
+func (k APIKey) revoked() bool {
+	return k.RevokedAt != ""
+}
+
+type APIKeyModelInterface interface {
+	Insert(apiKey APIKey) error
+	GetByHash(hash string) (APIKey, error)
+	GetAll() ([]APIKey, error)
+	Revoke(id string, revokedAt string) error
+	AddUsage(requestCounts map[string]int64, lastUsedAt string) error
+}
+
+// APIKeyModel stores one document per key, keyed by the key's id.
+type APIKeyModel struct {
+	Client     *firestore.Client
+	Collection string
+}
+
+func (m *APIKeyModel) Insert(apiKey APIKey) error {
+	ctx := context.Background()
+	_, err := m.Client.Collection(m.Collection).Doc(apiKey.ID).Create(ctx, apiKey)
+	if err != nil {
+		return fmt.Errorf("failed to insert api key: %v", err)
+	}
+	return nil
+}
+
+func (m *APIKeyModel) GetByHash(hash string) (APIKey, error) {
+	ctx := context.Background()
+
+	iter := m.Client.Collection(m.Collection).Where("Hash", "==", hash).Limit(1).Documents(ctx)
+	defer iter.Stop()
+
+	doc, err := iter.Next()
+	if err == iterator.Done {
+		return APIKey{}, errRecordNotFound
+	}
+	if err != nil {
+		return APIKey{}, fmt.Errorf("failed to look up api key: %v", err)
+	}
+
+	var apiKey APIKey
+	if err := doc.DataTo(&apiKey); err != nil {
+		return APIKey{}, fmt.Errorf("failed to decode api key %s: %v", doc.Ref.ID, err)
+	}
+	return apiKey, nil
+}
+
+func (m *APIKeyModel) GetAll() ([]APIKey, error) {
+	ctx := context.Background()
+	apiKeys := []APIKey{}
+
+	iter := m.Client.Collection(m.Collection).Documents(ctx)
+	defer iter.Stop()
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate api keys: %v", err)
+		}
+
+		var apiKey APIKey
+		if err := doc.DataTo(&apiKey); err != nil {
+			return nil, fmt.Errorf("failed to decode api key %s: %v", doc.Ref.ID, err)
+		}
+		apiKeys = append(apiKeys, apiKey)
+	}
+
+	sort.Slice(apiKeys, func(i, j int) bool {
+		return apiKeys[i].CreatedAt < apiKeys[j].CreatedAt
+	})
+
+	return apiKeys, nil
+}
+
+func (m *APIKeyModel) Revoke(id string, revokedAt string) error {
+	ctx := context.Background()
+	docRef := m.Client.Collection(m.Collection).Doc(id)
+
+	_, err := docRef.Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return errRecordNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get api key %s: %v", id, err)
+	}
+
+	_, err = docRef.Update(ctx, []firestore.Update{{Path: "RevokedAt", Value: revokedAt}})
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key %s: %v", id, err)
+	}
+	return nil
+}
+
+func (m *APIKeyModel) AddUsage(requestCounts map[string]int64, lastUsedAt string) error {
+	ctx := context.Background()
+	batch := m.Client.BulkWriter(ctx)
+
+	for id, count := range requestCounts {
+		docRef := m.Client.Collection(m.Collection).Doc(id)
+		_, err := batch.Update(docRef, []firestore.Update{
+			{Path: "RequestCount", Value: firestore.Increment(count)},
+			{Path: "LastUsedAt", Value: lastUsedAt},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to queue usage for api key %s: %v", id, err)
+		}
+	}
+
+	batch.End()
+	return nil
+}
+
+// generateAPIKey returns a new random key along with the record to store for it.
+func generateAPIKey(name string, ratePerSecond float64, burst int, admin bool, now time.Time) (string, APIKey, error) {
+	randomBytes := make([]byte, 24)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", APIKey{}, err
+	}
+
+	plaintext := apiKeyPrefix + hex.EncodeToString(randomBytes)
+
+	apiKey := APIKey{
+		ID:            getGUID(),
+		Name:          name,
+		Hash:          hashAPIKey(plaintext),
+		Prefix:        plaintext[:len(apiKeyPrefix)+6],
+		RatePerSecond: ratePerSecond,
+		Burst:         burst,
+		Admin:         admin,
+		CreatedAt:     now.UTC().Format(time.RFC3339),
+	}
+
+	return plaintext, apiKey, nil
+}
+
+func hashAPIKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(plaintext)))
+	return hex.EncodeToString(sum[:])
+}
+
+// apiKeyCacheTTL bounds how long a revoked key keeps working in a running server. Unknown
+// keys are cached too, so the lookups are dropped wholesale once there are this many.
+const (
+	apiKeyCacheTTL   = time.Minute
+	maxCachedAPIKeys = 10000
+)
+
+type cachedAPIKey struct {
+	apiKey    APIKey
+	found     bool
+	expiresAt time.Time
+}
+
+// apiKeyAuth keeps the per-process state of API key authentication: recent lookups, a
+// token bucket per key and the request counts not yet written to storage.
+type apiKeyAuth struct {
+	mu            sync.Mutex
+	lookups       map[string]cachedAPIKey
+	limiters      map[string]*rate.Limiter
+	requestCounts map[string]int64
+}
+
+func newAPIKeyAuth() *apiKeyAuth {
+	return &apiKeyAuth{
+		lookups:       make(map[string]cachedAPIKey),
+		limiters:      make(map[string]*rate.Limiter),
+		requestCounts: make(map[string]int64),
+	}
+}
+
+// lookup returns the key matching the hash, reading storage at most once per TTL per hash.
+func (a *apiKeyAuth) lookup(model APIKeyModelInterface, hash string, now time.Time) (APIKey, bool, error) {
+	a.mu.Lock()
+	cached, ok := a.lookups[hash]
+	a.mu.Unlock()
+
+	if ok && now.Before(cached.expiresAt) {
+		return cached.apiKey, cached.found, nil
+	}
+
+	apiKey, err := model.GetByHash(hash)
+	found := true
+	if errors.Is(err, errRecordNotFound) {
+		found = false
+	} else if err != nil {
+		return APIKey{}, false, err
+	}
+
+	a.mu.Lock()
+	if len(a.lookups) >= maxCachedAPIKeys {
+		a.lookups = make(map[string]cachedAPIKey)
+	}
+	a.lookups[hash] = cachedAPIKey{apiKey: apiKey, found: found, expiresAt: now.Add(apiKeyCacheTTL)}
+	a.mu.Unlock()
+
+	return apiKey, found, nil
+}
+
+// allow takes a token from the key's bucket, creating the bucket on first use. The returned
+// duration is how long the client should wait before retrying when no token was left.
+func (a *apiKeyAuth) allow(apiKey APIKey, now time.Time) (bool, time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	limiter, ok := a.limiters[apiKey.ID]
+	if !ok || limiter.Limit() != rate.Limit(apiKey.RatePerSecond) || limiter.Burst() != apiKey.Burst {
+		limiter = rate.NewLimiter(rate.Limit(apiKey.RatePerSecond), apiKey.Burst)
+		a.limiters[apiKey.ID] = limiter
+	}
+
+	reservation := limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return false, time.Second
+	}
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+func (a *apiKeyAuth) recordRequest(apiKey APIKey) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.requestCounts[apiKey.ID]++
+}
+
+// flushUsage writes the request counts gathered since the last flush. On failure the counts
+// are put back so that the next flush retries them.
+func (a *apiKeyAuth) flushUsage(model APIKeyModelInterface, now time.Time) error {
+	a.mu.Lock()
+	requestCounts := a.requestCounts
+	a.requestCounts = make(map[string]int64)
+	a.mu.Unlock()
+
+	if len(requestCounts) == 0 {
+		return nil
+	}
+
+	err := model.AddUsage(requestCounts, now.UTC().Format(time.RFC3339))
+	if err != nil {
+		a.mu.Lock()
+		for id, count := range requestCounts {
+			a.requestCounts[id] += count
+		}
+		a.mu.Unlock()
+		return err
+	}
+
+	return nil
+}
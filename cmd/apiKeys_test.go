@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newAPIKeyTestApplication returns a test application with a single key, allowing bursts of
+// 2 requests refilled at 1 per second.
+func newAPIKeyTestApplication(t *testing.T) (*application, string) {
+	t.Helper()
+
+	now := time.Date(2026, 10, 19, 6, 0, 0, 0, time.UTC)
+
+	plaintext, apiKey, err := generateAPIKey("tester", 1, 2, false, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := newTestApplication(t, calendarTestEvents)
+	app.dbAPIKeys = &mockAPIKeyModel{apiKeys: []APIKey{apiKey}}
+
+	return app, plaintext
+}
+
+// TestGenerateAPIKey tests that only the hash of a new key is kept
+func TestGenerateAPIKey(t *testing.T) {
+	plaintext, apiKey, err := generateAPIKey("tester", 5, 20, false, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(plaintext, apiKeyPrefix) || len(plaintext) != len(apiKeyPrefix)+48 {
+		t.Errorf("Expected a %s prefixed key of 48 hex characters, got '%s'", apiKeyPrefix, plaintext)
+	}
+	if apiKey.Hash != hashAPIKey(plaintext) || strings.Contains(apiKey.Hash, plaintext) {
+		t.Error("Expected the record to hold the key's sha256 hash")
+	}
+	if !strings.HasPrefix(plaintext, apiKey.Prefix) {
+		t.Errorf("Expected prefix '%s' to start the key", apiKey.Prefix)
+	}
+
+	other, _, _ := generateAPIKey("tester", 5, 20, false, time.Now())
+	if other == plaintext {
+		t.Error("Expected keys to be random")
+	}
+}
+
+// TestAuthenticate_Positive tests requests that are let through
+func TestAuthenticate_Positive(t *testing.T) {
+	tests := []struct {
+		name       string
+		require    bool
+		path       string
+		headers    func(key string) map[string]string
+		withAPIKey bool
+	}{
+		{
+			name:    "No key while keys are optional",
+			require: false,
+			path:    "/v1/events",
+			headers: func(key string) map[string]string { return nil },
+		},
+		{
+			name:       "Bearer token",
+			require:    true,
+			path:       "/v1/events",
+			headers:    func(key string) map[string]string { return map[string]string{"Authorization": "Bearer " + key} },
+			withAPIKey: true,
+		},
+		{
+			name:       "X-API-Key header",
+			require:    true,
+			path:       "/v1/events",
+			headers:    func(key string) map[string]string { return map[string]string{"X-API-Key": key} },
+			withAPIKey: true,
+		},
+		{
+			name:       "Query parameter for calendar apps",
+			require:    true,
+			path:       "/v1/calendar.ics?api_key=",
+			headers:    func(key string) map[string]string { return nil },
+			withAPIKey: true,
+		},
+		{
+			name:    "OpenAPI specification is exempt",
+			require: true,
+			path:    "/v1/openapi.json",
+			headers: func(key string) map[string]string { return nil },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, plaintext := newAPIKeyTestApplication(t)
+			app.config.apiKeys.require = tt.require
+
+			path := tt.path
+			if strings.HasSuffix(path, "api_key=") {
+				path += plaintext
+			}
+
+			rr := getWithHeaders(t, app, path, tt.headers(plaintext))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+			}
+
+			app.apiKeyAuth.mu.Lock()
+			requestCount := app.apiKeyAuth.requestCounts[app.dbAPIKeys.(*mockAPIKeyModel).apiKeys[0].ID]
+			app.apiKeyAuth.mu.Unlock()
+
+			if tt.withAPIKey && requestCount != 1 {
+				t.Errorf("Expected the request to be counted against the key, got %d", requestCount)
+			}
+			if !tt.withAPIKey && requestCount != 0 {
+				t.Errorf("Expected no request to be counted, got %d", requestCount)
+			}
+		})
+	}
+}
+
+// TestAuthenticate_Negative tests requests that are rejected
+func TestAuthenticate_Negative(t *testing.T) {
+	tests := []struct {
+		name               string
+		require            bool
+		headers            map[string]string
+		expectedStatusCode int
+	}{
+		{
+			name:               "No key while keys are required",
+			require:            true,
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Unknown key while keys are optional",
+			require:            false,
+			headers:            map[string]string{"X-API-Key": "edm_unknown"},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Malformed Authorization header",
+			require:            false,
+			headers:            map[string]string{"Authorization": "Basic dXNlcjpwYXNz"},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, _ := newAPIKeyTestApplication(t)
+			app.config.apiKeys.require = tt.require
+
+			rr := getWithHeaders(t, app, "/v1/events", tt.headers)
+			if rr.Code != tt.expectedStatusCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatusCode, rr.Code)
+			}
+			if rr.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("Expected a WWW-Authenticate challenge, got '%s'", rr.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+// TestAuthenticate_RevokedKey tests that revoked keys are rejected
+func TestAuthenticate_RevokedKey(t *testing.T) {
+	app, plaintext := newAPIKeyTestApplication(t)
+	model := app.dbAPIKeys.(*mockAPIKeyModel)
+
+	err := app.keysCommand([]string{"revoke", model.apiKeys[0].ID}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := getWithHeaders(t, app, "/v1/events", map[string]string{"X-API-Key": plaintext})
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, rr.Code)
+	}
+}
+
+// TestAuthenticate_RateLimit tests the per key token bucket
+func TestAuthenticate_RateLimit(t *testing.T) {
+	app, plaintext := newAPIKeyTestApplication(t)
+	headers := map[string]string{"X-API-Key": plaintext}
+
+	// The key's bucket holds 2 tokens and refills 1 per second.
+	for i := 0; i < 2; i++ {
+		if rr := getWithHeaders(t, app, "/v1/events", headers); rr.Code != http.StatusOK {
+			t.Fatalf("Expected request %d to be allowed, got status %d", i+1, rr.Code)
+		}
+	}
+
+	rr := getWithHeaders(t, app, "/v1/events", headers)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status %d, got %d", http.StatusTooManyRequests, rr.Code)
+	}
+	if rr.Header().Get("Retry-After") != "1" {
+		t.Errorf("Expected Retry-After '1', got '%s'", rr.Header().Get("Retry-After"))
+	}
+
+	if calls := app.dbAPIKeys.(*mockAPIKeyModel).getByHashCalls; calls != 1 {
+		t.Errorf("Expected the key lookup to be cached, got %d lookups", calls)
+	}
+}
+
+// TestAPIKeyAuth_FlushUsage tests that request counts are written and retried on failure
+func TestAPIKeyAuth_FlushUsage(t *testing.T) {
+	app, plaintext := newAPIKeyTestApplication(t)
+	model := app.dbAPIKeys.(*mockAPIKeyModel)
+	headers := map[string]string{"X-API-Key": plaintext}
+
+	getWithHeaders(t, app, "/v1/events", headers)
+	getWithHeaders(t, app, "/v1/events", headers)
+
+	model.err = errors.New("firestore unavailable")
+	if err := app.apiKeyAuth.flushUsage(model, time.Now()); err == nil {
+		t.Fatal("Expected the failed flush to return its error")
+	}
+
+	model.err = nil
+	flushedAt := time.Date(2026, 10, 19, 7, 0, 0, 0, time.UTC)
+	if err := app.apiKeyAuth.flushUsage(model, flushedAt); err != nil {
+		t.Fatal(err)
+	}
+
+	if model.apiKeys[0].RequestCount != 2 {
+		t.Errorf("Expected 2 requests after the retried flush, got %d", model.apiKeys[0].RequestCount)
+	}
+	if model.apiKeys[0].LastUsedAt != "2026-10-19T07:00:00Z" {
+		t.Errorf("Expected last used '2026-10-19T07:00:00Z', got '%s'", model.apiKeys[0].LastUsedAt)
+	}
+
+	if err := app.apiKeyAuth.flushUsage(model, flushedAt); err != nil || model.apiKeys[0].RequestCount != 2 {
+		t.Errorf("Expected an empty flush to change nothing, got %d requests", model.apiKeys[0].RequestCount)
+	}
+}
+
+// TestKeysCommand tests creating, listing and revoking keys from the command line
+func TestKeysCommand(t *testing.T) {
+	app := newTestApplication(t, nil)
+	model := app.dbAPIKeys.(*mockAPIKeyModel)
+
+	var out bytes.Buffer
+	err := app.keysCommand([]string{"create", "-name", "ticket bot", "-rate", "2", "-burst", "4", "-admin"}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(model.apiKeys) != 1 {
+		t.Fatalf("Expected 1 stored key, got %d", len(model.apiKeys))
+	}
+	apiKey := model.apiKeys[0]
+	if apiKey.Name != "ticket bot" || apiKey.RatePerSecond != 2 || apiKey.Burst != 4 || !apiKey.Admin {
+		t.Errorf("Expected the flags to be stored, got %+v", apiKey)
+	}
+
+	var plaintext string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if strings.HasPrefix(line, apiKeyPrefix) {
+			plaintext = line
+		}
+	}
+	if hashAPIKey(plaintext) != apiKey.Hash {
+		t.Errorf("Expected the printed key to match the stored hash, got output %q", out.String())
+	}
+
+	out.Reset()
+	if err := app.keysCommand([]string{"list"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), apiKey.ID) || strings.Contains(out.String(), plaintext) {
+		t.Errorf("Expected the list to show the key's id but not the key, got %q", out.String())
+	}
+
+	if err := app.keysCommand([]string{"revoke", apiKey.ID}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !model.apiKeys[0].revoked() {
+		t.Error("Expected the key to be revoked")
+	}
+
+	invalid := [][]string{
+		{},
+		{"create"},
+		{"create", "-name", "x", "-rate", "0"},
+		{"create", "-name", "x", "-burst", "0"},
+		{"create", "-unknown"},
+		{"revoke"},
+		{"revoke", "missing-id"},
+		{"rotate"},
+	}
+	for _, args := range invalid {
+		if err := app.keysCommand(args, &out); err == nil {
+			t.Errorf("Expected keys %v to fail", args)
+		}
+	}
+}
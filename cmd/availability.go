@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// The availability of an event's tickets, as far as its listing tells.
+const (
+	availabilityOnSale  = "on_sale"
+	availabilityLimited = "limited"
+	availabilitySoldOut = "sold_out"
+	availabilityFree    = "free"
+	availabilityUnknown = "unknown"
+)
+
+var availabilities = []string{availabilityOnSale, availabilityLimited, availabilitySoldOut, availabilityFree, availabilityUnknown}
+
+// availabilityPhrases map the wording of badges and ticket buttons to an availability. They
+// are tried in order, so that "almost sold out" is limited rather than sold out and a sold out
+// badge wins over the "Tickets" label of the button it's on.
+var availabilityPhrases = []struct {
+	phrase       string
+	availability string
+}{
+	{"almost sold out", availabilityLimited},
+	{"nearly sold out", availabilityLimited},
+	{"sold out", availabilitySoldOut},
+	{"soldout", availabilitySoldOut},
+	{"at capacity", availabilitySoldOut},
+	{"limited", availabilityLimited},
+	{"selling fast", availabilityLimited},
+	{"few left", availabilityLimited},
+	{"last tickets", availabilityLimited},
+	{"final tickets", availabilityLimited},
+	{"free", availabilityFree},
+	{"no cover", availabilityFree},
+	{"complimentary", availabilityFree},
+	{"on sale", availabilityOnSale},
+	{"tickets", availabilityOnSale},
+	{"buy", availabilityOnSale},
+	{"book now", availabilityOnSale},
+}
+
+// parseAvailability reads the availability out of the texts a listing shows next to an
+// event, such as a badge, a button label or its class names. Phrases match as whole words
+// ignoring case and punctuation, texts that say nothing we know of are unknown.
+func parseAvailability(texts ...string) string {
+	tokens := strings.Fields(normalizeSearchText(strings.Join(texts, " ")))
+
+	for _, p := range availabilityPhrases {
+		if artistMatches(strings.Fields(p.phrase), tokens) {
+			return p.availability
+		}
+	}
+	return availabilityUnknown
+}
+
+// urVenueAvailability reads the availability of an event of an UrVenue listing, which Wynn,
+// Zouk and LIV all use, from its ticket button and the status badges of the event item.
+func urVenueAvailability(item *goquery.Selection, ticketButton *goquery.Selection) string {
+	texts := []string{ticketButton.Text(), ticketButton.AttrOr("class", "")}
+	item.Find(".uv-badge, .uv-status, .uv-soldout, .soldout, .sold-out").Each(func(i int, badge *goquery.Selection) {
+		texts = append(texts, badge.Text(), badge.AttrOr("class", ""))
+	})
+	return parseAvailability(texts...)
+}
+
+// eventAvailability is the availability of an event, unknown for events stored before it was
+// recorded.
+func eventAvailability(edmEvent EdmEvent) string {
+	if edmEvent.Availability == "" {
+		return availabilityUnknown
+	}
+	return edmEvent.Availability
+}
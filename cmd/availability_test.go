@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// TestParseAvailability tests reading the availability out of badges and button labels
+func TestParseAvailability(t *testing.T) {
+	tests := []struct {
+		name     string
+		texts    []string
+		expected string
+	}{
+		{name: "Sold out badge", texts: []string{"SOLD OUT"}, expected: availabilitySoldOut},
+		{name: "Sold out class", texts: []string{"Tickets", "uv-btn uv-soldout"}, expected: availabilitySoldOut},
+		{name: "Almost sold out", texts: []string{"Almost Sold-Out!"}, expected: availabilityLimited},
+		{name: "Limited", texts: []string{"Limited Tickets"}, expected: availabilityLimited},
+		{name: "Free", texts: []string{"Free Entry"}, expected: availabilityFree},
+		{name: "Ticket button", texts: []string{"Buy Tickets"}, expected: availabilityOnSale},
+		{name: "Whole words only", texts: []string{"Freedom Fridays"}, expected: availabilityUnknown},
+		{name: "Other badge", texts: []string{"Special Event"}, expected: availabilityUnknown},
+		{name: "Nothing", texts: nil, expected: availabilityUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseAvailability(tt.texts...); got != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestURVenueAvailability tests reading the availability of an UrVenue event item from its
+// ticket button and badges
+func TestURVenueAvailability(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		expected string
+	}{
+		{name: "Ticket button", html: `<div class="eventitem"><a class="uv-btn" href="/e">Tickets</a></div>`, expected: availabilityOnSale},
+		{name: "Sold out button", html: `<div class="eventitem"><a class="uv-btn uv-soldout" href="/e">Tickets</a></div>`, expected: availabilitySoldOut},
+		{name: "Badge", html: `<div class="eventitem"><span class="uv-badge">Limited</span><a class="uv-btn" href="/e">Tickets</a></div>`, expected: availabilityLimited},
+		{name: "Artist name isn't read", html: `<div class="eventitem"><span class="uv-events-name">Free Spirit</span><a class="uv-btn" href="/e"></a></div>`, expected: availabilityUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			item := doc.Find("div.eventitem")
+
+			if got := urVenueAvailability(item, item.Find("a.uv-btn")); got != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	icsTimeZone   = "America/Los_Angeles"
+	icsUIDDomain  = "edmeventsscraperapigo.christiangabrielsson.net"
+	icsLineLength = 75
+)
+
+// The VTIMEZONE for Las Vegas, daylight saving time starts on the second Sunday of March
+// and ends on the first Sunday of November.
+var icsVTimeZone = []string{
+	"BEGIN:VTIMEZONE",
+	"TZID:" + icsTimeZone,
+	"X-LIC-LOCATION:" + icsTimeZone,
+	"BEGIN:DAYLIGHT",
+	"TZOFFSETFROM:-0800",
+	"TZOFFSETTO:-0700",
+	"TZNAME:PDT",
+	"DTSTART:19700308T020000",
+	"RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=2SU",
+	"END:DAYLIGHT",
+	"BEGIN:STANDARD",
+	"TZOFFSETFROM:-0700",
+	"TZOFFSETTO:-0800",
+	"TZNAME:PST",
+	"DTSTART:19701101T020000",
+	"RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=1SU",
+	"END:STANDARD",
+	"END:VTIMEZONE",
+}
+
+// calendarFeedHandler serves the events matching the /v1/events filters as an iCalendar
+// feed. The per-artist and per-venue routes fill in the matching filter from the path.
+func (app *application) calendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	v := newValidator()
+	filters := app.readEventFilters(r.URL.Query(), v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	calendarName := "Las Vegas EDM Events"
+	if artist := r.PathValue("artist"); artist != "" {
+		filters.Artist = strings.ToLower(artist)
+		calendarName += " - " + filters.Artist
+	}
+	if venue := r.PathValue("venue"); venue != "" {
+		filters.Venue = strings.ToLower(venue)
+		calendarName += " - " + filters.Venue
+	}
+
+	edmEvents, err := app.queryEdmEvents(filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="calendar.ics"`)
+	w.WriteHeader(http.StatusOK)
+
+	err = writeCalendar(w, calendarName, edmEvents, time.Now())
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+// writeCalendar writes the events as an RFC 5545 VCALENDAR. Events whose date could not
+// be parsed are left out rather than failing the whole feed.
+func writeCalendar(w io.Writer, calendarName string, edmEvents []EdmEvent, now time.Time) error {
+	ics := &icsWriter{w: bufio.NewWriter(w)}
+
+	ics.line("BEGIN:VCALENDAR")
+	ics.line("VERSION:2.0")
+	ics.line("PRODID:-//edmEventsScraperApiGo//EDM Events Las Vegas//EN")
+	ics.line("CALSCALE:GREGORIAN")
+	ics.line("METHOD:PUBLISH")
+	ics.line("X-WR-CALNAME:" + escapeICSText(calendarName))
+	ics.line("X-WR-TIMEZONE:" + icsTimeZone)
+	for _, line := range icsVTimeZone {
+		ics.line(line)
+	}
+
+	for _, edmEvent := range edmEvents {
+		start, end, err := icsEventTimes(edmEvent.EventDate)
+		if err != nil {
+			continue
+		}
+
+		stamp := now.UTC()
+		if lastModified, err := time.Parse(time.RFC3339, edmEvent.LastModified); err == nil {
+			stamp = lastModified.UTC()
+		}
+
+		ics.line("BEGIN:VEVENT")
+		ics.line("UID:" + edmEvent.Id + "@" + icsUIDDomain)
+		ics.line("DTSTAMP:" + stamp.Format("20060102T150405Z"))
+		ics.line("LAST-MODIFIED:" + stamp.Format("20060102T150405Z"))
+		ics.line(fmt.Sprintf("SEQUENCE:%d", edmEvent.Sequence))
+		ics.line(start)
+		if end != "" {
+			ics.line(end)
+		}
+		ics.line("SUMMARY:" + escapeICSText(edmEvent.ArtistName+" @ "+edmEvent.ClubName))
+		ics.line("LOCATION:" + escapeICSText(edmEvent.ClubName+", Las Vegas, NV"))
+		if edmEvent.TicketUrl != "" {
+			ics.line("URL:" + edmEvent.TicketUrl)
+			ics.line("DESCRIPTION:" + escapeICSText("Tickets: "+edmEvent.TicketUrl))
+		}
+		ics.line("STATUS:CONFIRMED")
+		ics.line("TRANSP:OPAQUE")
+		ics.line("END:VEVENT")
+	}
+
+	ics.line("END:VCALENDAR")
+
+	if ics.err != nil {
+		return ics.err
+	}
+	return ics.w.Flush()
+}
+
+// icsEventTimes converts a stored EventDate into DTSTART and DTEND properties. The scrapers
+// only know the night of an event and store it as midnight, so those become all-day events.
+// A date carrying a time of day is Las Vegas wall-clock time and is anchored to the VTIMEZONE.
+func icsEventTimes(eventDate string) (string, string, error) {
+	t, err := time.Parse(time.RFC3339, eventDate)
+	if err != nil {
+		return "", "", err
+	}
+
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+		start := "DTSTART;VALUE=DATE:" + t.Format("20060102")
+		end := "DTEND;VALUE=DATE:" + t.AddDate(0, 0, 1).Format("20060102")
+		return start, end, nil
+	}
+
+	return "DTSTART;TZID=" + icsTimeZone + ":" + t.Format("20060102T150405"), "", nil
+}
+
+// escapeICSText escapes a value of the TEXT type as described in RFC 5545 section 3.3.11.
+func escapeICSText(s string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	)
+	return replacer.Replace(s)
+}
+
+// icsWriter writes content lines terminated by CRLF and folded at 75 octets, taking care
+// not to split a multi-byte UTF-8 character across two lines.
+type icsWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (ics *icsWriter) line(s string) {
+	if ics.err != nil {
+		return
+	}
+
+	limit := icsLineLength
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		_, ics.err = ics.w.WriteString(s[:cut] + "\r\n ")
+		if ics.err != nil {
+			return
+		}
+		s = s[cut:]
+		// Continuation lines start with a space, which counts towards the 75 octets.
+		limit = icsLineLength - 1
+	}
+
+	_, ics.err = ics.w.WriteString(s + "\r\n")
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+var calendarTestEvents = []EdmEvent{
+	{Id: "id-1", ClubName: "xs nightclub", ArtistName: "tiësto", EventDate: "2026-11-20T00:00:00Z", TicketUrl: "https://www.wynnsocial.com/events/20261120", Sequence: 1, LastModified: "2026-10-01T08:30:00Z"},
+	{Id: "id-2", ClubName: "omnia", ArtistName: "martin garrix", EventDate: "2026-11-21T00:00:00Z", TicketUrl: "https://taogroup.com/event/martin-garrix"},
+	{Id: "id-3", ClubName: "zouk nightclub", ArtistName: "martin garrix, alesso", EventDate: "2026-11-22T23:00:00Z"},
+}
+
+// TestWriteCalendar tests the rendered iCalendar document
+func TestWriteCalendar(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	err := writeCalendar(&buf, "Las Vegas EDM Events", calendarTestEvents, now)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	ics := buf.String()
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{name: "Calendar header", expected: "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"},
+		{name: "Las Vegas time zone", expected: "BEGIN:VTIMEZONE\r\nTZID:America/Los_Angeles\r\n"},
+		{name: "Stable UID from the event id", expected: "UID:id-1@" + icsUIDDomain + "\r\n"},
+		{name: "Sequence from the event", expected: "SEQUENCE:1\r\n"},
+		{name: "Stamp from last modified", expected: "DTSTAMP:20261001T083000Z\r\n"},
+		{name: "Stamp falls back to now", expected: "DTSTAMP:20261019T120000Z\r\n"},
+		{name: "Date only events are all day", expected: "DTSTART;VALUE=DATE:20261120\r\nDTEND;VALUE=DATE:20261121\r\n"},
+		{name: "Timed events use the time zone", expected: "DTSTART;TZID=America/Los_Angeles:20261122T230000\r\n"},
+		{name: "Ticket url", expected: "URL:https://www.wynnsocial.com/events/20261120\r\n"},
+		{name: "Venue location", expected: "LOCATION:xs nightclub\\, Las Vegas\\, NV\r\n"},
+		{name: "Commas are escaped", expected: "SUMMARY:martin garrix\\, alesso @ zouk nightclub\r\n"},
+		{name: "Calendar footer", expected: "END:VCALENDAR\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(ics, tt.expected) {
+				t.Errorf("Expected calendar to contain %q", tt.expected)
+			}
+		})
+	}
+
+	if count := strings.Count(ics, "BEGIN:VEVENT"); count != 3 {
+		t.Errorf("Expected 3 events, got %d", count)
+	}
+}
+
+// TestICSWriterFoldsLongLines tests that lines are folded at 75 octets without splitting runes
+func TestICSWriterFoldsLongLines(t *testing.T) {
+	var buf bytes.Buffer
+	edmEvent := EdmEvent{
+		Id:         "id-long",
+		ClubName:   "encore beach club",
+		ArtistName: strings.Repeat("tiësto b2b ", 12),
+		EventDate:  "2026-11-20T00:00:00Z",
+	}
+
+	err := writeCalendar(&buf, "Las Vegas EDM Events", []EdmEvent{edmEvent}, time.Now())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		if len(line) > icsLineLength {
+			t.Errorf("Expected lines of at most %d octets, got %d: %q", icsLineLength, len(line), line)
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("Expected valid UTF-8 on every folded line, got %q", line)
+		}
+	}
+
+	unfolded := strings.ReplaceAll(buf.String(), "\r\n ", "")
+	if !strings.Contains(unfolded, "SUMMARY:"+edmEvent.ArtistName+" @ encore beach club") {
+		t.Error("Expected the unfolded summary to match the artist name")
+	}
+}
+
+// TestCalendarFeedHandler tests the calendar feed and its artist and venue shortcuts
+func TestCalendarFeedHandler(t *testing.T) {
+	tests := []struct {
+		name               string
+		path               string
+		expectedStatusCode int
+		expectedEvents     int
+		expectedCalName    string
+	}{
+		{
+			name:               "All events",
+			path:               "/v1/calendar.ics",
+			expectedStatusCode: http.StatusOK,
+			expectedEvents:     3,
+			expectedCalName:    "X-WR-CALNAME:Las Vegas EDM Events\r\n",
+		},
+		{
+			name:               "Artist query filter",
+			path:               "/v1/calendar.ics?artist=Martin+Garrix",
+			expectedStatusCode: http.StatusOK,
+			expectedEvents:     2,
+		},
+		{
+			name:               "Artist shortcut",
+			path:               "/v1/artists/martin%20garrix/calendar.ics",
+			expectedStatusCode: http.StatusOK,
+			expectedEvents:     2,
+			expectedCalName:    "X-WR-CALNAME:Las Vegas EDM Events - martin garrix\r\n",
+		},
+		{
+			name:               "Venue shortcut",
+			path:               "/v1/venues/omnia/calendar.ics",
+			expectedStatusCode: http.StatusOK,
+			expectedEvents:     1,
+		},
+		{
+			name:               "Date range",
+			path:               "/v1/calendar.ics?from=2026-11-21&to=2026-11-21",
+			expectedStatusCode: http.StatusOK,
+			expectedEvents:     1,
+		},
+		{
+			name:               "Invalid date is rejected",
+			path:               "/v1/calendar.ics?from=21-11-2026",
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, calendarTestEvents)
+			rr := get(t, app, tt.path)
+
+			if rr.Code != tt.expectedStatusCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatusCode, rr.Code)
+			}
+			if tt.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			if contentType := rr.Header().Get("Content-Type"); contentType != "text/calendar; charset=utf-8" {
+				t.Errorf("Expected text/calendar content type, got '%s'", contentType)
+			}
+			if count := strings.Count(rr.Body.String(), "BEGIN:VEVENT"); count != tt.expectedEvents {
+				t.Errorf("Expected %d events, got %d", tt.expectedEvents, count)
+			}
+			if tt.expectedCalName != "" && !strings.Contains(rr.Body.String(), tt.expectedCalName) {
+				t.Errorf("Expected calendar name %q", tt.expectedCalName)
+			}
+		})
+	}
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// The chat platforms new events can be posted to, through their incoming webhooks.
+const (
+	chatTypeDiscord = "discord"
+	chatTypeSlack   = "slack"
+)
+
+// Limits of a single message. Discord allows 10 embeds and 6000 characters across them,
+// Slack 50 blocks.
+const (
+	discordMaxEmbeds          = 10
+	discordMaxEmbedCharacters = 6000
+	discordMaxTitleLength     = 256
+	discordMaxFieldLength     = 1024
+	slackMaxBlocks            = 50
+	slackMaxTextLength        = 3000
+)
+
+const (
+	// Attempts made to post a message before giving up on the rest of the channel's messages.
+	chatMaxAttempts = 3
+	// Longest wait for a rate limited post, a longer Retry-After fails the post.
+	chatMaxRetryAfter = 30 * time.Second
+)
+
+// errChatMessageRejected is returned when the platform refuses a message for good, such as
+// when the webhook was deleted, in which case posting it again won't help.
+var errChatMessageRejected = errors.New("message rejected")
+
+// chatChannel is a Discord or Slack channel new events are posted to. Without filters every
+// new event is posted. With venues or artists, only the events at one of the venues or of one
+// of the artists are, both matched as whole words ignoring case and accents.
+type chatChannel struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	URL     string   `json:"url"`
+	Venues  []string `json:"venues"`
+	Artists []string `json:"artists"`
+}
+
+// chatNotifier posts the events added by a sync to the configured channels.
+type chatNotifier struct {
+	channels []chatChannel
+	client   *http.Client
+	// retryDelay is doubled after every failed attempt, rate limited posts wait as long as
+	// the platform asks instead.
+	retryDelay time.Duration
+}
+
+// loadChatChannels reads the channels from a JSON file. Their webhook URLs are credentials,
+// which is why they're kept in a file rather than passed as flags.
+func loadChatChannels(path string) ([]chatChannel, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var channels []chatChannel
+	err = json.Unmarshal(content, &channels)
+	if err != nil {
+		return nil, fmt.Errorf("chat channels must be a JSON array of channels: %w", err)
+	}
+
+	for i, channel := range channels {
+		if channel.Name == "" {
+			return nil, fmt.Errorf("chat channel %d has no name", i)
+		}
+		if channel.Type != chatTypeDiscord && channel.Type != chatTypeSlack {
+			return nil, fmt.Errorf("chat channel %q has type %q, expected %s or %s", channel.Name, channel.Type, chatTypeDiscord, chatTypeSlack)
+		}
+		u, err := url.Parse(channel.URL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, fmt.Errorf("chat channel %q needs the absolute URL of an incoming webhook", channel.Name)
+		}
+	}
+
+	return channels, nil
+}
+
+func newChatNotifier(path string) (*chatNotifier, error) {
+	channels, err := loadChatChannels(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &chatNotifier{
+		channels:   channels,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		retryDelay: time.Second,
+	}, nil
+}
+
+// matches reports whether an event passes the channel's filters.
+func (c chatChannel) matches(edmEvent EdmEvent) bool {
+	if len(c.Venues) == 0 && len(c.Artists) == 0 {
+		return true
+	}
+
+	clubTokens := strings.Fields(normalizeSearchText(edmEvent.ClubName))
+	for _, venue := range c.Venues {
+		if artistMatches(strings.Fields(normalizeSearchText(venue)), clubTokens) {
+			return true
+		}
+	}
+
+	artistTokens := strings.Fields(normalizeSearchText(edmEvent.ArtistName))
+	for _, artist := range c.Artists {
+		if artistMatches(strings.Fields(normalizeSearchText(artist)), artistTokens) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// eventWhen describes when an event is on, such as "Saturday, November 21, 2026 at 10:30 PM".
+func eventWhen(edmEvent EdmEvent) string {
+	night := nightOf(edmEvent)
+	if night.IsZero() {
+		return edmEvent.EventDate
+	}
+
+	when := humanNight(night)
+	if t := startTime(edmEvent.EventDate); t != "" {
+		when += " at " + t
+	}
+	return when
+}
+
+func newShowsTitle(count int) string {
+	if count == 1 {
+		return "1 new show in Las Vegas"
+	}
+	return fmt.Sprintf("%d new shows in Las Vegas", count)
+}
+
+// truncate shortens s to at most max characters, ending it with an ellipsis when cut.
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:max-1]) + "…"
+}
+
+type discordMessage struct {
+	Content string         `json:"content,omitempty"`
+	Embeds  []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title     string         `json:"title"`
+	URL       string         `json:"url,omitempty"`
+	Color     int            `json:"color"`
+	Fields    []discordField `json:"fields"`
+	Thumbnail *discordImage  `json:"thumbnail,omitempty"`
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordImage struct {
+	URL string `json:"url"`
+}
+
+// characters counts the characters Discord holds against the limit of a message's embeds.
+func (e discordEmbed) characters() int {
+	n := utf8.RuneCountInString(e.Title)
+	for _, field := range e.Fields {
+		n += utf8.RuneCountInString(field.Name) + utf8.RuneCountInString(field.Value)
+	}
+	return n
+}
+
+// discordMessages renders the events as one embed each, the artist linking to the tickets,
+// split into as many messages as Discord's limits require. Webhooks that aren't owned by an
+// application can't send buttons, so the ticket link is also a field of its own.
+func discordMessages(edmEvents []EdmEvent) []discordMessage {
+	messages := []discordMessage{}
+	var current discordMessage
+	characters := 0
+
+	for _, edmEvent := range edmEvents {
+		embed := discordEmbed{
+			Title: truncate(edmEvent.ArtistName, discordMaxTitleLength),
+			URL:   edmEvent.TicketUrl,
+			Color: 0x9b59b6,
+			Fields: []discordField{
+				{Name: "Venue", Value: truncate(edmEvent.ClubName, discordMaxFieldLength), Inline: true},
+				{Name: "When", Value: truncate(eventWhen(edmEvent), discordMaxFieldLength), Inline: true},
+			},
+		}
+		if edmEvent.TicketUrl != "" {
+			embed.Fields = append(embed.Fields, discordField{Name: "Tickets", Value: truncate("[Buy tickets]("+edmEvent.TicketUrl+")", discordMaxFieldLength)})
+		}
+		if edmEvent.ArtistImageUrl != "" {
+			embed.Thumbnail = &discordImage{URL: edmEvent.ArtistImageUrl}
+		}
+
+		if len(current.Embeds) == discordMaxEmbeds || characters+embed.characters() > discordMaxEmbedCharacters {
+			messages = append(messages, current)
+			current = discordMessage{}
+			characters = 0
+		}
+		current.Embeds = append(current.Embeds, embed)
+		characters += embed.characters()
+	}
+
+	if len(current.Embeds) > 0 {
+		messages = append(messages, current)
+	}
+	if len(messages) > 0 {
+		messages[0].Content = "**" + newShowsTitle(len(edmEvents)) + "**"
+	}
+
+	return messages
+}
+
+type slackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type      string         `json:"type"`
+	Text      *slackText     `json:"text,omitempty"`
+	Accessory *slackElement  `json:"accessory,omitempty"`
+	Elements  []slackElement `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackElement struct {
+	Type     string     `json:"type"`
+	Text     *slackText `json:"text,omitempty"`
+	URL      string     `json:"url,omitempty"`
+	ImageURL string     `json:"image_url,omitempty"`
+	AltText  string     `json:"alt_text,omitempty"`
+}
+
+// slackEscape escapes the characters Slack's mrkdwn reserves for links and mentions.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// slackMessages renders the events as a section with the artist's image and a ticket button
+// each, split into as many messages as Slack's block limit requires.
+func slackMessages(edmEvents []EdmEvent) []slackMessage {
+	title := newShowsTitle(len(edmEvents))
+	messages := []slackMessage{}
+	current := slackMessage{Text: title, Blocks: []slackBlock{
+		{Type: "header", Text: &slackText{Type: "plain_text", Text: title}},
+	}}
+
+	for _, edmEvent := range edmEvents {
+		text := fmt.Sprintf("*%s*\n%s\n%s", slackEscape(edmEvent.ArtistName), slackEscape(edmEvent.ClubName), slackEscape(eventWhen(edmEvent)))
+		section := slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: truncate(text, slackMaxTextLength)}}
+		if edmEvent.ArtistImageUrl != "" {
+			section.Accessory = &slackElement{Type: "image", ImageURL: edmEvent.ArtistImageUrl, AltText: truncate(edmEvent.ArtistName, 2000)}
+		}
+		blocks := []slackBlock{section}
+
+		if edmEvent.TicketUrl != "" {
+			blocks = append(blocks, slackBlock{Type: "actions", Elements: []slackElement{
+				{Type: "button", Text: &slackText{Type: "plain_text", Text: "Tickets"}, URL: edmEvent.TicketUrl},
+			}})
+		}
+
+		if len(current.Blocks)+len(blocks) > slackMaxBlocks {
+			messages = append(messages, current)
+			current = slackMessage{Text: title}
+		}
+		current.Blocks = append(current.Blocks, blocks...)
+	}
+
+	if len(edmEvents) > 0 {
+		messages = append(messages, current)
+	}
+
+	return messages
+}
+
+// post sends one message to an incoming webhook, retrying failed attempts and waiting out
+// rate limits.
+func (n *chatNotifier) post(webhookURL string, message any) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	delay := n.retryDelay
+	for attempt := 1; ; attempt++ {
+		wait, err := n.attempt(webhookURL, body)
+		if err == nil || attempt == chatMaxAttempts || errors.Is(err, errChatMessageRejected) {
+			return err
+		}
+
+		if wait == 0 {
+			wait = delay
+			delay *= 2
+		}
+		if wait > chatMaxRetryAfter {
+			return fmt.Errorf("%w, asked to retry after %s", err, wait)
+		}
+		time.Sleep(wait)
+	}
+}
+
+// attempt makes one attempt at posting the body. When rate limited it also returns how long
+// the platform asked to wait.
+func (n *chatNotifier) attempt(webhookURL string, body []byte) (time.Duration, error) {
+	res, err := n.client.Post(webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		return 0, nil
+	}
+
+	err = fmt.Errorf("webhook responded with %s", res.Status)
+	switch {
+	case res.StatusCode == http.StatusTooManyRequests:
+		// Discord may send fractional seconds.
+		seconds, _ := strconv.ParseFloat(res.Header.Get("Retry-After"), 64)
+		return time.Duration(seconds * float64(time.Second)), err
+	case res.StatusCode >= 400 && res.StatusCode <= 499:
+		return 0, fmt.Errorf("%w: %v", errChatMessageRejected, err)
+	}
+	return 0, err
+}
+
+// notifyChatChannels posts the events a sync added to every channel whose filters they pass,
+// soonest first. A channel that can't be posted to doesn't keep the others from being posted.
+func (app *application) notifyChatChannels(created []EdmEvent) error {
+	if app.chat == nil || len(created) == 0 {
+		return nil
+	}
+
+	edmEvents := append([]EdmEvent{}, created...)
+	sort.SliceStable(edmEvents, func(i, j int) bool {
+		return edmEvents[i].EventDate < edmEvents[j].EventDate
+	})
+
+	var errs []error
+	for _, channel := range app.chat.channels {
+		channelEvents := []EdmEvent{}
+		for _, edmEvent := range edmEvents {
+			if channel.matches(edmEvent) {
+				channelEvents = append(channelEvents, edmEvent)
+			}
+		}
+
+		var messages []any
+		switch channel.Type {
+		case chatTypeDiscord:
+			for _, message := range discordMessages(channelEvents) {
+				messages = append(messages, message)
+			}
+		case chatTypeSlack:
+			for _, message := range slackMessages(channelEvents) {
+				messages = append(messages, message)
+			}
+		}
+
+		// Later messages would be out of order without the earlier ones, so a channel's
+		// remaining messages are dropped once one fails.
+		posted := 0
+		for i, message := range messages {
+			err := app.chat.post(channel.URL, message)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("posting message %d of %d to %s: %w", i+1, len(messages), channel.Name, err))
+				break
+			}
+			posted++
+		}
+		if posted > 0 && posted == len(messages) {
+			app.logger.Printf("Posted %d new events to %s", len(channelEvents), channel.Name)
+		}
+	}
+
+	return errors.Join(errs...)
+}
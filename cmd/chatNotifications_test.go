@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestLoadChatChannels tests reading and validating the channels file
+func TestLoadChatChannels(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		expectedError bool
+	}{
+		{name: "Valid", content: `[{"name": "team", "type": "discord", "url": "https://discord.com/api/webhooks/1/abc", "venues": ["XS"]}, {"name": "ops", "type": "slack", "url": "https://hooks.slack.com/services/T/B/x"}]`},
+		{name: "Not an array", content: `{"name": "team"}`, expectedError: true},
+		{name: "No name", content: `[{"type": "slack", "url": "https://hooks.slack.com/services/T/B/x"}]`, expectedError: true},
+		{name: "Unknown type", content: `[{"name": "team", "type": "teams", "url": "https://example.com"}]`, expectedError: true},
+		{name: "Relative URL", content: `[{"name": "team", "type": "slack", "url": "/services/T/B/x"}]`, expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "chat-channels.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			channels, err := loadChatChannels(path)
+			if tt.expectedError {
+				if err == nil {
+					t.Errorf("Expected an error, got %d channels", len(channels))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(channels) != 2 || channels[0].Venues[0] != "XS" || channels[1].Type != chatTypeSlack {
+				t.Errorf("Expected the 2 channels of the file, got %+v", channels)
+			}
+		})
+	}
+}
+
+// TestChatChannel_Matches tests the venue and artist filters of a channel
+func TestChatChannel_Matches(t *testing.T) {
+	edmEvent := EdmEvent{ClubName: "XS Nightclub", ArtistName: "Tiësto b2b Afrojack"}
+
+	tests := []struct {
+		name     string
+		channel  chatChannel
+		expected bool
+	}{
+		{name: "No filters", channel: chatChannel{}, expected: true},
+		{name: "Venue", channel: chatChannel{Venues: []string{"xs"}}, expected: true},
+		{name: "Other venue", channel: chatChannel{Venues: []string{"Omnia"}}, expected: false},
+		{name: "Artist", channel: chatChannel{Artists: []string{"Tiesto"}}, expected: true},
+		{name: "Other artist", channel: chatChannel{Artists: []string{"Kygo"}}, expected: false},
+		{name: "Either matches", channel: chatChannel{Venues: []string{"Omnia"}, Artists: []string{"Afrojack"}}, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.channel.matches(edmEvent); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// chatTestEvents returns n events at XS, each with an image and a ticket link.
+func chatTestEvents(n int) []EdmEvent {
+	edmEvents := []EdmEvent{}
+	for i := 0; i < n; i++ {
+		edmEvents = append(edmEvents, EdmEvent{
+			Id:             fmt.Sprintf("e%d", i),
+			ClubName:       "XS Nightclub",
+			ArtistName:     fmt.Sprintf("Artist %d", i),
+			EventDate:      "2026-11-21T22:30:00Z",
+			TicketUrl:      fmt.Sprintf("https://tickets.example.com/%d", i),
+			ArtistImageUrl: fmt.Sprintf("https://images.example.com/%d.jpg", i),
+		})
+	}
+	return edmEvents
+}
+
+// TestDiscordMessages tests the embeds of new events and their batching within Discord's
+// limits
+func TestDiscordMessages(t *testing.T) {
+	messages := discordMessages(chatTestEvents(25))
+
+	if len(messages) != 3 {
+		t.Fatalf("Expected 3 messages of at most 10 embeds, got %d", len(messages))
+	}
+	for i, expected := range []int{10, 10, 5} {
+		if len(messages[i].Embeds) != expected {
+			t.Errorf("Expected %d embeds in message %d, got %d", expected, i+1, len(messages[i].Embeds))
+		}
+	}
+	if messages[0].Content != "**25 new shows in Las Vegas**" || messages[1].Content != "" {
+		t.Errorf("Expected only the first message to have the title, got '%s' and '%s'", messages[0].Content, messages[1].Content)
+	}
+
+	embed := messages[0].Embeds[0]
+	if embed.Title != "Artist 0" || embed.URL != "https://tickets.example.com/0" {
+		t.Errorf("Expected the artist linking to the tickets, got '%s' linking to '%s'", embed.Title, embed.URL)
+	}
+	if embed.Thumbnail == nil || embed.Thumbnail.URL != "https://images.example.com/0.jpg" {
+		t.Errorf("Expected the artist's image, got %+v", embed.Thumbnail)
+	}
+	if len(embed.Fields) != 3 || embed.Fields[1].Value != "Saturday, November 21, 2026 at 10:30 PM" || !strings.Contains(embed.Fields[2].Value, "https://tickets.example.com/0") {
+		t.Errorf("Expected venue, night and ticket fields, got %+v", embed.Fields)
+	}
+
+	// Long names fill the 6000 characters of a message before its 10 embeds.
+	edmEvents := chatTestEvents(5)
+	for i := range edmEvents {
+		edmEvents[i].ArtistName = strings.Repeat("a", 300)
+		edmEvents[i].ClubName = strings.Repeat("v", 2000)
+	}
+	messages = discordMessages(edmEvents)
+	for _, message := range messages {
+		characters := 0
+		for _, embed := range message.Embeds {
+			characters += embed.characters()
+			if len([]rune(embed.Title)) > discordMaxTitleLength {
+				t.Errorf("Expected titles of at most %d characters, got %d", discordMaxTitleLength, len([]rune(embed.Title)))
+			}
+		}
+		if characters > discordMaxEmbedCharacters {
+			t.Errorf("Expected at most %d characters per message, got %d", discordMaxEmbedCharacters, characters)
+		}
+	}
+	if len(messages) != 2 {
+		t.Errorf("Expected 2 messages, got %d", len(messages))
+	}
+}
+
+// TestSlackMessages tests the blocks of new events and their batching within Slack's limits
+func TestSlackMessages(t *testing.T) {
+	edmEvents := chatTestEvents(30)
+	edmEvents[0].ArtistName = "Above & Beyond <live>"
+
+	messages := slackMessages(edmEvents)
+
+	if len(messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(messages))
+	}
+	for i, message := range messages {
+		if len(message.Blocks) > slackMaxBlocks {
+			t.Errorf("Expected at most %d blocks in message %d, got %d", slackMaxBlocks, i+1, len(message.Blocks))
+		}
+		if message.Text != "30 new shows in Las Vegas" {
+			t.Errorf("Expected the notification text '30 new shows in Las Vegas', got '%s'", message.Text)
+		}
+	}
+
+	blocks := messages[0].Blocks
+	if blocks[0].Type != "header" {
+		t.Errorf("Expected the first message to start with a header, got '%s'", blocks[0].Type)
+	}
+
+	section := blocks[1]
+	if !strings.HasPrefix(section.Text.Text, "*Above &amp; Beyond &lt;live&gt;*\nXS Nightclub\nSaturday, November 21, 2026 at 10:30 PM") {
+		t.Errorf("Expected the escaped artist, venue and night, got '%s'", section.Text.Text)
+	}
+	if section.Accessory == nil || section.Accessory.ImageURL != "https://images.example.com/0.jpg" {
+		t.Errorf("Expected the artist's image, got %+v", section.Accessory)
+	}
+
+	actions := blocks[2]
+	if actions.Type != "actions" || actions.Elements[0].Type != "button" || actions.Elements[0].URL != "https://tickets.example.com/0" {
+		t.Errorf("Expected a ticket button, got %+v", actions)
+	}
+
+	if messages := slackMessages(nil); len(messages) != 0 {
+		t.Errorf("Expected no messages without events, got %d", len(messages))
+	}
+}
+
+// chatReceiver is a test incoming webhook recording the messages posted to it. The first
+// rateLimited requests are answered with a 429.
+type chatReceiver struct {
+	mu          sync.Mutex
+	bodies      []string
+	rateLimited int
+	requests    int
+}
+
+func (cr *chatReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	cr.requests++
+	if cr.requests <= cr.rateLimited {
+		w.Header().Set("Retry-After", "0.01")
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+	cr.bodies = append(cr.bodies, string(body))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TestNotifyChatChannels tests posting new events to the channels whose filters they pass
+func TestNotifyChatChannels(t *testing.T) {
+	discord := &chatReceiver{rateLimited: 1}
+	discordServer := httptest.NewServer(discord)
+	defer discordServer.Close()
+
+	slack := &chatReceiver{}
+	slackServer := httptest.NewServer(slack)
+	defer slackServer.Close()
+
+	failingRequests := 0
+	failingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failingRequests++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer failingServer.Close()
+
+	app := newTestApplication(t, nil)
+	app.chat = &chatNotifier{
+		client:     http.DefaultClient,
+		retryDelay: time.Millisecond,
+		channels: []chatChannel{
+			{Name: "broken", Type: chatTypeSlack, URL: failingServer.URL},
+			{Name: "team", Type: chatTypeDiscord, URL: discordServer.URL},
+			{Name: "omnia", Type: chatTypeSlack, URL: slackServer.URL, Venues: []string{"Omnia"}},
+		},
+	}
+
+	created := []EdmEvent{
+		{Id: "e1", ClubName: "XS Nightclub", ArtistName: "Kygo", EventDate: "2026-11-21T00:00:00Z"},
+		{Id: "e2", ClubName: "Omnia", ArtistName: "Tiësto", EventDate: "2026-11-20T00:00:00Z"},
+	}
+
+	err := app.notifyChatChannels(created)
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("Expected an error for the broken channel, got %v", err)
+	}
+	if failingRequests != 1 {
+		t.Errorf("Expected a deleted webhook not to be retried, got %d requests", failingRequests)
+	}
+
+	if len(discord.bodies) != 1 {
+		t.Fatalf("Expected 1 Discord message after the rate limit, got %d", len(discord.bodies))
+	}
+	var discordMessage discordMessage
+	if err := json.Unmarshal([]byte(discord.bodies[0]), &discordMessage); err != nil {
+		t.Fatal(err)
+	}
+	if len(discordMessage.Embeds) != 2 || discordMessage.Embeds[0].Title != "Tiësto" {
+		t.Errorf("Expected both events soonest first, got %+v", discordMessage.Embeds)
+	}
+
+	if len(slack.bodies) != 1 {
+		t.Fatalf("Expected 1 Slack message, got %d", len(slack.bodies))
+	}
+	if !strings.Contains(slack.bodies[0], "Tiësto") || strings.Contains(slack.bodies[0], "Kygo") {
+		t.Errorf("Expected only the Omnia event, got %s", slack.bodies[0])
+	}
+
+	// Nothing is posted for a sync without new events.
+	err = app.notifyChatChannels(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(discord.bodies) != 1 || len(slack.bodies) != 1 {
+		t.Errorf("Expected no more messages, got %d and %d", len(discord.bodies), len(slack.bodies))
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"net/http"
+)
+
+// Define a custom contextKey type, with the underlying type string, so our keys can't
+// collide with keys set by other packages.
+type contextKey string
+
+const (
+	edmEventLoaderContextKey = contextKey("edmEventLoader")
+	apiKeyContextKey         = contextKey("apiKey")
+	requestIDContextKey      = contextKey("requestID")
+)
+
+// contextSetEdmEventLoader returns a copy of the request with the loader added to its context.
+func (app *application) contextSetEdmEventLoader(r *http.Request, loader *edmEventLoader) *http.Request {
+	ctx := context.WithValue(r.Context(), edmEventLoaderContextKey, loader)
+	return r.WithContext(ctx)
+}
+
+// contextGetEdmEventLoader retrieves the loader from the context. The only time we'll use
+// this helper is when we logically expect there to be a loader in the context, and if it
+// doesn't exist it will firmly be an 'unexpected' error, so we panic.
+func (app *application) contextGetEdmEventLoader(ctx context.Context) *edmEventLoader {
+	loader, ok := ctx.Value(edmEventLoaderContextKey).(*edmEventLoader)
+	if !ok {
+		panic("missing edmEventLoader value in request context")
+	}
+	return loader
+}
+
+// contextSetAPIKey returns a copy of the request with the authenticated key added to its
+// context.
+func (app *application) contextSetAPIKey(r *http.Request, apiKey APIKey) *http.Request {
+	ctx := context.WithValue(r.Context(), apiKeyContextKey, apiKey)
+	return r.WithContext(ctx)
+}
+
+// contextGetAPIKey retrieves the key the request was authenticated with. Keys are optional,
+// so the second return value reports whether the request sent one.
+func (app *application) contextGetAPIKey(r *http.Request) (APIKey, bool) {
+	apiKey, ok := r.Context().Value(apiKeyContextKey).(APIKey)
+	return apiKey, ok
+}
+
+// contextSetRequestID returns a copy of the request with its id added to its context.
+func (app *application) contextSetRequestID(r *http.Request, requestID string) *http.Request {
+	ctx := context.WithValue(r.Context(), requestIDContextKey, requestID)
+	return r.WithContext(ctx)
+}
+
+// contextGetRequestID retrieves the id of the request, or an empty string for requests that
+// didn't go through the requestID middleware.
+func (app *application) contextGetRequestID(r *http.Request) string {
+	requestID, _ := r.Context().Value(requestIDContextKey).(string)
+	return requestID
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The methods and request headers preflight requests are answered with unless configured
+// otherwise. The headers cover API keys, conditional requests and resuming streams.
+var (
+	defaultCORSMethods = []string{http.MethodGet, http.MethodPost, http.MethodPatch, http.MethodDelete, http.MethodOptions}
+	defaultCORSHeaders = []string{"Authorization", "Content-Type", "X-API-Key", "If-None-Match", "If-Modified-Since", "Last-Event-ID", "X-Request-ID"}
+)
+
+// Response headers browsers only let scripts read when they are exposed.
+var corsExposedHeaders = []string{"ETag", "Location", "Retry-After", "X-Request-ID"}
+
+// corsOrigin is an allowed origin. With wildcard set, host is a domain whose subdomains are
+// allowed, but not the domain itself.
+type corsOrigin struct {
+	scheme   string
+	host     string
+	wildcard bool
+}
+
+// parseCORSOrigin parses an origin as scheme://host[:port], where the host may start with
+// "*." to allow every subdomain of a domain.
+func parseCORSOrigin(origin string) (corsOrigin, error) {
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return corsOrigin{}, fmt.Errorf("invalid CORS origin %q, expected scheme://host[:port]", origin)
+	}
+
+	host := strings.ToLower(u.Host)
+	if domain, ok := strings.CutPrefix(host, "*."); ok {
+		if domain == "" || strings.Contains(domain, "*") {
+			return corsOrigin{}, fmt.Errorf("invalid CORS origin %q, expected scheme://*.domain", origin)
+		}
+		return corsOrigin{scheme: u.Scheme, host: domain, wildcard: true}, nil
+	}
+	if strings.Contains(host, "*") {
+		return corsOrigin{}, fmt.Errorf("invalid CORS origin %q, wildcards are only allowed as the first label", origin)
+	}
+
+	return corsOrigin{scheme: u.Scheme, host: host}, nil
+}
+
+func (o corsOrigin) matches(origin string) bool {
+	scheme, host, ok := strings.Cut(strings.ToLower(origin), "://")
+	if !ok || scheme != o.scheme || host == "" || strings.ContainsAny(host, "/?#@") {
+		return false
+	}
+
+	if o.wildcard {
+		return strings.HasSuffix(host, "."+o.host)
+	}
+	return host == o.host
+}
+
+// corsPolicy holds the parsed CORS configuration.
+type corsPolicy struct {
+	allowAll         bool
+	origins          []corsOrigin
+	methods          []string
+	headers          []string
+	allowCredentials bool
+	maxAge           int
+}
+
+// newCORSPolicy parses the CORS configuration. "*" allows every origin, which is refused with
+// credentials as it would let any site make authenticated requests on a user's behalf.
+func newCORSPolicy(cfg config) (*corsPolicy, error) {
+	policy := &corsPolicy{
+		methods:          cfg.cors.allowedMethods,
+		headers:          cfg.cors.allowedHeaders,
+		allowCredentials: cfg.cors.allowCredentials,
+		maxAge:           int(cfg.cors.maxAge.Seconds()),
+	}
+
+	for _, origin := range cfg.cors.trustedOrigins {
+		if origin == "*" {
+			if cfg.cors.allowCredentials {
+				return nil, errors.New("the CORS origin * can't be used with credentials, list the trusted origins instead")
+			}
+			policy.allowAll = true
+			continue
+		}
+
+		o, err := parseCORSOrigin(origin)
+		if err != nil {
+			return nil, err
+		}
+		policy.origins = append(policy.origins, o)
+	}
+
+	return policy, nil
+}
+
+func (p *corsPolicy) allowsOrigin(origin string) bool {
+	if p.allowAll {
+		return true
+	}
+	for _, o := range p.origins {
+		if o.matches(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *corsPolicy) allowsHeaders(requestHeaders string) bool {
+	for _, header := range strings.Split(requestHeaders, ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		if !slices.ContainsFunc(p.headers, func(h string) bool { return strings.EqualFold(h, header) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// enableCORS adds the CORS headers for requests from trusted origins and answers their
+// preflight requests. Requests from other origins are served without CORS headers, so browsers
+// don't let the calling page read the response, and their preflight requests are refused.
+func (app *application) enableCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.cors == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if preflight {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if !app.cors.allowsOrigin(origin) {
+			if preflight {
+				app.corsPreflightRejectedResponse(w, r, "origin not allowed")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if preflight {
+			if !slices.Contains(app.cors.methods, r.Header.Get("Access-Control-Request-Method")) {
+				app.corsPreflightRejectedResponse(w, r, "method not allowed")
+				return
+			}
+			if !app.cors.allowsHeaders(r.Header.Get("Access-Control-Request-Headers")) {
+				app.corsPreflightRejectedResponse(w, r, "request headers not allowed")
+				return
+			}
+		}
+
+		// Without credentials every origin gets the same answer, which caches can share.
+		if app.cors.allowAll && !app.cors.allowCredentials {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		if app.cors.allowCredentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			w.Header().Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(app.cors.methods, ", "))
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(app.cors.headers, ", "))
+		if app.cors.maxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(app.cors.maxAge))
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
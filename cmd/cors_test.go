@@ -0,0 +1,171 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+// newCORSTestApplication returns a test application with the given CORS configuration
+func newCORSTestApplication(t *testing.T, trustedOrigins []string, allowCredentials bool) *application {
+	t.Helper()
+
+	app := newTestApplication(t, []EdmEvent{
+		{Id: "1", ArtistName: "Tiësto", ClubName: "XS", EventDate: "2099-01-01T22:00:00Z"},
+	})
+
+	var cfg config
+	cfg.cors.trustedOrigins = trustedOrigins
+	cfg.cors.allowedMethods = defaultCORSMethods
+	cfg.cors.allowedHeaders = defaultCORSHeaders
+	cfg.cors.allowCredentials = allowCredentials
+	cfg.cors.maxAge = time.Hour
+
+	policy, err := newCORSPolicy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.cors = policy
+
+	return app
+}
+
+// TestNewCORSPolicy tests which CORS configurations are accepted
+func TestNewCORSPolicy(t *testing.T) {
+	tests := []struct {
+		name             string
+		trustedOrigins   []string
+		allowCredentials bool
+		expectError      bool
+	}{
+		{name: "Origins", trustedOrigins: []string{"https://example.com", "http://localhost:3000"}},
+		{name: "Wildcard subdomains", trustedOrigins: []string{"https://*.example.com"}, allowCredentials: true},
+		{name: "Any origin", trustedOrigins: []string{"*"}},
+		{name: "Any origin with credentials", trustedOrigins: []string{"*"}, allowCredentials: true, expectError: true},
+		{name: "Missing scheme", trustedOrigins: []string{"example.com"}, expectError: true},
+		{name: "Path", trustedOrigins: []string{"https://example.com/app"}, expectError: true},
+		{name: "Wildcard in the middle", trustedOrigins: []string{"https://app.*.example.com"}, expectError: true},
+		{name: "Bare wildcard host", trustedOrigins: []string{"https://*."}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg config
+			cfg.cors.trustedOrigins = tt.trustedOrigins
+			cfg.cors.allowCredentials = tt.allowCredentials
+
+			_, err := newCORSPolicy(cfg)
+			if tt.expectError && err == nil {
+				t.Error("Expected an error, got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+// TestEnableCORS tests the CORS headers of simple requests
+func TestEnableCORS(t *testing.T) {
+	tests := []struct {
+		name                string
+		trustedOrigins      []string
+		allowCredentials    bool
+		origin              string
+		expectedAllowOrigin string
+		expectedCredentials string
+	}{
+		{name: "Trusted origin", trustedOrigins: []string{"https://example.com"}, origin: "https://example.com", expectedAllowOrigin: "https://example.com"},
+		{name: "Untrusted origin", trustedOrigins: []string{"https://example.com"}, origin: "https://evil.com"},
+		{name: "Other scheme", trustedOrigins: []string{"https://example.com"}, origin: "http://example.com"},
+		{name: "Other port", trustedOrigins: []string{"https://example.com"}, origin: "https://example.com:8443"},
+		{name: "Subdomain", trustedOrigins: []string{"https://*.example.com"}, origin: "https://app.example.com", expectedAllowOrigin: "https://app.example.com"},
+		{name: "Nested subdomain", trustedOrigins: []string{"https://*.example.com"}, origin: "https://a.b.example.com", expectedAllowOrigin: "https://a.b.example.com"},
+		{name: "Wildcard doesn't match the domain itself", trustedOrigins: []string{"https://*.example.com"}, origin: "https://example.com"},
+		{name: "Wildcard doesn't match a suffix", trustedOrigins: []string{"https://*.example.com"}, origin: "https://evilexample.com"},
+		{name: "Any origin", trustedOrigins: []string{"*"}, origin: "https://anything.com", expectedAllowOrigin: "*"},
+		{name: "Credentials", trustedOrigins: []string{"https://*.example.com"}, allowCredentials: true, origin: "https://app.example.com", expectedAllowOrigin: "https://app.example.com", expectedCredentials: "true"},
+		{name: "Credentials with an untrusted origin", trustedOrigins: []string{"https://*.example.com"}, allowCredentials: true, origin: "https://evil.com"},
+		{name: "No origins configured", origin: "https://example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newCORSTestApplication(t, tt.trustedOrigins, tt.allowCredentials)
+
+			r := httptest.NewRequest(http.MethodGet, "/v1/events", nil)
+			r.Header.Set("Origin", tt.origin)
+			rr := httptest.NewRecorder()
+			app.routes().ServeHTTP(rr, r)
+
+			if rr.Code != http.StatusOK {
+				t.Errorf("Expected status %d, got %d", http.StatusOK, rr.Code)
+			}
+			if allowOrigin := rr.Header().Get("Access-Control-Allow-Origin"); allowOrigin != tt.expectedAllowOrigin {
+				t.Errorf("Expected Access-Control-Allow-Origin '%s', got '%s'", tt.expectedAllowOrigin, allowOrigin)
+			}
+			if credentials := rr.Header().Get("Access-Control-Allow-Credentials"); credentials != tt.expectedCredentials {
+				t.Errorf("Expected Access-Control-Allow-Credentials '%s', got '%s'", tt.expectedCredentials, credentials)
+			}
+			if vary := rr.Header().Values("Vary"); !slices.Contains(vary, "Origin") {
+				t.Errorf("Expected Vary to contain 'Origin', got %v", vary)
+			}
+		})
+	}
+}
+
+// TestEnableCORS_Preflight tests the answers to preflight requests
+func TestEnableCORS_Preflight(t *testing.T) {
+	tests := []struct {
+		name           string
+		origin         string
+		method         string
+		headers        string
+		expectedStatus int
+	}{
+		{name: "Allowed", origin: "https://app.example.com", method: http.MethodPost, headers: "Authorization, content-type", expectedStatus: http.StatusNoContent},
+		{name: "Conditional GET", origin: "https://app.example.com", method: http.MethodGet, headers: "If-None-Match, If-Modified-Since", expectedStatus: http.StatusNoContent},
+		{name: "Disallowed origin", origin: "https://evil.com", method: http.MethodGet, expectedStatus: http.StatusForbidden},
+		{name: "Disallowed method", origin: "https://app.example.com", method: http.MethodPut, expectedStatus: http.StatusForbidden},
+		{name: "Disallowed header", origin: "https://app.example.com", method: http.MethodGet, headers: "X-Custom", expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newCORSTestApplication(t, []string{"https://*.example.com"}, true)
+			app.config.apiKeys.require = true
+
+			r := httptest.NewRequest(http.MethodOptions, "/v1/admin/runs", nil)
+			r.Header.Set("Origin", tt.origin)
+			r.Header.Set("Access-Control-Request-Method", tt.method)
+			if tt.headers != "" {
+				r.Header.Set("Access-Control-Request-Headers", tt.headers)
+			}
+			rr := httptest.NewRecorder()
+			app.routes().ServeHTTP(rr, r)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+
+			if tt.expectedStatus != http.StatusNoContent {
+				if allowOrigin := rr.Header().Get("Access-Control-Allow-Origin"); allowOrigin != "" {
+					t.Errorf("Expected no Access-Control-Allow-Origin, got '%s'", allowOrigin)
+				}
+				return
+			}
+
+			if allowOrigin := rr.Header().Get("Access-Control-Allow-Origin"); allowOrigin != tt.origin {
+				t.Errorf("Expected Access-Control-Allow-Origin '%s', got '%s'", tt.origin, allowOrigin)
+			}
+			if methods := rr.Header().Get("Access-Control-Allow-Methods"); methods != "GET, POST, PATCH, DELETE, OPTIONS" {
+				t.Errorf("Expected Access-Control-Allow-Methods 'GET, POST, PATCH, DELETE, OPTIONS', got '%s'", methods)
+			}
+			if maxAge := rr.Header().Get("Access-Control-Max-Age"); maxAge != "3600" {
+				t.Errorf("Expected Access-Control-Max-Age '3600', got '%s'", maxAge)
+			}
+		})
+	}
+}
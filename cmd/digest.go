@@ -0,0 +1,366 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// The formats a digest renders as, the Markdown doubling as the plain text of its email.
+const (
+	digestFormatJSON     = "json"
+	digestFormatMarkdown = "markdown"
+	digestFormatHTML     = "html"
+)
+
+var digestFormats = []string{digestFormatJSON, digestFormatMarkdown, digestFormatHTML}
+
+const digestTemplate = "digest.tmpl"
+
+const digestUsage = "usage: digest [-to ADDRESSES] [-format json|markdown|html] [-dry-run]"
+
+// digest is "This weekend in Vegas": the events of the coming Thursday through Sunday nights,
+// grouped by night and venue.
+type digest struct {
+	Title       string        `json:"title"`
+	From        string        `json:"from"`
+	To          string        `json:"to"`
+	GeneratedAt string        `json:"generated_at"`
+	NewSince    string        `json:"new_since,omitempty"`
+	EventCount  int           `json:"event_count"`
+	NewCount    int           `json:"new_count"`
+	Nights      []digestNight `json:"nights"`
+}
+
+type digestNight struct {
+	Date       string        `json:"date"`
+	EventCount int           `json:"event_count"`
+	Venues     []digestVenue `json:"venues"`
+}
+
+type digestVenue struct {
+	Venue  string        `json:"venue"`
+	Events []digestEvent `json:"events"`
+}
+
+// digestEvent is an event of the digest, New when it was announced after the previous digest.
+type digestEvent struct {
+	EdmEvent
+	New bool `json:"new"`
+}
+
+// Name returns the night as the templates print it, such as "Friday, October 23, 2026".
+func (n digestNight) Name() string {
+	t, _ := time.Parse(queryDateFormat, n.Date)
+	return humanNight(t)
+}
+
+// DigestRecord remembers a digest that was sent, the next one highlights the events
+// announced since.
+type DigestRecord struct {
+	ID          string `json:"id"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	GeneratedAt string `json:"generated_at"`
+	EventCount  int    `json:"event_count"`
+	NewCount    int    `json:"new_count"`
+}
+
+type DigestModelInterface interface {
+	Insert(record DigestRecord) error
+	Latest() (DigestRecord, error)
+}
+
+// DigestModel stores one document per digest sent, keyed by the record's id.
+type DigestModel struct {
+	Client     *firestore.Client
+	Collection string
+}
+
+func (m *DigestModel) Insert(record DigestRecord) error {
+	ctx := context.Background()
+	_, err := m.Client.Collection(m.Collection).Doc(record.ID).Create(ctx, record)
+	if err != nil {
+		return fmt.Errorf("failed to insert digest record: %v", err)
+	}
+	return nil
+}
+
+// Latest returns the most recent digest, errRecordNotFound before the first one.
+func (m *DigestModel) Latest() (DigestRecord, error) {
+	ctx := context.Background()
+
+	iter := m.Client.Collection(m.Collection).OrderBy("GeneratedAt", firestore.Desc).Limit(1).Documents(ctx)
+	defer iter.Stop()
+
+	doc, err := iter.Next()
+	if err == iterator.Done {
+		return DigestRecord{}, errRecordNotFound
+	}
+	if err != nil {
+		return DigestRecord{}, fmt.Errorf("failed to get the latest digest: %v", err)
+	}
+
+	var record DigestRecord
+	if err := doc.DataTo(&record); err != nil {
+		return DigestRecord{}, fmt.Errorf("failed to decode digest %s: %v", doc.Ref.ID, err)
+	}
+	return record, nil
+}
+
+// digestWeekend returns the Thursday through Sunday nights a digest made today covers. From
+// Monday to Wednesday that's the coming weekend, from Thursday on it's the rest of the
+// current one.
+func digestWeekend(today time.Time) (from time.Time, to time.Time) {
+	// Days since Thursday, Sunday being the last night of the weekend.
+	sinceThursday := (int(today.Weekday()) + 3) % 7
+	thursday := today.AddDate(0, 0, -sinceThursday)
+	if sinceThursday > 3 {
+		thursday = today.AddDate(0, 0, 7-sinceThursday)
+	}
+
+	from = thursday
+	if today.After(from) {
+		from = today
+	}
+	return from, thursday.AddDate(0, 0, 3)
+}
+
+// digestTitle names the weekend, such as "This weekend in Vegas, October 22–25".
+func digestTitle(from, to time.Time) string {
+	if from.Month() == to.Month() {
+		return fmt.Sprintf("This weekend in Vegas, %s %d–%d", from.Format("January"), from.Day(), to.Day())
+	}
+	return fmt.Sprintf("This weekend in Vegas, %s – %s", from.Format("January 2"), to.Format("January 2"))
+}
+
+// buildDigest lays out the events of the weekend that starts from today. Events first seen
+// after the previous digest was generated are marked new, without a previous digest none are.
+func buildDigest(edmEvents []EdmEvent, today time.Time, previous *DigestRecord, now time.Time) digest {
+	from, to := digestWeekend(today)
+
+	d := digest{
+		Title:       digestTitle(from, to),
+		From:        from.Format(queryDateFormat),
+		To:          to.Format(queryDateFormat),
+		GeneratedAt: now.UTC().Format(time.RFC3339),
+		Nights:      []digestNight{},
+	}
+	if previous != nil {
+		d.NewSince = previous.GeneratedAt
+	}
+
+	// The last night goes on past midnight into Monday.
+	edmEvents = filterEdmEvents(edmEvents, EventFilters{From: d.From, To: to.AddDate(0, 0, 1).Format(queryDateFormat)})
+
+	for _, n := range groupByNight(edmEvents) {
+		date := n.Date.Format(queryDateFormat)
+		if date < d.From || date > d.To {
+			continue
+		}
+
+		dn := digestNight{Date: date, EventCount: len(n.Events), Venues: []digestVenue{}}
+		for _, venue := range groupByVenue(n.Events) {
+			dv := digestVenue{Venue: venue.Venue}
+			for _, edmEvent := range venue.Events {
+				isNew := d.NewSince != "" && edmEvent.FirstSeen > d.NewSince
+				if isNew {
+					d.NewCount++
+				}
+				dv.Events = append(dv.Events, digestEvent{EdmEvent: edmEvent, New: isNew})
+			}
+			dn.Venues = append(dn.Venues, dv)
+		}
+
+		d.EventCount += dn.EventCount
+		d.Nights = append(d.Nights, dn)
+	}
+
+	return d
+}
+
+// currentDigest builds the digest of the coming weekend from the stored events, highlighting
+// what's new since the last digest that was sent.
+func (app *application) currentDigest(now time.Time) (digest, error) {
+	edmEvents, err := app.dbSnippets.GetAll()
+	if err != nil {
+		return digest{}, err
+	}
+
+	var previous *DigestRecord
+	record, err := app.dbDigests.Latest()
+	switch {
+	case err == nil:
+		previous = &record
+	case !errors.Is(err, errRecordNotFound):
+		return digest{}, err
+	}
+
+	today, err := time.Parse(queryDateFormat, lasVegasToday(now))
+	if err != nil {
+		return digest{}, err
+	}
+
+	return buildDigest(edmEvents, today, previous, now), nil
+}
+
+// writeDigest renders the digest in the given format, the Markdown and the HTML being the
+// plain text and the HTML body of its email.
+func writeDigest(w io.Writer, d digest, format string) error {
+	if format == digestFormatJSON {
+		js, err := json.MarshalIndent(d, "", "\t")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(js, '\n'))
+		return err
+	}
+
+	_, markdown, html, err := renderEmail(digestTemplate, d)
+	if err != nil {
+		return err
+	}
+	if format == digestFormatHTML {
+		_, err = io.WriteString(w, html+"\n")
+	} else {
+		_, err = io.WriteString(w, markdown+"\n")
+	}
+	return err
+}
+
+// digestHandler returns the digest of the coming weekend, as JSON by default or as the
+// Markdown or HTML of its email with the format parameter.
+func (app *application) digestHandler(w http.ResponseWriter, r *http.Request) {
+	v := newValidator()
+
+	format := app.readString(r.URL.Query(), "format", digestFormatJSON)
+	v.Check(slices.Contains(digestFormats, format), "format", "must be one of "+strings.Join(digestFormats, ", "))
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	d, err := app.currentDigest(time.Now())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if format == digestFormatJSON {
+		err = app.writeJSON(w, http.StatusOK, envelope{"digest": d}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var body strings.Builder
+	err = writeDigest(&body, d, format)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	contentType := "text/markdown; charset=utf-8"
+	if format == digestFormatHTML {
+		contentType = "text/html; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	io.WriteString(w, body.String())
+}
+
+// digestCommand builds the digest of the coming weekend, writes it out and emails it. Unless
+// it's a dry run the digest is recorded, so that the next one highlights the events announced
+// after it.
+func (app *application) digestCommand(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("digest", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+
+	to := flags.String("to", "", "Addresses to email the digest to, separated by spaces or commas")
+	format := flags.String("format", digestFormatMarkdown, "Format the digest is written out in (json|markdown|html)")
+	dryRun := flags.Bool("dry-run", false, "Only write the digest out, without emailing or recording it")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return fmt.Errorf("%v, %s", err, digestUsage)
+	}
+
+	recipients := splitList(*to)
+
+	v := newValidator()
+	v.Check(slices.Contains(digestFormats, *format), "format", "must be one of "+strings.Join(digestFormats, ", "))
+	for _, recipient := range recipients {
+		v.Check(Matches(recipient, EmailRX), "to", fmt.Sprintf("%q is not a valid email address", recipient))
+	}
+	v.Check(len(recipients) == 0 || app.mailer != nil || *dryRun, "to", "emailing the digest needs an SMTP server, set -smtp-host")
+	if !v.Valid() {
+		return fmt.Errorf("invalid digest: %v", v.Errors)
+	}
+
+	now := time.Now()
+	d, err := app.currentDigest(now)
+	if err != nil {
+		return err
+	}
+
+	err = writeDigest(out, d, *format)
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		return nil
+	}
+
+	record := DigestRecord{
+		ID:          getGUID(),
+		From:        d.From,
+		To:          d.To,
+		GeneratedAt: d.GeneratedAt,
+		EventCount:  d.EventCount,
+		NewCount:    d.NewCount,
+	}
+
+	var errs []error
+	for _, recipient := range recipients {
+		attempts, err := app.mailer.Send(recipient, digestTemplate, d)
+
+		entry := SendLogEntry{
+			ID:        getGUID(),
+			Channel:   channelEmail,
+			Recipient: recipient,
+			DigestID:  record.ID,
+			Status:    sendStatusSent,
+			Attempts:  attempts,
+			CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("emailing the digest to %s: %w", recipient, err))
+			entry.Status = sendStatusFailed
+			entry.Error = err.Error()
+		}
+
+		err = app.dbSendLog.Insert(entry)
+		if err != nil {
+			app.logger.Printf("failed to record email to %s: %v", recipient, err)
+		}
+	}
+
+	// The digest is recorded even if an email failed, the others did go out.
+	err = app.dbDigests.Insert(record)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// digestTestEvents span the week of Monday, October 19, 2026.
+var digestTestEvents = []EdmEvent{
+	{Id: "wed", ClubName: "XS Nightclub", ArtistName: "Kaskade", EventDate: "2026-10-21T00:00:00Z", FirstSeen: "2026-10-01T06:00:00Z"},
+	{Id: "thu", ClubName: "Omnia", ArtistName: "Tiësto", EventDate: "2026-10-22T22:30:00Z", TicketUrl: "https://tickets.example.com/thu", FirstSeen: "2026-10-01T06:00:00Z"},
+	{Id: "fri-late", ClubName: "XS Nightclub", ArtistName: "John_Summit", EventDate: "2026-10-24T01:00:00Z", FirstSeen: "2026-10-18T06:00:00Z"},
+	{Id: "fri", ClubName: "Hakkasan", ArtistName: "Kygo", EventDate: "2026-10-23T00:00:00Z", FirstSeen: "2026-10-18T06:00:00Z"},
+	{Id: "sun", ClubName: "Omnia", ArtistName: "Alesso", EventDate: "2026-10-25T00:00:00Z", FirstSeen: "2026-10-01T06:00:00Z"},
+	{Id: "mon", ClubName: "Omnia", ArtistName: "Zedd", EventDate: "2026-10-26T00:00:00Z", FirstSeen: "2026-10-18T06:00:00Z"},
+}
+
+// TestDigestWeekend tests the nights a digest covers depending on the day it's made
+func TestDigestWeekend(t *testing.T) {
+	tests := []struct {
+		today        string
+		expectedFrom string
+		expectedTo   string
+	}{
+		{today: "2026-10-19", expectedFrom: "2026-10-22", expectedTo: "2026-10-25"},
+		{today: "2026-10-21", expectedFrom: "2026-10-22", expectedTo: "2026-10-25"},
+		{today: "2026-10-22", expectedFrom: "2026-10-22", expectedTo: "2026-10-25"},
+		{today: "2026-10-24", expectedFrom: "2026-10-24", expectedTo: "2026-10-25"},
+		{today: "2026-10-25", expectedFrom: "2026-10-25", expectedTo: "2026-10-25"},
+		{today: "2026-10-26", expectedFrom: "2026-10-29", expectedTo: "2026-11-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.today, func(t *testing.T) {
+			today, _ := time.Parse(queryDateFormat, tt.today)
+			from, to := digestWeekend(today)
+			if got := from.Format(queryDateFormat); got != tt.expectedFrom {
+				t.Errorf("Expected from %s, got %s", tt.expectedFrom, got)
+			}
+			if got := to.Format(queryDateFormat); got != tt.expectedTo {
+				t.Errorf("Expected to %s, got %s", tt.expectedTo, got)
+			}
+		})
+	}
+
+	from, to := digestWeekend(time.Date(2026, 10, 26, 0, 0, 0, 0, time.UTC))
+	if title := digestTitle(from, to); title != "This weekend in Vegas, October 29 – November 1" {
+		t.Errorf("Expected the title to name both months, got '%s'", title)
+	}
+}
+
+// TestBuildDigest tests grouping the weekend's events by night and venue and highlighting
+// those announced since the previous digest
+func TestBuildDigest(t *testing.T) {
+	today := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2026, 10, 19, 16, 0, 0, 0, time.UTC)
+	previous := &DigestRecord{ID: "d1", GeneratedAt: "2026-10-12T16:00:00Z"}
+
+	d := buildDigest(digestTestEvents, today, previous, now)
+
+	if d.Title != "This weekend in Vegas, October 22–25" {
+		t.Errorf("Expected title 'This weekend in Vegas, October 22–25', got '%s'", d.Title)
+	}
+	if d.EventCount != 4 || d.NewCount != 2 {
+		t.Errorf("Expected 4 events with 2 new, got %d with %d new", d.EventCount, d.NewCount)
+	}
+	if d.NewSince != previous.GeneratedAt {
+		t.Errorf("Expected new since '%s', got '%s'", previous.GeneratedAt, d.NewSince)
+	}
+
+	nights := []string{}
+	for _, n := range d.Nights {
+		nights = append(nights, n.Date)
+	}
+	if strings.Join(nights, ",") != "2026-10-22,2026-10-23,2026-10-25" {
+		t.Fatalf("Expected the nights with events from Thursday to Sunday, got %v", nights)
+	}
+
+	friday := d.Nights[1]
+	if len(friday.Venues) != 2 || friday.Venues[0].Venue != "Hakkasan" || friday.Venues[1].Venue != "XS Nightclub" {
+		t.Errorf("Expected Friday's venues Hakkasan and XS Nightclub, got %+v", friday.Venues)
+	}
+	if event := friday.Venues[1].Events[0]; event.Id != "fri-late" || !event.New {
+		t.Errorf("Expected the set after midnight on Friday night and marked new, got %+v", event)
+	}
+	if event := d.Nights[0].Venues[0].Events[0]; event.New {
+		t.Errorf("Expected an event announced before the previous digest not to be new")
+	}
+
+	first := buildDigest(digestTestEvents, today, nil, now)
+	if first.NewCount != 0 {
+		t.Errorf("Expected no new events without a previous digest, got %d", first.NewCount)
+	}
+}
+
+// TestWriteDigest tests the Markdown, HTML and JSON renderings of a digest
+func TestWriteDigest(t *testing.T) {
+	d := buildDigest(digestTestEvents, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), &DigestRecord{GeneratedAt: "2026-10-12T16:00:00Z"}, time.Now())
+
+	var markdown bytes.Buffer
+	if err := writeDigest(&markdown, d, digestFormatMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"# This weekend in Vegas, October 22–25\n",
+		"4 shows from Thursday to Sunday night, **2 new** since the last digest.",
+		"## Friday, October 23, 2026\n",
+		"### XS Nightclub\n",
+		`- **NEW** John\_Summit at 1:00 AM`,
+		"- [Tiësto](https://tickets.example.com/thu) at 10:30 PM",
+	} {
+		if !strings.Contains(markdown.String(), expected) {
+			t.Errorf("Expected the Markdown to contain %q, got:\n%s", expected, markdown.String())
+		}
+	}
+
+	var html bytes.Buffer
+	if err := writeDigest(&html, d, digestFormatHTML); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html.String(), "<h2>Friday, October 23, 2026</h2>") || strings.Count(html.String(), ">NEW</strong>") != 2 {
+		t.Errorf("Expected the HTML to list the nights and the 2 new events, got:\n%s", html.String())
+	}
+
+	var js bytes.Buffer
+	if err := writeDigest(&js, d, digestFormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	var decoded digest
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.EventCount != 4 || !decoded.Nights[1].Venues[1].Events[0].New {
+		t.Errorf("Expected the JSON to round trip, got %+v", decoded)
+	}
+
+	empty := buildDigest(nil, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), nil, time.Now())
+	markdown.Reset()
+	if err := writeDigest(&markdown, empty, digestFormatMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(markdown.String(), "No shows are listed for this weekend yet.") {
+		t.Errorf("Expected an empty weekend to say so, got:\n%s", markdown.String())
+	}
+}
+
+// TestDigestHandler tests the formats /v1/digest answers in
+func TestDigestHandler(t *testing.T) {
+	tests := []struct {
+		name                string
+		path                string
+		expectedStatus      int
+		expectedContentType string
+	}{
+		{name: "JSON", path: "/v1/digest", expectedStatus: http.StatusOK, expectedContentType: "application/json"},
+		{name: "Markdown", path: "/v1/digest?format=markdown", expectedStatus: http.StatusOK, expectedContentType: "text/markdown; charset=utf-8"},
+		{name: "HTML", path: "/v1/digest?format=html", expectedStatus: http.StatusOK, expectedContentType: "text/html; charset=utf-8"},
+		{name: "Unknown format", path: "/v1/digest?format=pdf", expectedStatus: http.StatusUnprocessableEntity, expectedContentType: "application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, digestTestEvents)
+
+			rr := get(t, app, tt.path)
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if got := rr.Header().Get("Content-Type"); got != tt.expectedContentType {
+				t.Errorf("Expected Content-Type '%s', got '%s'", tt.expectedContentType, got)
+			}
+		})
+	}
+}
+
+// TestDigestCommand tests writing, emailing and recording the digest from the command line
+func TestDigestCommand(t *testing.T) {
+	t.Run("Dry run", func(t *testing.T) {
+		app := newTestApplication(t, digestTestEvents)
+
+		var out bytes.Buffer
+		err := app.digestCommand([]string{"-dry-run", "-format", "json"}, &out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !json.Valid(out.Bytes()) {
+			t.Errorf("Expected the digest as JSON, got %s", out.String())
+		}
+		if records := app.dbDigests.(*mockDigestModel).records; len(records) != 0 {
+			t.Errorf("Expected a dry run not to be recorded, got %d records", len(records))
+		}
+	})
+
+	t.Run("Emailed and recorded", func(t *testing.T) {
+		server := newFakeSMTPServer(t, false)
+
+		app := newTestApplication(t, digestTestEvents)
+		app.mailer = server.mailer(t, smtpTLSNone, "", "")
+
+		var out bytes.Buffer
+		err := app.digestCommand([]string{"-to", "team@example.com,ops@example.com"}, &out)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.HasPrefix(out.String(), "# This weekend in Vegas") {
+			t.Errorf("Expected the Markdown digest to be written out, got %s", out.String())
+		}
+
+		messages := server.received()
+		if len(messages) != 2 {
+			t.Fatalf("Expected 2 emails, got %d", len(messages))
+		}
+		if email := parseEmail(t, messages[0].data); !strings.HasPrefix(email.subject, "This weekend in Vegas") {
+			t.Errorf("Expected the digest's title as subject, got '%s'", email.subject)
+		}
+
+		records := app.dbDigests.(*mockDigestModel).records
+		if len(records) != 1 {
+			t.Fatalf("Expected the digest to be recorded, got %d records", len(records))
+		}
+		entries := app.dbSendLog.(*mockSendLogModel).entries
+		if len(entries) != 2 || entries[0].DigestID != records[0].ID || entries[0].Status != sendStatusSent {
+			t.Errorf("Expected 2 sent emails logged against the digest, got %+v", entries)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		tests := []struct {
+			name string
+			args []string
+		}{
+			{name: "Unknown format", args: []string{"-format", "pdf"}},
+			{name: "Invalid address", args: []string{"-to", "team"}},
+			{name: "No SMTP server", args: []string{"-to", "team@example.com"}},
+			{name: "Unknown flag", args: []string{"-weekend"}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				app := newTestApplication(t, digestTestEvents)
+				if err := app.digestCommand(tt.args, &bytes.Buffer{}); err == nil {
+					t.Errorf("Expected an error")
+				}
+			})
+		}
+	})
+}
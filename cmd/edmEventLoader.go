@@ -0,0 +1,66 @@
+package main
+
+import "sync"
+
+// edmEventLoader batches the event lookups made while resolving a single GraphQL request.
+// Nested fields such as venue -> events -> artist -> events would otherwise read the
+// collection once per object, instead every lookup is served from one GetAll call and the
+// result of each distinct filter is cached for the rest of the request.
+type edmEventLoader struct {
+	model SnippetModelInterface
+
+	once      sync.Once
+	edmEvents []EdmEvent
+	err       error
+
+	mu    sync.Mutex
+	cache map[EventFilters][]EdmEvent
+}
+
+func newEdmEventLoader(model SnippetModelInterface) *edmEventLoader {
+	return &edmEventLoader{
+		model: model,
+		cache: make(map[EventFilters][]EdmEvent),
+	}
+}
+
+// load returns the events matching the filters, sorted like the REST endpoints. Pagination
+// is left to the caller so that pages of the same filter share a cache entry.
+func (l *edmEventLoader) load(f EventFilters) ([]EdmEvent, error) {
+	l.once.Do(func() {
+		l.edmEvents, l.err = l.model.GetAll()
+	})
+	if l.err != nil {
+		return nil, l.err
+	}
+
+	f.Page, f.PageSize = 0, 0
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if edmEvents, ok := l.cache[f]; ok {
+		return edmEvents, nil
+	}
+
+	edmEvents := filterEdmEvents(l.edmEvents, f)
+	l.cache[f] = edmEvents
+
+	return edmEvents, nil
+}
+
+// loadByID returns the event with the given id, or false if there is none.
+func (l *edmEventLoader) loadByID(id string) (EdmEvent, bool, error) {
+	edmEvents, err := l.load(EventFilters{})
+	if err != nil {
+		return EdmEvent{}, false, err
+	}
+
+	for _, edmEvent := range edmEvents {
+		if edmEvent.Id == id {
+			return edmEvent, true, nil
+		}
+	}
+
+	return EdmEvent{}, false, nil
+}
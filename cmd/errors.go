@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// The logError() method is a generic helper for logging an error message along with the
+// request method, URL and id.
+func (app *application) logError(r *http.Request, err error) {
+	app.logger.Printf("request_id=%s %s %s: %v", app.contextGetRequestID(r), r.Method, r.URL.String(), err)
+}
+
+// The errorResponse() method is a generic helper for sending JSON-formatted error
+// messages to the client with a given status code.
+func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, status int, message any) {
+	env := envelope{"error": message}
+
+	err := app.writeJSON(w, status, env, nil)
+	if err != nil {
+		app.logError(r, err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// The serverErrorResponse() method will be used when our application encounters an
+// unexpected problem at runtime.
+func (app *application) serverErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.logError(r, err)
+
+	message := "the server encountered a problem and could not process your request"
+	app.errorResponse(w, r, http.StatusInternalServerError, message)
+}
+
+func (app *application) notFoundResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the requested resource could not be found"
+	app.errorResponse(w, r, http.StatusNotFound, message)
+}
+
+func (app *application) badRequestResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+}
+
+func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, errors map[string]string) {
+	app.errorResponse(w, r, http.StatusUnprocessableEntity, errors)
+}
+
+func (app *application) invalidAPIKeyResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+
+	message := "invalid or revoked api key"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (app *application) apiKeyRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+
+	message := "you must send an api key to access this resource"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", retryAfterSeconds(retryAfter))
+
+	message := "rate limit exceeded"
+	app.errorResponse(w, r, http.StatusTooManyRequests, message)
+}
+
+func (app *application) notAcceptableResponse(w http.ResponseWriter, r *http.Request, mediaTypes []string) {
+	message := "the Accept header doesn't list a supported media type, use one of " + strings.Join(mediaTypes, ", ")
+	app.errorResponse(w, r, http.StatusNotAcceptable, message)
+}
+
+func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "your api key doesn't have the necessary permissions to access this resource"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+func (app *application) runInProgressResponse(w http.ResponseWriter, r *http.Request, run scrapeRun) {
+	w.Header().Set("Location", "/v1/admin/runs/"+run.ID)
+
+	app.errorResponse(w, r, http.StatusConflict, envelope{"message": errRunInProgress.Error(), "run_id": run.ID})
+}
+
+func (app *application) corsPreflightRejectedResponse(w http.ResponseWriter, r *http.Request, reason string) {
+	message := "CORS request rejected: " + reason
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+// The serverError() and clientError() helpers answer requests for the HTML pages, which
+// browsers expect as plain text rather than JSON.
+func (app *application) serverError(w http.ResponseWriter, r *http.Request, err error) {
+	app.logError(r, err)
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+func (app *application) clientError(w http.ResponseWriter, status int) {
+	http.Error(w, http.StatusText(status), status)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The formats /v1/events can answer in, chosen with the format parameter or the Accept header.
+const (
+	formatJSON   = "json"
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+	formatICS    = "ics"
+)
+
+var eventFormats = []string{formatJSON, formatCSV, formatNDJSON, formatICS}
+
+var eventFormatMediaTypes = map[string]string{
+	formatJSON:   "application/json",
+	formatCSV:    "text/csv",
+	formatNDJSON: "application/x-ndjson",
+	formatICS:    "text/calendar",
+}
+
+// Streamed responses are flushed to the client every this many events.
+const streamFlushInterval = 100
+
+var eventsCSVHeader = []string{"id", "date", "artist", "venue", "ticket_url", "artist_image_url", "availability", "source", "first_seen", "last_modified"}
+
+// negotiateEventFormat returns the format asked for by the format parameter or, without one,
+// the most preferred media type of the Accept header that we can produce. ok is false when
+// the Accept header only lists media types we can't produce. Wildcards, text/* included, give
+// the default JSON.
+func negotiateEventFormat(r *http.Request) (format string, ok bool) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return strings.ToLower(format), true
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return formatJSON, true
+	}
+
+	bestQuality := 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			quality, err = strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+		}
+		if quality <= bestQuality {
+			continue
+		}
+
+		switch mediaType {
+		case "*/*", "application/*", "text/*":
+			format = formatJSON
+		default:
+			format = ""
+			for f, formatMediaType := range eventFormatMediaTypes {
+				if mediaType == formatMediaType {
+					format = f
+				}
+			}
+			if format == "" {
+				continue
+			}
+		}
+		bestQuality = quality
+	}
+
+	if bestQuality == 0 {
+		return "", false
+	}
+	return format, true
+}
+
+// isStreamedEventFormat reports whether the request asks for one of the formats that are
+// written as the events are encoded rather than buffered.
+func isStreamedEventFormat(r *http.Request) bool {
+	format, ok := negotiateEventFormat(r)
+	return ok && format != formatJSON && slices.Contains(eventFormats, format)
+}
+
+// writeEvents streams the events in the given format. Only JSON is paginated by default,
+// the other formats export every matching event unless page or page_size is set.
+func (app *application) writeEvents(w http.ResponseWriter, r *http.Request, format string, edmEvents []EdmEvent) {
+	rc := http.NewResponseController(w)
+	// Exporting every event can take longer than the server's write timeout.
+	rc.SetWriteDeadline(time.Time{})
+	flush := func(i int) {
+		if (i+1)%streamFlushInterval == 0 {
+			rc.Flush()
+		}
+	}
+
+	w.Header().Set("Content-Type", eventFormatMediaTypes[format]+"; charset=utf-8")
+
+	var err error
+	switch format {
+	case formatCSV:
+		w.Header().Set("Content-Disposition", `attachment; filename="events.csv"`)
+		w.WriteHeader(http.StatusOK)
+		err = writeEventsCSV(w, edmEvents, flush)
+	case formatNDJSON:
+		w.WriteHeader(http.StatusOK)
+		err = writeEventsNDJSON(w, edmEvents, flush)
+	case formatICS:
+		w.Header().Set("Content-Disposition", `inline; filename="events.ics"`)
+		w.WriteHeader(http.StatusOK)
+		err = writeCalendar(w, "Las Vegas EDM Events", edmEvents, time.Now())
+	}
+
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+// writeEventsCSV writes the events with a header row. Values that a spreadsheet would run as
+// a formula are prefixed with a quote.
+func writeEventsCSV(w io.Writer, edmEvents []EdmEvent, flush func(i int)) error {
+	cw := csv.NewWriter(w)
+
+	err := cw.Write(eventsCSVHeader)
+	if err != nil {
+		return err
+	}
+
+	for i, edmEvent := range edmEvents {
+		record := []string{
+			edmEvent.Id,
+			edmEvent.EventDate,
+			edmEvent.ArtistName,
+			edmEvent.ClubName,
+			edmEvent.TicketUrl,
+			edmEvent.ArtistImageUrl,
+			eventAvailability(edmEvent),
+			edmEvent.Source,
+			edmEvent.FirstSeen,
+			edmEvent.LastModified,
+		}
+		for j := range record {
+			record[j] = escapeCSVFormula(record[j])
+		}
+
+		err := cw.Write(record)
+		if err != nil {
+			return err
+		}
+
+		if (i+1)%streamFlushInterval == 0 {
+			cw.Flush()
+		}
+		flush(i)
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func escapeCSVFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// writeEventsNDJSON writes one JSON encoded event per line.
+func writeEventsNDJSON(w io.Writer, edmEvents []EdmEvent, flush func(i int)) error {
+	enc := json.NewEncoder(w)
+
+	for i, edmEvent := range edmEvents {
+		err := enc.Encode(edmEvent)
+		if err != nil {
+			return err
+		}
+		flush(i)
+	}
+
+	return nil
+}
+
+func validateEventFormat(v *validator, format string) {
+	v.Check(slices.Contains(eventFormats, format), "format", "must be one of "+strings.Join(eventFormats, ", "))
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestNegotiateEventFormat tests the format picked from the format parameter and Accept header
+func TestNegotiateEventFormat(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		accept         string
+		expectedFormat string
+		expectedOK     bool
+	}{
+		{name: "Default", path: "/v1/events", expectedFormat: formatJSON, expectedOK: true},
+		{name: "Any", path: "/v1/events", accept: "*/*", expectedFormat: formatJSON, expectedOK: true},
+		{name: "Browser", path: "/v1/events", accept: "text/html,application/xhtml+xml,*/*;q=0.8", expectedFormat: formatJSON, expectedOK: true},
+		{name: "Any text", path: "/v1/events", accept: "text/*", expectedFormat: formatJSON, expectedOK: true},
+		{name: "CSV", path: "/v1/events", accept: "text/csv", expectedFormat: formatCSV, expectedOK: true},
+		{name: "NDJSON", path: "/v1/events", accept: "application/x-ndjson", expectedFormat: formatNDJSON, expectedOK: true},
+		{name: "Calendar", path: "/v1/events", accept: "text/calendar; charset=utf-8", expectedFormat: formatICS, expectedOK: true},
+		{name: "Quality", path: "/v1/events", accept: "application/json;q=0.5, text/csv;q=0.9", expectedFormat: formatCSV, expectedOK: true},
+		{name: "Format parameter wins", path: "/v1/events?format=ICS", accept: "text/csv", expectedFormat: formatICS, expectedOK: true},
+		{name: "Unsupported", path: "/v1/events", accept: "application/xml", expectedOK: false},
+		{name: "Refused", path: "/v1/events", accept: "application/json;q=0", expectedOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+
+			format, ok := negotiateEventFormat(r)
+			if ok != tt.expectedOK {
+				t.Fatalf("Expected ok %t, got %t", tt.expectedOK, ok)
+			}
+			if ok && format != tt.expectedFormat {
+				t.Errorf("Expected format '%s', got '%s'", tt.expectedFormat, format)
+			}
+		})
+	}
+}
+
+// TestListEdmEvents_Formats tests the body of each format
+func TestListEdmEvents_Formats(t *testing.T) {
+	edmEvents := append([]EdmEvent{}, calendarTestEvents...)
+	edmEvents = append(edmEvents, EdmEvent{Id: "id-4", ClubName: "omnia", ArtistName: "=HYPERLINK(\"http://x\")", EventDate: "2026-11-23T00:00:00Z"})
+
+	t.Run("CSV", func(t *testing.T) {
+		app := newTestApplication(t, edmEvents)
+
+		rr := getWithHeaders(t, app, "/v1/events", map[string]string{"Accept": "text/csv"})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+		}
+		if !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/csv") {
+			t.Errorf("Expected text/csv, got '%s'", rr.Header().Get("Content-Type"))
+		}
+
+		records, err := csv.NewReader(rr.Body).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != len(edmEvents)+1 {
+			t.Fatalf("Expected a header and %d rows, got %d records", len(edmEvents), len(records))
+		}
+		if strings.Join(records[0], ",") != strings.Join(eventsCSVHeader, ",") {
+			t.Errorf("Expected header %v, got %v", eventsCSVHeader, records[0])
+		}
+		if records[1][2] != "tiësto" || records[1][3] != "xs nightclub" {
+			t.Errorf("Expected the first row to be tiësto at xs nightclub, got %v", records[1])
+		}
+		if records[4][2] != "'=HYPERLINK(\"http://x\")" {
+			t.Errorf("Expected the formula to be escaped, got '%s'", records[4][2])
+		}
+	})
+
+	t.Run("NDJSON", func(t *testing.T) {
+		app := newTestApplication(t, edmEvents)
+
+		rr := get(t, app, "/v1/events?format=ndjson&venue=omnia")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		scanner := bufio.NewScanner(rr.Body)
+		var ids []string
+		for scanner.Scan() {
+			var edmEvent EdmEvent
+			if err := json.Unmarshal(scanner.Bytes(), &edmEvent); err != nil {
+				t.Fatalf("Expected one event per line, got '%s'", scanner.Text())
+			}
+			ids = append(ids, edmEvent.Id)
+		}
+		if strings.Join(ids, ",") != "id-2,id-4" {
+			t.Errorf("Expected id-2 and id-4, got %v", ids)
+		}
+	})
+
+	t.Run("ICS", func(t *testing.T) {
+		app := newTestApplication(t, edmEvents)
+
+		rr := getWithHeaders(t, app, "/v1/events", map[string]string{"Accept": "text/calendar"})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+		}
+		if !strings.HasPrefix(rr.Body.String(), "BEGIN:VCALENDAR\r\n") {
+			t.Errorf("Expected a calendar, got '%s'", rr.Body.String())
+		}
+		if count := strings.Count(rr.Body.String(), "BEGIN:VEVENT"); count != len(edmEvents) {
+			t.Errorf("Expected %d events, got %d", len(edmEvents), count)
+		}
+	})
+
+	t.Run("Paginated export", func(t *testing.T) {
+		app := newTestApplication(t, edmEvents)
+
+		rr := get(t, app, "/v1/events?format=ndjson&page=2&page_size=3")
+		if lines := strings.Count(rr.Body.String(), "\n"); lines != 1 {
+			t.Errorf("Expected the second page to hold 1 event, got %d", lines)
+		}
+	})
+
+	t.Run("JSON is still paginated", func(t *testing.T) {
+		app := newTestApplication(t, edmEvents)
+
+		rr := get(t, app, "/v1/events?format=json&page_size=2")
+		if !strings.HasPrefix(rr.Header().Get("Content-Type"), "application/json") || !strings.Contains(rr.Body.String(), `"total_records":4`) {
+			t.Errorf("Expected a JSON page, got %s", rr.Body.String())
+		}
+		if rr.Header().Get("Vary") != "Accept" {
+			t.Errorf("Expected Vary: Accept, got '%s'", rr.Header().Get("Vary"))
+		}
+	})
+}
+
+// TestListEdmEvents_FormatErrors tests unsupported formats
+func TestListEdmEvents_FormatErrors(t *testing.T) {
+	tests := []struct {
+		name               string
+		path               string
+		accept             string
+		expectedStatusCode int
+	}{
+		{name: "Unknown format", path: "/v1/events?format=xml", expectedStatusCode: http.StatusUnprocessableEntity},
+		{name: "Unsupported Accept", path: "/v1/events", accept: "application/xml", expectedStatusCode: http.StatusNotAcceptable},
+		{name: "Invalid filter", path: "/v1/events?format=csv&from=soon", expectedStatusCode: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, calendarTestEvents)
+
+			rr := getWithHeaders(t, app, tt.path, map[string]string{"Accept": tt.accept})
+			if rr.Code != tt.expectedStatusCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatusCode, rr.Code)
+			}
+			if err := loadOpenAPIDocument(t).validateResponse("/v1/events", http.MethodGet, rr); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// TestListEdmEvents_StreamedNotCached tests that exports aren't kept in the response cache
+func TestListEdmEvents_StreamedNotCached(t *testing.T) {
+	app := newTestApplication(t, calendarTestEvents)
+	app.dbSyncStates = &mockSyncStateModel{sourceSyncs: openAPITestSyncs}
+
+	first := get(t, app, "/v1/events?format=csv")
+	if first.Header().Get("ETag") == "" {
+		t.Error("Expected exports to carry an ETag")
+	}
+
+	model := app.dbSnippets.(*mockSnippetModel)
+	calls := model.getAllCalls
+
+	get(t, app, "/v1/events?format=csv")
+	if model.getAllCalls != calls+1 {
+		t.Errorf("Expected the export to be written again, got %d loads", model.getAllCalls-calls)
+	}
+
+	rr := getWithHeaders(t, app, "/v1/events?format=csv", map[string]string{"If-None-Match": first.Header().Get("ETag")})
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("Expected status %d, got %d", http.StatusNotModified, rr.Code)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import "net/http"
+
+// listEdmEventsHandler answers with JSON by default, or CSV, NDJSON or ICS as negotiated with
+// the format parameter or the Accept header.
+func (app *application) listEdmEventsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", "Accept")
+
+	format, ok := negotiateEventFormat(r)
+	if !ok {
+		mediaTypes := []string{}
+		for _, format := range eventFormats {
+			mediaTypes = append(mediaTypes, eventFormatMediaTypes[format])
+		}
+		app.notAcceptableResponse(w, r, mediaTypes)
+		return
+	}
+
+	qs := r.URL.Query()
+	v := newValidator()
+	filters := app.readEventFilters(qs, v)
+	validateEventFormat(v, format)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	edmEvents, err := app.queryEdmEvents(filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if format != formatJSON {
+		if qs.Has("page") || qs.Has("page_size") {
+			edmEvents, _ = filters.paginate(edmEvents)
+		}
+		app.writeEvents(w, r, format, edmEvents)
+		return
+	}
+
+	edmEvents, metadata := filters.paginate(edmEvents)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"events": edmEvents, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
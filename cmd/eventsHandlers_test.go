@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+// TestListEdmEventsHandler tests filtering and pagination of /v1/events
+func TestListEdmEventsHandler(t *testing.T) {
+	tests := []struct {
+		name               string
+		path               string
+		expectedStatusCode int
+		expectedIds        []string
+		expectedTotal      int
+		expectedErrorKey   string
+	}{
+		{
+			name:               "All events sorted by date",
+			path:               "/v1/events",
+			expectedStatusCode: http.StatusOK,
+			expectedIds:        []string{"id-1", "id-2", "id-3"},
+			expectedTotal:      3,
+		},
+		{
+			name:               "Artist filter is case insensitive and matches multi-artist titles",
+			path:               "/v1/events?artist=MARTIN%20GARRIX",
+			expectedStatusCode: http.StatusOK,
+			expectedIds:        []string{"id-2", "id-3"},
+			expectedTotal:      2,
+		},
+		{
+			name:               "Venue filter",
+			path:               "/v1/events?venue=xs",
+			expectedStatusCode: http.StatusOK,
+			expectedIds:        []string{"id-1"},
+			expectedTotal:      1,
+		},
+		{
+			name:               "Second page",
+			path:               "/v1/events?page=2&page_size=2",
+			expectedStatusCode: http.StatusOK,
+			expectedIds:        []string{"id-3"},
+			expectedTotal:      3,
+		},
+		{
+			name:               "Page past the end",
+			path:               "/v1/events?page=5&page_size=2",
+			expectedStatusCode: http.StatusOK,
+			expectedIds:        []string{},
+			expectedTotal:      3,
+		},
+		{
+			name:               "Invalid page size",
+			path:               "/v1/events?page_size=1000",
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedErrorKey:   "page_size",
+		},
+		{
+			name:               "Non numeric page",
+			path:               "/v1/events?page=abc",
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedErrorKey:   "page",
+		},
+		{
+			name:               "To before from",
+			path:               "/v1/events?from=2026-11-22&to=2026-11-20",
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedErrorKey:   "to",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, calendarTestEvents)
+			rr := get(t, app, tt.path)
+
+			if rr.Code != tt.expectedStatusCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatusCode, rr.Code)
+			}
+
+			if tt.expectedErrorKey != "" {
+				var response struct {
+					Error map[string]string `json:"error"`
+				}
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Expected JSON error body, got %v", err)
+				}
+				if _, ok := response.Error[tt.expectedErrorKey]; !ok {
+					t.Errorf("Expected validation error for '%s', got %v", tt.expectedErrorKey, response.Error)
+				}
+				return
+			}
+
+			var response struct {
+				Events   []EdmEvent `json:"events"`
+				Metadata Metadata   `json:"metadata"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Expected JSON body, got %v", err)
+			}
+
+			if len(response.Events) != len(tt.expectedIds) {
+				t.Fatalf("Expected %d events, got %d", len(tt.expectedIds), len(response.Events))
+			}
+			for i, id := range tt.expectedIds {
+				if response.Events[i].Id != id {
+					t.Errorf("Expected event %d to be '%s', got '%s'", i, id, response.Events[i].Id)
+				}
+			}
+			if response.Metadata.TotalRecords != tt.expectedTotal {
+				t.Errorf("Expected %d total records, got %d", tt.expectedTotal, response.Metadata.TotalRecords)
+			}
+		})
+	}
+}
+
+// TestListEdmEventsHandler_StorageError tests that storage failures become a 500 response
+func TestListEdmEventsHandler_StorageError(t *testing.T) {
+	app := newTestApplication(t, nil)
+	app.dbSnippets = &mockSnippetModel{err: errors.New("firestore unavailable")}
+
+	rr := get(t, app, "/v1/events")
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, rr.Code)
+	}
+
+	rr = get(t, app, "/v1/does-not-exist")
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, rr.Code)
+	}
+}
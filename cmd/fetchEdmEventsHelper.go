@@ -1,13 +1,103 @@
 package main
 
-func getEdmEventsFromAllLasVegas(ScrapingURLs ScrapingURLs) []EdmEvent {
-	wynnEdmEvents := scrapeWynnForEdmEvents(ScrapingURLs.Wynn)
-	zoukEdmEvents := scrapeZoukEdmEvents(ScrapingURLs.Zouk)
-	taoGroupHospitalityEdmEvents := scrapeTaoGroupHospitalityEdmEvents(ScrapingURLs.TaoGroupHospitality)
-	livEdmEvents := scrapeLivForEdmEvents(ScrapingURLs.Liv)
-	allEdmEvents := append(zoukEdmEvents, wynnEdmEvents...)
-	allEdmEvents = append(allEdmEvents, taoGroupHospitalityEdmEvents...)
-	allEdmEvents = append(allEdmEvents, livEdmEvents...)
-	return allEdmEvents
+import (
+	"fmt"
+	"net/http"
+	"slices"
+)
 
+// The names each scraper's events are recorded under.
+const (
+	sourceWynn                = "wynn"
+	sourceZouk                = "zouk"
+	sourceTaoGroupHospitality = "taogroup"
+	sourceLiv                 = "liv"
+)
+
+var allSources = []string{sourceWynn, sourceZouk, sourceTaoGroupHospitality, sourceLiv}
+
+// scrapeStats counts what a scraper did with the event items it found, for run reports. Every
+// raw item ends up either returned or counted as parse failed, past or filtered. HTTP errors
+// are counted by status, including the ones that end a pagination.
+type scrapeStats struct {
+	Requests    int            `json:"requests"`
+	Raw         int            `json:"raw"`
+	ParseFailed int            `json:"parse_failed"`
+	PastDropped int            `json:"past_dropped"`
+	Filtered    int            `json:"filtered"`
+	HTTPErrors  map[string]int `json:"http_errors,omitempty"`
+}
+
+// addHTTPError counts a failed request by its status, such as "503 Service Unavailable", or
+// as "no response" when the server couldn't be reached at all.
+func (s *scrapeStats) addHTTPError(statusCode int) {
+	if s.HTTPErrors == nil {
+		s.HTTPErrors = make(map[string]int)
+	}
+	s.HTTPErrors[httpErrorName(statusCode)]++
+}
+
+func httpErrorName(statusCode int) string {
+	if statusCode == 0 {
+		return "no response"
+	}
+	return fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode))
+}
+
+// scrapers maps every source to the scraper for it, in the order they run.
+var scrapers = []struct {
+	source string
+	scrape func(ScrapingURLs ScrapingURLs) ([]EdmEvent, scrapeStats, error)
+}{
+	{sourceZouk, func(ScrapingURLs ScrapingURLs) ([]EdmEvent, scrapeStats, error) {
+		return scrapeZoukEdmEvents(ScrapingURLs.Zouk)
+	}},
+	{sourceWynn, func(ScrapingURLs ScrapingURLs) ([]EdmEvent, scrapeStats, error) {
+		return scrapeWynnForEdmEvents(ScrapingURLs.Wynn)
+	}},
+	{sourceTaoGroupHospitality, func(ScrapingURLs ScrapingURLs) ([]EdmEvent, scrapeStats, error) {
+		return scrapeTaoGroupHospitalityEdmEvents(ScrapingURLs.TaoGroupHospitality)
+	}},
+	{sourceLiv, func(ScrapingURLs ScrapingURLs) ([]EdmEvent, scrapeStats, error) {
+		return scrapeLivForEdmEvents(ScrapingURLs.Liv)
+	}},
+}
+
+// getEdmEventsFromAllLasVegas runs every scraper. Sources that could not be scraped at all
+// are left out of the events and reported in the returned map instead.
+func getEdmEventsFromAllLasVegas(ScrapingURLs ScrapingURLs) ([]EdmEvent, map[string]error) {
+	return getEdmEventsFromSources(ScrapingURLs, allSources, nil)
+}
+
+// getEdmEventsFromSources runs the scrapers of the given sources, calling onScraped, when it
+// isn't nil, as each of them finishes.
+func getEdmEventsFromSources(ScrapingURLs ScrapingURLs, sources []string, onScraped func(source string, edmEvents []EdmEvent, stats scrapeStats, err error)) ([]EdmEvent, map[string]error) {
+	scrapeErrors := make(map[string]error)
+	allEdmEvents := []EdmEvent{}
+
+	for _, scraper := range scrapers {
+		if !slices.Contains(sources, scraper.source) {
+			continue
+		}
+
+		edmEvents, stats, err := scraper.scrape(ScrapingURLs)
+		if onScraped != nil {
+			onScraped(scraper.source, edmEvents, stats, err)
+		}
+		if err != nil {
+			scrapeErrors[scraper.source] = err
+			continue
+		}
+		allEdmEvents = append(allEdmEvents, withSource(edmEvents, scraper.source)...)
+	}
+
+	return allEdmEvents, scrapeErrors
+}
+
+// withSource tags every event with the source that scraped it.
+func withSource(edmEvents []EdmEvent, source string) []EdmEvent {
+	for i := range edmEvents {
+		edmEvents[i].Source = source
+	}
+	return edmEvents
 }
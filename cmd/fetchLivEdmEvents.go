@@ -20,17 +20,33 @@ goquery to query the HTML
 ----
 */
 
-func scrapeLivForEdmEvents(url string) []EdmEvent {
+// scrapeLivForEdmEvents returns an error when the first page could not be fetched.
+func scrapeLivForEdmEvents(url string) ([]EdmEvent, scrapeStats, error) {
 	edmEvents := []EdmEvent{}
 	currentDate := time.Now().Format("2006-01-02")
+	firstPage := true
+	var stats scrapeStats
+	var scrapeErr error
 
 	for {
 		url := formatPaginatedDateURLWynn(url, currentDate)
 
 		resp, err := http.Get(url)
+		stats.Requests++
+		if err == nil && resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			stats.addHTTPError(resp.StatusCode)
+			err = fmt.Errorf("status recieved was %d", resp.StatusCode)
+		} else if err != nil {
+			stats.addHTTPError(0)
+		}
 		if err != nil {
+			if firstPage {
+				scrapeErr = fmt.Errorf("error scraping %s: %w", url, err)
+			}
 			break
 		}
+		firstPage = false
 
 		// JSON unmarshal
 		var livEdmEventsResponse LivEdmEventsResponse
@@ -38,7 +54,7 @@ func scrapeLivForEdmEvents(url string) []EdmEvent {
 		resp.Body.Close()
 
 		// GoQuery on the HTML string
-		events := parseHTMLWithGoQuery(livEdmEventsResponse.Agenda)
+		events := parseHTMLWithGoQuery(livEdmEventsResponse.Agenda, &stats)
 		edmEvents = append(edmEvents, events...)
 
 		// Pagination logic
@@ -50,10 +66,12 @@ func scrapeLivForEdmEvents(url string) []EdmEvent {
 
 	fmt.Println(len(edmEvents), "LIV edmEvents")
 	fmt.Println("Scraping Completed for LIV!!!")
-	return edmEvents
+	return edmEvents, stats, scrapeErr
 }
 
-func parseHTMLWithGoQuery(htmlContent string) []EdmEvent {
+// parseHTMLWithGoQuery returns the events of a page of the agenda, counting the event items
+// it drops in stats.
+func parseHTMLWithGoQuery(htmlContent string, stats *scrapeStats) []EdmEvent {
 	var edmEvents []EdmEvent
 
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
@@ -62,18 +80,22 @@ func parseHTMLWithGoQuery(htmlContent string) []EdmEvent {
 	}
 
 	doc.Find("div.uv-carousel-lat").Each(func(i int, selection *goquery.Selection) {
+		stats.Raw++
 		edmEvent := EdmEvent{}
 		artistName := selection.Find("h3.uv-event-name-title").Text()
 		clubName := selection.Find("div.uwsvenuename").Text()
 		edmEvent.Id = getGUID()
 		edmEvent.ArtistName = strings.ToLower(artistName)
 		edmEvent.ClubName = strings.ToLower(clubName)
-		venueTicketurl, _ := selection.Find("a.hd-link").Attr("href")
+		ticketButton := selection.Find("a.hd-link")
+		venueTicketurl, _ := ticketButton.Attr("href")
 		edmEvent.TicketUrl = venueTicketurl
+		edmEvent.Availability = urVenueAvailability(selection, ticketButton)
 		formattedDate, err := formatDateFrom_YYYYMMDD_toRFC3339(extractEventDate(venueTicketurl))
 
 		if err != nil {
 			fmt.Println("Error while parsing the date:", err)
+			stats.ParseFailed++
 			return
 		}
 
@@ -82,11 +104,14 @@ func parseHTMLWithGoQuery(htmlContent string) []EdmEvent {
 		isPastDate, err := isPastDate(formattedDate)
 		if err != nil {
 			fmt.Println("Error while parsing the date:", err)
+			stats.ParseFailed++
 			return
 		}
 
 		if !isPastDate {
 			edmEvents = append(edmEvents, edmEvent)
+		} else {
+			stats.PastDropped++
 		}
 	})
 
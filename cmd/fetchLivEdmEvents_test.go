@@ -206,7 +206,7 @@ func TestScrapeLivForEdmEvents_Positive(t *testing.T) {
 			defer server.Close()
 
 			// Call the function directly with test server URL
-			events := scrapeLivForEdmEvents(server.URL + "?date=")
+			events, _, _ := scrapeLivForEdmEvents(server.URL + "?date=")
 
 			// Verify event count
 			if len(events) != tt.expectedEventCount {
@@ -435,7 +435,7 @@ func TestScrapeLivForEdmEvents_Negative(t *testing.T) {
 			defer server.Close()
 
 			// Call the function directly with test server URL
-			events := scrapeLivForEdmEvents(server.URL + "?date=")
+			events, _, _ := scrapeLivForEdmEvents(server.URL + "?date=")
 
 			// Verify event count
 			if len(events) != tt.expectedEventCount {
@@ -9,17 +9,30 @@ import (
 	"strings"
 )
 
-func scrapeTaoGroupHospitalityEdmEvents(scrappingUrl string) []EdmEvent {
+// scrapeTaoGroupHospitalityEdmEvents returns an error when the first page could not be
+// fetched, later pages failing is how the pagination ends.
+func scrapeTaoGroupHospitalityEdmEvents(scrappingUrl string) ([]EdmEvent, scrapeStats, error) {
 	pageNumber := 1
 
 	var taoGroupHospitalityEdmEvents TaoGroupHospitalityEdmEvents
 	edmEvents := []EdmEvent{}
+	var stats scrapeStats
+	var scrapeErr error
 
 	for {
 		taoGroupHospitalityUrl := formatPaginatedURL(scrappingUrl, pageNumber)
 		fmt.Println("Visting ", taoGroupHospitalityUrl)
 		response, err := getTaoGroupHospitalityEdmEvents(taoGroupHospitalityUrl)
+		stats.Requests++
 		if err != nil {
+			statusCode := 0
+			if response != nil {
+				statusCode = response.StatusCode
+			}
+			stats.addHTTPError(statusCode)
+			if pageNumber == 1 {
+				scrapeErr = fmt.Errorf("error scraping %s: %w", taoGroupHospitalityUrl, err)
+			}
 			fmt.Println("Got at the end of the paginated response, breaking out of the loop")
 			break
 		}
@@ -39,8 +52,11 @@ func scrapeTaoGroupHospitalityEdmEvents(scrappingUrl string) []EdmEvent {
 		defer response.Body.Close()
 
 		for _, taoGroupHospitalityEvent := range taoGroupHospitalityEdmEvents {
+			stats.Raw++
+
 			// Skip if venue array is empty
 			if len(taoGroupHospitalityEvent.ACF.EventVenue) == 0 {
+				stats.Filtered++
 				continue
 			}
 
@@ -54,30 +70,37 @@ func scrapeTaoGroupHospitalityEdmEvents(scrappingUrl string) []EdmEvent {
 
 			if err != nil {
 				fmt.Println("Error while parsing the date:", err)
+				stats.ParseFailed++
 				continue
 			}
 
 			edmEvent.EventDate = formattedDate
 			edmEvent.TicketUrl = taoGroupHospitalityEvent.Link
+			edmEvent.Availability = parseAvailability(taoGroupHospitalityEvent.ACF.EventTitle.Badge)
 
 			isPastDate, err := isPastDate(formattedDate)
 			if err != nil {
 				fmt.Println("Error while parsing the date:", err)
+				stats.ParseFailed++
 				continue
 			}
 
 			if !isPastDate {
 				edmEvents = append(edmEvents, edmEvent)
+			} else {
+				stats.PastDropped++
 			}
 
 		}
 	}
 	fmt.Println(len(edmEvents), "NOT filtered events")
+	unfiltered := len(edmEvents)
 	edmEvents = filterUnwantedEvents(edmEvents, []string{"lavo italian restaurant las vegas", "lavo italian restaurant"})
+	stats.Filtered += unfiltered - len(edmEvents)
 	fmt.Println(len(edmEvents), "filtered events")
 
 	fmt.Println("Scraping Completed for Tao Group Hospitality")
-	return edmEvents
+	return edmEvents, stats, scrapeErr
 }
 
 func getTaoGroupHospitalityEdmEvents(url string) (*http.Response, error) {
@@ -31,7 +31,7 @@ func TestScrapeTaoGroupHospitalityEdmEvents_Positive(t *testing.T) {
 						"id": 1,
 						"link": "https://taogroup.com/event/tiesto",
 						"acf": {
-							"event_title": {"display_title": "Tiësto"},
+							"event_title": {"display_title": "Tiësto", "badge": "SOLD OUT"},
 							"event_start_date": "%s 10:00 PM",
 							"event_venue": [{"post_title": "Hakkasan - Las Vegas"}]
 						}
@@ -52,6 +52,9 @@ func TestScrapeTaoGroupHospitalityEdmEvents_Positive(t *testing.T) {
 				if events[0].Id == "" {
 					t.Error("Expected event ID to be generated")
 				}
+				if events[0].Availability != availabilitySoldOut {
+					t.Errorf("Expected availability '%s', got '%s'", availabilitySoldOut, events[0].Availability)
+				}
 			},
 		},
 		{
@@ -208,7 +211,7 @@ func TestScrapeTaoGroupHospitalityEdmEvents_Positive(t *testing.T) {
 			defer server.Close()
 
 			// Call the function directly with test server URL
-			events := scrapeTaoGroupHospitalityEdmEvents(server.URL + "?")
+			events, _, _ := scrapeTaoGroupHospitalityEdmEvents(server.URL + "?")
 
 			// Verify event count
 			if len(events) != tt.expectedEventCount {
@@ -422,7 +425,7 @@ func TestScrapeTaoGroupHospitalityEdmEvents_Negative(t *testing.T) {
 			defer server.Close()
 
 			// Call the function directly with test server URL
-			events := scrapeTaoGroupHospitalityEdmEvents(server.URL + "?")
+			events, _, _ := scrapeTaoGroupHospitalityEdmEvents(server.URL + "?")
 
 			// Verify event count
 			if len(events) != tt.expectedEventCount {
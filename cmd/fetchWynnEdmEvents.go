@@ -7,25 +7,32 @@ import (
 	"github.com/gocolly/colly"
 )
 
-func scrapeWynnForEdmEvents(scrapeurl string) []EdmEvent {
+// scrapeWynnForEdmEvents returns an error when the events page could not be scraped.
+func scrapeWynnForEdmEvents(scrapeurl string) ([]EdmEvent, scrapeStats, error) {
 	edmEvents := []EdmEvent{}
+	var stats scrapeStats
+	var scrapeErr error
 	c := colly.NewCollector()
 	c.Wait()
 
 	c.OnHTML("div.eventitem ", func(h *colly.HTMLElement) {
 		selection := h.DOM
+		stats.Raw++
 		edmEvent := EdmEvent{}
 		artistName := selection.Find("span.uv-events-name").Text()
 		clubName := selection.Find("span.venueurl").Text()
 		edmEvent.Id = getGUID()
 		edmEvent.ArtistName = strings.ToLower(artistName)
 		edmEvent.ClubName = strings.ToLower(clubName)
-		venueTicketurl, _ := selection.Find("a.uv-btn").Attr("href")
+		ticketButton := selection.Find("a.uv-btn")
+		venueTicketurl, _ := ticketButton.Attr("href")
 		edmEvent.TicketUrl = venueTicketurl
+		edmEvent.Availability = urVenueAvailability(selection, ticketButton)
 		formattedDate, err := formatDateFrom_YYYYMMDD_toRFC3339(extractEventDate(venueTicketurl))
 
 		if err != nil {
 			fmt.Println("Error while parsing the date:", err)
+			stats.ParseFailed++
 			return
 		}
 
@@ -34,25 +41,33 @@ func scrapeWynnForEdmEvents(scrapeurl string) []EdmEvent {
 		isPastDate, err := isPastDate(formattedDate)
 		if err != nil {
 			fmt.Println("Error while parsing the date:", err)
+			stats.ParseFailed++
 			return
 		}
 
 		if !isPastDate {
 			edmEvents = append(edmEvents, edmEvent)
+		} else {
+			stats.PastDropped++
 		}
 	})
 
 	c.OnRequest(func(r *colly.Request) {
 		fmt.Println("Visiting", r.URL.String())
+		stats.Requests++
 	})
 
 	c.OnError(func(r *colly.Response, e error) {
 		fmt.Printf("Error while scraping: %s\n", e.Error())
+		stats.addHTTPError(r.StatusCode)
+		scrapeErr = fmt.Errorf("error scraping %s: %w", r.Request.URL, e)
 	})
 
 	c.OnScraped(func(r *colly.Response) {
 		fmt.Println(len(edmEvents), "NOT filtered events")
+		unfiltered := len(edmEvents)
 		edmEvents = filterUnwantedEvents(edmEvents, []string{"wynn field club", "festival", "art of the wild"})
+		stats.Filtered += unfiltered - len(edmEvents)
 		fmt.Println(len(edmEvents), "filtered events")
 
 	})
@@ -60,5 +75,5 @@ func scrapeWynnForEdmEvents(scrapeurl string) []EdmEvent {
 	c.Visit(scrapeurl)
 
 	fmt.Println("Scraping Completed for Wynn")
-	return edmEvents
+	return edmEvents, stats, scrapeErr
 }
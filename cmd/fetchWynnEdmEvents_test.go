@@ -30,7 +30,8 @@ func TestScrapeWynnForEdmEvents_Positive(t *testing.T) {
 						<div class="eventitem">
 							<span class="uv-events-name">Tiësto</span>
 							<span class="venueurl">XS Nightclub</span>
-							<a class="uv-btn" href="https://wynnlasvegas.com/events/%s"></a>
+							<span class="uv-badge">Almost Sold Out</span>
+							<a class="uv-btn" href="https://wynnlasvegas.com/events/%s">Tickets</a>
 						</div>
 					</body>
 				</html>
@@ -48,6 +49,9 @@ func TestScrapeWynnForEdmEvents_Positive(t *testing.T) {
 				if events[0].Id == "" {
 					t.Error("Expected event ID to be generated")
 				}
+				if events[0].Availability != availabilityLimited {
+					t.Errorf("Expected availability '%s', got '%s'", availabilityLimited, events[0].Availability)
+				}
 			},
 		},
 		{
@@ -169,7 +173,7 @@ func TestScrapeWynnForEdmEvents_Positive(t *testing.T) {
 			defer server.Close()
 
 			// Call the function directly with test server URL
-			events := scrapeWynnForEdmEvents(server.URL)
+			events, _, _ := scrapeWynnForEdmEvents(server.URL)
 
 			// Verify event count
 			if len(events) != tt.expectedEventCount {
@@ -391,7 +395,7 @@ func TestScrapeWynnForEdmEvents_Negative(t *testing.T) {
 			defer server.Close()
 
 			// Call the function directly with test server URL
-			events := scrapeWynnForEdmEvents(server.URL)
+			events, _, _ := scrapeWynnForEdmEvents(server.URL)
 
 			// Verify event count
 			if len(events) != tt.expectedEventCount {
@@ -400,3 +404,59 @@ func TestScrapeWynnForEdmEvents_Negative(t *testing.T) {
 		})
 	}
 }
+
+// TestScrapeWynnForEdmEvents_Stats tests the counts of what the scraper kept and dropped
+func TestScrapeWynnForEdmEvents_Stats(t *testing.T) {
+	futureDateStr := time.Now().AddDate(0, 0, 30).Format("20060102")
+	pastDateStr := time.Now().AddDate(0, 0, -30).Format("20060102")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `
+			<html>
+				<body>
+					<div class="eventitem">
+						<span class="uv-events-name">Tiësto</span>
+						<span class="venueurl">XS Nightclub</span>
+						<a class="uv-btn" href="https://wynnlasvegas.com/events/%s">Tickets</a>
+					</div>
+					<div class="eventitem">
+						<span class="uv-events-name">Kygo</span>
+						<span class="venueurl">XS Nightclub</span>
+						<a class="uv-btn" href="https://wynnlasvegas.com/events/%s">Tickets</a>
+					</div>
+					<div class="eventitem">
+						<span class="uv-events-name">Garden Party</span>
+						<span class="venueurl">Wynn Field Club</span>
+						<a class="uv-btn" href="https://wynnlasvegas.com/events/%s">Tickets</a>
+					</div>
+					<div class="eventitem">
+						<span class="uv-events-name">Alesso</span>
+						<span class="venueurl">Encore Beach Club</span>
+						<a class="uv-btn" href="https://wynnlasvegas.com/events/tba">Tickets</a>
+					</div>
+				</body>
+			</html>
+		`, futureDateStr, pastDateStr, futureDateStr)
+	}))
+	defer server.Close()
+
+	events, stats, err := scrapeWynnForEdmEvents(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 1 || stats.Requests != 1 || stats.Raw != 4 || stats.PastDropped != 1 || stats.Filtered != 1 || stats.ParseFailed != 1 {
+		t.Errorf("Expected 1 of 4 events kept with one past, filtered and unparsable, got %d events and %+v", len(events), stats)
+	}
+
+	// A page that can't be fetched shows up in the HTTP errors.
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	_, stats, _ = scrapeWynnForEdmEvents(down.URL)
+	if stats.HTTPErrors["503 Service Unavailable"] != 1 {
+		t.Errorf("Expected a 503 to be counted, got %v", stats.HTTPErrors)
+	}
+}
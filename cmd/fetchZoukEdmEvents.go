@@ -20,12 +20,17 @@ I am fetching the url for each month possible, we start at the current month the
 might come in useful if they decide to remove the lazy loading feature then this won't be needed
 */
 
-func scrapeZoukEdmEvents(url string) []EdmEvent {
+// scrapeZoukEdmEvents returns an error when the first month could not be scraped, later
+// months failing is how the pagination ends.
+func scrapeZoukEdmEvents(url string) ([]EdmEvent, scrapeStats, error) {
 	currentTime := time.Now()
 	monthNumber := int(currentTime.Month())
 	year := currentTime.Year()
 	edmEvents := []EdmEvent{}
 	hasEventItems := true
+	firstMonth := true
+	var stats scrapeStats
+	var scrapeErr error
 
 	c := colly.NewCollector()
 
@@ -40,18 +45,22 @@ func scrapeZoukEdmEvents(url string) []EdmEvent {
 
 	c.OnHTML("div.eventitem ", func(h *colly.HTMLElement) {
 		selection := h.DOM
+		stats.Raw++
 		edmEvent := EdmEvent{}
 		artistName := selection.Find("span.uv-event-name").Text()
 		clubName := selection.Find("a.venueurl").Text()
 		edmEvent.Id = getGUID()
 		edmEvent.ArtistName = strings.ToLower(artistName)
 		edmEvent.ClubName = strings.ToLower(clubName)
-		venueTicketurl, _ := selection.Find(".uv-boxitem.noloader").Attr("href")
+		ticketButton := selection.Find(".uv-boxitem.noloader")
+		venueTicketurl, _ := ticketButton.Attr("href")
 		edmEvent.TicketUrl = venueTicketurl
+		edmEvent.Availability = urVenueAvailability(selection, ticketButton)
 		formattedDate, err := formatDateFrom_YYYYMMDD_toRFC3339(extractEventDate(venueTicketurl))
 
 		if err != nil {
 			fmt.Println("Error while parsing the date:", err)
+			stats.ParseFailed++
 			return
 		}
 
@@ -60,21 +69,29 @@ func scrapeZoukEdmEvents(url string) []EdmEvent {
 		isPastDate, err := isPastDate(formattedDate)
 		if err != nil {
 			fmt.Println("Error while parsing the date:", err)
+			stats.ParseFailed++
 			return
 		}
 
 		if !isPastDate {
 			edmEvents = append(edmEvents, edmEvent)
+		} else {
+			stats.PastDropped++
 		}
 	})
 
 	c.OnRequest(func(r *colly.Request) {
 		fmt.Println("Visiting", r.URL.String())
+		stats.Requests++
 	})
 
 	c.OnError(func(r *colly.Response, e error) {
 		fmt.Printf("Error while scraping: %s\n", e.Error())
+		stats.addHTTPError(r.StatusCode)
 		hasEventItems = false
+		if firstMonth {
+			scrapeErr = fmt.Errorf("error scraping %s: %w", r.Request.URL, e)
+		}
 	})
 
 	c.OnScraped(func(r *colly.Response) {
@@ -85,10 +102,11 @@ func scrapeZoukEdmEvents(url string) []EdmEvent {
 		scrapeurl := formatPaginatedDateURLZouk(url, year, monthNumber)
 		year, monthNumber = incrementYearMonth(year, monthNumber)
 		c.Visit(scrapeurl)
+		firstMonth = false
 	}
 
 	fmt.Println("Scraping Completed for Zouk")
-	return edmEvents
+	return edmEvents, stats, scrapeErr
 }
 
 func incrementYearMonth(year int, monthNumber int) (int, int) {
@@ -175,7 +175,7 @@ func TestScrapeZoukEdmEvents_Positive(t *testing.T) {
 			defer server.Close()
 
 			// Call the function directly with test server URL
-			events := scrapeZoukEdmEvents(server.URL + "?date=")
+			events, _, _ := scrapeZoukEdmEvents(server.URL + "?date=")
 
 			// Verify event count
 			if len(events) != tt.expectedEventCount {
@@ -442,7 +442,7 @@ func TestScrapeZoukEdmEvents_Negative(t *testing.T) {
 			defer server.Close()
 
 			// Call the function directly with test server URL
-			events := scrapeZoukEdmEvents(server.URL + "?date=")
+			events, _, _ := scrapeZoukEdmEvents(server.URL + "?date=")
 
 			// Verify event count
 			if len(events) != tt.expectedEventCount {
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const queryDateFormat = "2006-01-02"
+
+// EventFilters holds the query string filters shared by every endpoint that lists events.
+type EventFilters struct {
+	Artist   string
+	Venue    string
+	Search   string
+	From     string
+	To       string
+	Page     int
+	PageSize int
+}
+
+// Metadata describes the page of results returned by a paginated listing.
+type Metadata struct {
+	CurrentPage  int `json:"current_page,omitempty"`
+	PageSize     int `json:"page_size,omitempty"`
+	FirstPage    int `json:"first_page,omitempty"`
+	LastPage     int `json:"last_page,omitempty"`
+	TotalRecords int `json:"total_records"`
+}
+
+// readEventFilters reads the artist, venue, from, to, page and page_size query string
+// parameters, recording any invalid values in the validator.
+func (app *application) readEventFilters(qs url.Values, v *validator) EventFilters {
+	var f EventFilters
+
+	f.Artist = strings.ToLower(strings.TrimSpace(app.readString(qs, "artist", "")))
+	f.Venue = strings.ToLower(strings.TrimSpace(app.readString(qs, "venue", "")))
+	f.From = app.readString(qs, "from", "")
+	f.To = app.readString(qs, "to", "")
+	f.Page = app.readInt(qs, "page", 1, v)
+	f.PageSize = app.readInt(qs, "page_size", 50, v)
+
+	validateEventFilters(v, f)
+
+	return f
+}
+
+func validateEventFilters(v *validator, f EventFilters) {
+	v.Check(f.Page > 0, "page", "must be greater than zero")
+	v.Check(f.Page <= 10_000, "page", "must be a maximum of 10 thousand")
+	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
+	v.Check(f.PageSize <= 500, "page_size", "must be a maximum of 500")
+
+	if f.From != "" {
+		_, err := time.Parse(queryDateFormat, f.From)
+		v.Check(err == nil, "from", "must be a date in the format YYYY-MM-DD")
+	}
+	if f.To != "" {
+		_, err := time.Parse(queryDateFormat, f.To)
+		v.Check(err == nil, "to", "must be a date in the format YYYY-MM-DD")
+	}
+	if f.From != "" && f.To != "" && v.Valid() {
+		v.Check(f.From <= f.To, "to", "must not be before from")
+	}
+}
+
+// matches reports whether an event passes the artist, venue, search and date filters. They
+// match on substrings since multi-artist nights are stored as a single title, and search
+// matches either the artist or the venue.
+func (f EventFilters) matches(edmEvent EdmEvent) bool {
+	if f.Artist != "" && !strings.Contains(strings.ToLower(edmEvent.ArtistName), f.Artist) {
+		return false
+	}
+	if f.Venue != "" && !strings.Contains(strings.ToLower(edmEvent.ClubName), f.Venue) {
+		return false
+	}
+	if f.Search != "" && !strings.Contains(strings.ToLower(edmEvent.ArtistName), f.Search) &&
+		!strings.Contains(strings.ToLower(edmEvent.ClubName), f.Search) {
+		return false
+	}
+
+	// EventDate is stored as RFC3339, so its first ten characters compare as YYYY-MM-DD.
+	eventDay := edmEvent.EventDate
+	if len(eventDay) >= len(queryDateFormat) {
+		eventDay = eventDay[:len(queryDateFormat)]
+	}
+	if f.From != "" && eventDay < f.From {
+		return false
+	}
+	if f.To != "" && eventDay > f.To {
+		return false
+	}
+
+	return true
+}
+
+// paginate returns the requested page of events along with the matching metadata.
+func (f EventFilters) paginate(edmEvents []EdmEvent) ([]EdmEvent, Metadata) {
+	return paginateItems(f, edmEvents)
+}
+
+// paginateItems returns the requested page of any sorted listing.
+func paginateItems[T any](f EventFilters, items []T) ([]T, Metadata) {
+	totalRecords := len(items)
+	if totalRecords == 0 {
+		return []T{}, Metadata{TotalRecords: 0}
+	}
+
+	metadata := Metadata{
+		CurrentPage:  f.Page,
+		PageSize:     f.PageSize,
+		FirstPage:    1,
+		LastPage:     (totalRecords + f.PageSize - 1) / f.PageSize,
+		TotalRecords: totalRecords,
+	}
+
+	offset := (f.Page - 1) * f.PageSize
+	if offset >= totalRecords {
+		return []T{}, metadata
+	}
+
+	end := min(offset+f.PageSize, totalRecords)
+
+	return items[offset:end], metadata
+}
+
+// queryEdmEvents is the query layer shared by the API endpoints: it loads the stored events,
+// applies the filters and sorts the result by date, venue and artist.
+func (app *application) queryEdmEvents(f EventFilters) ([]EdmEvent, error) {
+	edmEvents, err := app.dbSnippets.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	return filterEdmEvents(edmEvents, f), nil
+}
+
+// filterEdmEvents returns the events matching the filters sorted by date, venue and artist.
+func filterEdmEvents(edmEvents []EdmEvent, f EventFilters) []EdmEvent {
+	filtered := []EdmEvent{}
+	for _, edmEvent := range edmEvents {
+		if f.matches(edmEvent) {
+			filtered = append(filtered, edmEvent)
+		}
+	}
+
+	sort.SliceStable(filtered, func(i, j int) bool {
+		if filtered[i].EventDate != filtered[j].EventDate {
+			return filtered[i].EventDate < filtered[j].EventDate
+		}
+		if filtered[i].ClubName != filtered[j].ClubName {
+			return filtered[i].ClubName < filtered[j].ClubName
+		}
+		return filtered[i].ArtistName < filtered[j].ArtistName
+	})
+
+	return filtered
+}
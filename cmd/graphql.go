@@ -0,0 +1,422 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+const graphqlSchemaString = `
+	schema {
+		query: Query
+	}
+
+	type Query {
+		events(artist: String, venue: String, from: String, to: String, page: Int = 1, pageSize: Int = 50): EventConnection!
+		event(id: ID!): Event
+		artists(name: String): [Artist!]!
+		artist(name: String!): Artist!
+		venues(name: String): [Venue!]!
+		venue(name: String!): Venue!
+	}
+
+	type Event {
+		id: ID!
+		date: String!
+		ticketUrl: String
+		artistImageUrl: String
+		availability: String!
+		sequence: Int!
+		firstSeen: String
+		lastModified: String
+		artist: Artist!
+		venue: Venue!
+	}
+
+	type Artist {
+		name: String!
+		events(venue: String, from: String, to: String, page: Int = 1, pageSize: Int = 50): EventConnection!
+	}
+
+	type Venue {
+		name: String!
+		events(artist: String, from: String, to: String, page: Int = 1, pageSize: Int = 50): EventConnection!
+	}
+
+	type EventConnection {
+		events: [Event!]!
+		metadata: Metadata!
+	}
+
+	type Metadata {
+		currentPage: Int
+		pageSize: Int
+		firstPage: Int
+		lastPage: Int
+		totalRecords: Int!
+	}
+`
+
+// newGraphQLSchema parses the schema against the resolvers. MaxDepth keeps clients from
+// walking venue -> events -> artist -> events forever.
+func (app *application) newGraphQLSchema() *graphql.Schema {
+	return graphql.MustParseSchema(graphqlSchemaString, &graphqlResolver{app: app}, graphql.MaxDepth(10))
+}
+
+type graphqlRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+	Extensions    struct {
+		PersistedQuery *struct {
+			Version    int    `json:"version"`
+			Sha256Hash string `json:"sha256Hash"`
+		} `json:"persistedQuery"`
+	} `json:"extensions"`
+}
+
+// graphqlHandler accepts queries as a JSON POST body or, for cacheable reads, as GET query
+// string parameters. When an allowlist of persisted queries is configured only those queries
+// are executed, and clients may send just the sha256 hash of the query instead of its text.
+func (app *application) graphqlHandler(schema *graphql.Schema) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var input graphqlRequest
+
+		switch r.Method {
+		case http.MethodGet:
+			qs := r.URL.Query()
+			input.Query = qs.Get("query")
+			input.OperationName = qs.Get("operationName")
+			for _, key := range []string{"variables", "extensions"} {
+				if qs.Get(key) == "" {
+					continue
+				}
+				var err error
+				if key == "variables" {
+					err = json.Unmarshal([]byte(qs.Get(key)), &input.Variables)
+				} else {
+					err = json.Unmarshal([]byte(qs.Get(key)), &input.Extensions)
+				}
+				if err != nil {
+					app.badRequestResponse(w, r, fmt.Errorf("%s must be a JSON object", key))
+					return
+				}
+			}
+		default:
+			err := app.readJSON(w, r, &input)
+			if err != nil {
+				app.badRequestResponse(w, r, err)
+				return
+			}
+		}
+
+		query, err := app.resolvePersistedQuery(input)
+		if err != nil {
+			app.writeJSON(w, http.StatusOK, envelope{"errors": []envelope{{"message": err.Error()}}}, nil)
+			return
+		}
+
+		r = app.contextSetEdmEventLoader(r, newEdmEventLoader(app.dbSnippets))
+		response := schema.Exec(r.Context(), query, input.OperationName, input.Variables)
+
+		err = app.writeJSON(w, http.StatusOK, response, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+var (
+	errPersistedQueryNotFound = errors.New("PersistedQueryNotFound")
+	errQueryNotAllowed        = errors.New("query is not in the persisted query allowlist")
+)
+
+// resolvePersistedQuery returns the query text to execute for the request, enforcing the
+// allowlist when one is configured.
+func (app *application) resolvePersistedQuery(input graphqlRequest) (string, error) {
+	var hash string
+	if input.Extensions.PersistedQuery != nil {
+		hash = strings.ToLower(input.Extensions.PersistedQuery.Sha256Hash)
+	}
+
+	if input.Query == "" {
+		if hash == "" {
+			return "", errors.New("must provide a query string")
+		}
+		query, ok := app.persistedQueries[hash]
+		if !ok {
+			return "", errPersistedQueryNotFound
+		}
+		return query, nil
+	}
+
+	if app.persistedQueries == nil {
+		return input.Query, nil
+	}
+
+	sum := sha256.Sum256([]byte(input.Query))
+	if _, ok := app.persistedQueries[hex.EncodeToString(sum[:])]; !ok {
+		return "", errQueryNotAllowed
+	}
+
+	return input.Query, nil
+}
+
+// loadPersistedQueries reads the allowlist file, a JSON array of query documents. Each query
+// is keyed by the hex sha256 of its text, the same hash Apollo clients send.
+func loadPersistedQueries(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var queries []string
+	err = json.Unmarshal(content, &queries)
+	if err != nil {
+		return nil, fmt.Errorf("persisted queries must be a JSON array of strings: %w", err)
+	}
+
+	persistedQueries := make(map[string]string, len(queries))
+	for _, query := range queries {
+		sum := sha256.Sum256([]byte(query))
+		persistedQueries[hex.EncodeToString(sum[:])] = query
+	}
+
+	return persistedQueries, nil
+}
+
+// graphqlEventArgs mirrors the /v1/events query string filters.
+type graphqlEventArgs struct {
+	Artist   *string
+	Venue    *string
+	From     *string
+	To       *string
+	Page     int32
+	PageSize int32
+}
+
+func (args graphqlEventArgs) filters() (EventFilters, error) {
+	f := EventFilters{Page: int(args.Page), PageSize: int(args.PageSize)}
+	if args.Artist != nil {
+		f.Artist = strings.ToLower(strings.TrimSpace(*args.Artist))
+	}
+	if args.Venue != nil {
+		f.Venue = strings.ToLower(strings.TrimSpace(*args.Venue))
+	}
+	if args.From != nil {
+		f.From = *args.From
+	}
+	if args.To != nil {
+		f.To = *args.To
+	}
+
+	v := newValidator()
+	validateEventFilters(v, f)
+	if !v.Valid() {
+		keys := make([]string, 0, len(v.Errors))
+		for key := range v.Errors {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		messages := make([]string, 0, len(keys))
+		for _, key := range keys {
+			messages = append(messages, key+" "+v.Errors[key])
+		}
+		return f, errors.New(strings.Join(messages, ", "))
+	}
+
+	return f, nil
+}
+
+type graphqlResolver struct {
+	app *application
+}
+
+func (q *graphqlResolver) Events(ctx context.Context, args graphqlEventArgs) (*eventConnectionResolver, error) {
+	return q.app.resolveEventConnection(ctx, args)
+}
+
+func (q *graphqlResolver) Event(ctx context.Context, args struct{ ID graphql.ID }) (*eventResolver, error) {
+	edmEvent, ok, err := q.app.contextGetEdmEventLoader(ctx).loadByID(string(args.ID))
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &eventResolver{app: q.app, edmEvent: edmEvent}, nil
+}
+
+func (q *graphqlResolver) Artists(ctx context.Context, args struct{ Name *string }) ([]*artistResolver, error) {
+	f := EventFilters{}
+	if args.Name != nil {
+		f.Artist = strings.ToLower(strings.TrimSpace(*args.Name))
+	}
+
+	edmEvents, err := q.app.contextGetEdmEventLoader(ctx).load(f)
+	if err != nil {
+		return nil, err
+	}
+
+	artists := []*artistResolver{}
+	for _, name := range distinctNames(edmEvents, func(e EdmEvent) string { return e.ArtistName }) {
+		artists = append(artists, &artistResolver{app: q.app, name: name})
+	}
+	return artists, nil
+}
+
+func (q *graphqlResolver) Artist(args struct{ Name string }) *artistResolver {
+	return &artistResolver{app: q.app, name: strings.ToLower(strings.TrimSpace(args.Name))}
+}
+
+func (q *graphqlResolver) Venues(ctx context.Context, args struct{ Name *string }) ([]*venueResolver, error) {
+	f := EventFilters{}
+	if args.Name != nil {
+		f.Venue = strings.ToLower(strings.TrimSpace(*args.Name))
+	}
+
+	edmEvents, err := q.app.contextGetEdmEventLoader(ctx).load(f)
+	if err != nil {
+		return nil, err
+	}
+
+	venues := []*venueResolver{}
+	for _, name := range distinctNames(edmEvents, func(e EdmEvent) string { return e.ClubName }) {
+		venues = append(venues, &venueResolver{app: q.app, name: name})
+	}
+	return venues, nil
+}
+
+func (q *graphqlResolver) Venue(args struct{ Name string }) *venueResolver {
+	return &venueResolver{app: q.app, name: strings.ToLower(strings.TrimSpace(args.Name))}
+}
+
+func (app *application) resolveEventConnection(ctx context.Context, args graphqlEventArgs) (*eventConnectionResolver, error) {
+	f, err := args.filters()
+	if err != nil {
+		return nil, err
+	}
+
+	edmEvents, err := app.contextGetEdmEventLoader(ctx).load(f)
+	if err != nil {
+		return nil, err
+	}
+
+	page, metadata := f.paginate(edmEvents)
+	return &eventConnectionResolver{app: app, edmEvents: page, metadata: metadata}, nil
+}
+
+// distinctNames returns the sorted, de-duplicated non-empty names picked from the events.
+func distinctNames(edmEvents []EdmEvent, name func(EdmEvent) string) []string {
+	seen := make(map[string]bool)
+	names := []string{}
+	for _, edmEvent := range edmEvents {
+		n := name(edmEvent)
+		if n == "" || seen[n] {
+			continue
+		}
+		seen[n] = true
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type eventConnectionResolver struct {
+	app       *application
+	edmEvents []EdmEvent
+	metadata  Metadata
+}
+
+func (c *eventConnectionResolver) Events() []*eventResolver {
+	events := make([]*eventResolver, 0, len(c.edmEvents))
+	for _, edmEvent := range c.edmEvents {
+		events = append(events, &eventResolver{app: c.app, edmEvent: edmEvent})
+	}
+	return events
+}
+
+func (c *eventConnectionResolver) Metadata() *metadataResolver {
+	return &metadataResolver{metadata: c.metadata}
+}
+
+type eventResolver struct {
+	app      *application
+	edmEvent EdmEvent
+}
+
+func (e *eventResolver) ID() graphql.ID        { return graphql.ID(e.edmEvent.Id) }
+func (e *eventResolver) Date() string          { return e.edmEvent.EventDate }
+func (e *eventResolver) TicketUrl() *string    { return optionalString(e.edmEvent.TicketUrl) }
+func (e *eventResolver) Availability() string  { return eventAvailability(e.edmEvent) }
+func (e *eventResolver) Sequence() int32       { return int32(e.edmEvent.Sequence) }
+func (e *eventResolver) FirstSeen() *string    { return optionalString(e.edmEvent.FirstSeen) }
+func (e *eventResolver) LastModified() *string { return optionalString(e.edmEvent.LastModified) }
+
+func (e *eventResolver) ArtistImageUrl() *string {
+	return optionalString(e.edmEvent.ArtistImageUrl)
+}
+
+func (e *eventResolver) Artist() *artistResolver {
+	return &artistResolver{app: e.app, name: e.edmEvent.ArtistName}
+}
+
+func (e *eventResolver) Venue() *venueResolver {
+	return &venueResolver{app: e.app, name: e.edmEvent.ClubName}
+}
+
+type artistResolver struct {
+	app  *application
+	name string
+}
+
+func (a *artistResolver) Name() string { return a.name }
+
+func (a *artistResolver) Events(ctx context.Context, args graphqlEventArgs) (*eventConnectionResolver, error) {
+	args.Artist = &a.name
+	return a.app.resolveEventConnection(ctx, args)
+}
+
+type venueResolver struct {
+	app  *application
+	name string
+}
+
+func (v *venueResolver) Name() string { return v.name }
+
+func (v *venueResolver) Events(ctx context.Context, args graphqlEventArgs) (*eventConnectionResolver, error) {
+	args.Venue = &v.name
+	return v.app.resolveEventConnection(ctx, args)
+}
+
+type metadataResolver struct {
+	metadata Metadata
+}
+
+func (m *metadataResolver) CurrentPage() *int32 { return optionalInt(m.metadata.CurrentPage) }
+func (m *metadataResolver) PageSize() *int32    { return optionalInt(m.metadata.PageSize) }
+func (m *metadataResolver) FirstPage() *int32   { return optionalInt(m.metadata.FirstPage) }
+func (m *metadataResolver) LastPage() *int32    { return optionalInt(m.metadata.LastPage) }
+func (m *metadataResolver) TotalRecords() int32 { return int32(m.metadata.TotalRecords) }
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func optionalInt(i int) *int32 {
+	if i == 0 {
+		return nil
+	}
+	n := int32(i)
+	return &n
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type graphqlTestResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func postGraphQL(t *testing.T, app *application, body string) graphqlTestResponse {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewBufferString(body))
+	r.Header.Set("Content-Type", "application/json")
+	app.routes().ServeHTTP(rr, r)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var response graphqlTestResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected JSON body, got %v", err)
+	}
+	return response
+}
+
+func graphqlBody(t *testing.T, query string, variables map[string]any) string {
+	t.Helper()
+
+	body, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+// TestGraphQLHandler_Positive tests queries over events, artists and venues
+func TestGraphQLHandler_Positive(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		variables    map[string]any
+		expectedData string
+	}{
+		{
+			name:         "Events with filters and pagination",
+			query:        `{ events(artist: "martin garrix", pageSize: 1) { events { id } metadata { currentPage lastPage totalRecords } } }`,
+			expectedData: `{"events":{"events":[{"id":"id-2"}],"metadata":{"currentPage":1,"lastPage":2,"totalRecords":2}}}`,
+		},
+		{
+			name:         "Single event by id",
+			query:        `query($id: ID!) { event(id: $id) { date ticketUrl venue { name } } }`,
+			variables:    map[string]any{"id": "id-1"},
+			expectedData: `{"event":{"date":"2026-11-20T00:00:00Z","ticketUrl":"https://www.wynnsocial.com/events/20261120","venue":{"name":"xs nightclub"}}}`,
+		},
+		{
+			name:         "Unknown event is null",
+			query:        `{ event(id: "missing") { id } }`,
+			expectedData: `{"event":null}`,
+		},
+		{
+			name:         "Venue to upcoming events to artist to other appearances",
+			query:        `{ venue(name: "omnia") { events { events { artist { name events { events { venue { name } } } } } } } }`,
+			expectedData: `{"venue":{"events":{"events":[{"artist":{"name":"martin garrix","events":{"events":[{"venue":{"name":"omnia"}},{"venue":{"name":"zouk nightclub"}}]}}}]}}}`,
+		},
+		{
+			name:         "Distinct venues",
+			query:        `{ venues { name } }`,
+			expectedData: `{"venues":[{"name":"omnia"},{"name":"xs nightclub"},{"name":"zouk nightclub"}]}`,
+		},
+		{
+			name:         "Artists by name",
+			query:        `{ artists(name: "garrix") { name } }`,
+			expectedData: `{"artists":[{"name":"martin garrix"},{"name":"martin garrix, alesso"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, calendarTestEvents)
+			response := postGraphQL(t, app, graphqlBody(t, tt.query, tt.variables))
+
+			if len(response.Errors) != 0 {
+				t.Fatalf("Expected no errors, got %v", response.Errors)
+			}
+			if string(response.Data) != tt.expectedData {
+				t.Errorf("Expected data %s, got %s", tt.expectedData, response.Data)
+			}
+		})
+	}
+}
+
+// TestGraphQLHandler_Negative tests validation errors and bad requests
+func TestGraphQLHandler_Negative(t *testing.T) {
+	tests := []struct {
+		name            string
+		body            string
+		expectedMessage string
+	}{
+		{
+			name:            "Page size over the REST maximum",
+			body:            `{"query": "{ events(pageSize: 1000) { events { id } } }"}`,
+			expectedMessage: "page_size must be a maximum of 500",
+		},
+		{
+			name:            "Invalid date",
+			body:            `{"query": "{ events(from: \"tomorrow\") { events { id } } }"}`,
+			expectedMessage: "from must be a date in the format YYYY-MM-DD",
+		},
+		{
+			name:            "Unknown field",
+			body:            `{"query": "{ events { events { price } } }"}`,
+			expectedMessage: `Cannot query field "price" on type "Event".`,
+		},
+		{
+			name:            "Missing query",
+			body:            `{"query": ""}`,
+			expectedMessage: "must provide a query string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, calendarTestEvents)
+			response := postGraphQL(t, app, tt.body)
+
+			if len(response.Errors) == 0 {
+				t.Fatal("Expected an error, got none")
+			}
+			if response.Errors[0].Message != tt.expectedMessage {
+				t.Errorf("Expected message '%s', got '%s'", tt.expectedMessage, response.Errors[0].Message)
+			}
+		})
+	}
+}
+
+// TestGraphQLHandler_BatchesStorageReads tests that nested resolvers share one storage read
+func TestGraphQLHandler_BatchesStorageReads(t *testing.T) {
+	app := newTestApplication(t, calendarTestEvents)
+	query := `{
+		venues {
+			events { events { artist { events { events { venue { events { metadata { totalRecords } } } } } } } }
+		}
+	}`
+
+	response := postGraphQL(t, app, graphqlBody(t, query, nil))
+	if len(response.Errors) != 0 {
+		t.Fatalf("Expected no errors, got %v", response.Errors)
+	}
+
+	model := app.dbSnippets.(*mockSnippetModel)
+	if model.getAllCalls != 1 {
+		t.Errorf("Expected 1 storage read, got %d", model.getAllCalls)
+	}
+}
+
+// TestGraphQLHandler_PersistedQueries tests the persisted query allowlist mode
+func TestGraphQLHandler_PersistedQueries(t *testing.T) {
+	allowedQuery := `{ venues { name } }`
+	sum := sha256.Sum256([]byte(allowedQuery))
+	allowedHash := hex.EncodeToString(sum[:])
+
+	allowlist := filepath.Join(t.TempDir(), "persisted-queries.json")
+	content, _ := json.Marshal([]string{allowedQuery})
+	if err := os.WriteFile(allowlist, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	persistedQueries, err := loadPersistedQueries(allowlist)
+	if err != nil {
+		t.Fatalf("Expected no error loading the allowlist, got %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		body            string
+		expectedMessage string
+	}{
+		{
+			name: "Allowlisted query text",
+			body: graphqlBody(t, allowedQuery, nil),
+		},
+		{
+			name: "Allowlisted query by hash only",
+			body: `{"extensions": {"persistedQuery": {"version": 1, "sha256Hash": "` + allowedHash + `"}}}`,
+		},
+		{
+			name:            "Query outside the allowlist",
+			body:            graphqlBody(t, `{ artists { name } }`, nil),
+			expectedMessage: "query is not in the persisted query allowlist",
+		},
+		{
+			name:            "Unknown hash",
+			body:            `{"extensions": {"persistedQuery": {"version": 1, "sha256Hash": "deadbeef"}}}`,
+			expectedMessage: "PersistedQueryNotFound",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, calendarTestEvents)
+			app.persistedQueries = persistedQueries
+
+			response := postGraphQL(t, app, tt.body)
+
+			if tt.expectedMessage == "" {
+				if len(response.Errors) != 0 {
+					t.Fatalf("Expected no errors, got %v", response.Errors)
+				}
+				if !strings.Contains(string(response.Data), `"venues"`) {
+					t.Errorf("Expected venues in the data, got %s", response.Data)
+				}
+				return
+			}
+
+			if len(response.Errors) == 0 || response.Errors[0].Message != tt.expectedMessage {
+				t.Errorf("Expected error '%s', got %v", tt.expectedMessage, response.Errors)
+			}
+		})
+	}
+}
+
+// TestGraphQLHandler_Get tests that queries can be sent as query string parameters
+func TestGraphQLHandler_Get(t *testing.T) {
+	app := newTestApplication(t, calendarTestEvents)
+	qs := url.Values{
+		"query":     {`query($venue: String) { events(venue: $venue) { metadata { totalRecords } } }`},
+		"variables": {`{"venue": "zouk"}`},
+	}
+
+	rr := get(t, app, "/graphql?"+qs.Encode())
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	expected := `{"data":{"events":{"metadata":{"totalRecords":1}}}}` + "\n"
+	if rr.Body.String() != expected {
+		t.Errorf("Expected body %s, got %s", expected, rr.Body.String())
+	}
+
+	rr = get(t, app, "/graphql?query=%7B%7D&variables=not-json")
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+}
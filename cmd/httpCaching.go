@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The data only changes when a scrape syncs it, so responses are cached in-process until the
+// sync state changes. The cache is dropped wholesale once it holds this many responses.
+const maxCachedResponses = 1000
+
+type cachedResponse struct {
+	etag   string
+	status int
+	header http.Header
+	body   []byte
+}
+
+type responseCache struct {
+	mu      sync.Mutex
+	version string
+	entries map[string]cachedResponse
+}
+
+func newResponseCache() *responseCache {
+	return &responseCache{entries: make(map[string]cachedResponse)}
+}
+
+// get returns the response cached under key, provided it was stored for the current sync
+// version. Seeing a new version invalidates everything cached for the previous one.
+func (c *responseCache) get(version string, key string) (cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.version != version {
+		c.version = version
+		c.entries = make(map[string]cachedResponse)
+		return cachedResponse{}, false
+	}
+
+	response, ok := c.entries[key]
+	return response, ok
+}
+
+func (c *responseCache) set(version string, key string, response cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.version != version || len(c.entries) >= maxCachedResponses {
+		c.version = version
+		c.entries = make(map[string]cachedResponse)
+	}
+	c.entries[key] = response
+}
+
+// invalidate drops every cached response, for syncs that happen inside this process.
+func (c *responseCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.version = ""
+	c.entries = make(map[string]cachedResponse)
+}
+
+// bufferedResponseWriter records a handler's response so it can be cached before being sent.
+type bufferedResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponseWriter) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponseWriter) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *bufferedResponseWriter) Write(p []byte) (int, error) {
+	b.WriteHeader(http.StatusOK)
+	return b.body.Write(p)
+}
+
+// conditionalGet adds ETag and Last-Modified headers derived from the last successful sync
+// of the sources in the request's scope, answers If-None-Match and If-Modified-Since with
+// 304 Not Modified, and serves repeated requests from the in-process response cache.
+func (app *application) conditionalGet(next http.HandlerFunc) http.HandlerFunc {
+	return app.conditionalGetStreaming(next, nil)
+}
+
+// conditionalGetStreaming works like conditionalGet, except that the requests for which
+// streamed returns true are passed straight to next instead of being buffered and cached,
+// so that large responses reach the client as they are written.
+func (app *application) conditionalGetStreaming(next http.HandlerFunc, streamed func(r *http.Request) bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sourceSyncs, err := app.dbSyncStates.GetAll()
+		if err != nil {
+			app.logError(r, err)
+		}
+		if err != nil || len(sourceSyncs) == 0 {
+			next(w, r)
+			return
+		}
+
+		key := responseCacheKey(r)
+		scope := syncScope(sourceSyncs, requestVenue(r))
+		etag := scopeETag(key, scope)
+		lastModified := scopeLastModified(scope)
+
+		w.Header().Set("Cache-Control", "no-cache")
+
+		if isNotModified(r, etag, lastModified) {
+			setValidators(w.Header(), etag, lastModified)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		if streamed != nil && streamed(r) {
+			next(&validatorResponseWriter{ResponseWriter: w, etag: etag, lastModified: lastModified}, r)
+			return
+		}
+
+		version := syncVersion(sourceSyncs)
+		if cached, ok := app.responseCache.get(version, key); ok && cached.etag == etag {
+			setValidators(w.Header(), etag, lastModified)
+			writeCachedResponse(w, cached)
+			return
+		}
+
+		buffered := &bufferedResponseWriter{header: make(http.Header)}
+		next(buffered, r)
+
+		response := cachedResponse{
+			etag:   etag,
+			status: buffered.status,
+			header: buffered.header,
+			body:   buffered.body.Bytes(),
+		}
+		// Errors are not tied to the sync, don't let clients revalidate them.
+		if response.status == http.StatusOK {
+			app.responseCache.set(version, key, response)
+			setValidators(w.Header(), etag, lastModified)
+		}
+
+		writeCachedResponse(w, response)
+	}
+}
+
+func setValidators(header http.Header, etag string, lastModified time.Time) {
+	header.Set("ETag", etag)
+	if !lastModified.IsZero() {
+		header.Set("Last-Modified", lastModified.Format(http.TimeFormat))
+	}
+}
+
+// validatorResponseWriter adds the validators to a streamed response once the handler
+// commits to a 200, so that the errors it answers with instead carry none. It unwraps to the
+// underlying writer so that http.ResponseController can still flush the stream.
+type validatorResponseWriter struct {
+	http.ResponseWriter
+	etag         string
+	lastModified time.Time
+	wroteHeader  bool
+}
+
+func (vw *validatorResponseWriter) WriteHeader(status int) {
+	if !vw.wroteHeader {
+		vw.wroteHeader = true
+		if status == http.StatusOK {
+			setValidators(vw.ResponseWriter.Header(), vw.etag, vw.lastModified)
+		}
+	}
+	vw.ResponseWriter.WriteHeader(status)
+}
+
+func (vw *validatorResponseWriter) Write(p []byte) (int, error) {
+	if !vw.wroteHeader {
+		vw.WriteHeader(http.StatusOK)
+	}
+	return vw.ResponseWriter.Write(p)
+}
+
+func (vw *validatorResponseWriter) Unwrap() http.ResponseWriter {
+	return vw.ResponseWriter
+}
+
+func writeCachedResponse(w http.ResponseWriter, response cachedResponse) {
+	for key, value := range response.header {
+		w.Header()[key] = value
+	}
+	w.WriteHeader(response.status)
+	w.Write(response.body)
+}
+
+// responseCacheKey identifies a response. The feeds embed links built from the host and
+// scheme, so those are part of the key along with the negotiated content type.
+func responseCacheKey(r *http.Request) string {
+	return strings.Join([]string{baseURL(r), r.URL.RequestURI(), r.Header.Get("Accept")}, "|")
+}
+
+// requestVenue returns the venue filter of the request, from the path or the query string.
+func requestVenue(r *http.Request) string {
+	if venue := r.PathValue("venue"); venue != "" {
+		return strings.ToLower(venue)
+	}
+	return strings.ToLower(strings.TrimSpace(r.URL.Query().Get("venue")))
+}
+
+// syncScope returns the sources whose last sync produced a venue matching the filter. When
+// no venue is filtered on, or no source knows the venue yet, every source is in scope since
+// any of their syncs could change the response.
+func syncScope(sourceSyncs []SourceSync, venue string) []SourceSync {
+	if venue == "" {
+		return sourceSyncs
+	}
+
+	scope := []SourceSync{}
+	for _, sourceSync := range sourceSyncs {
+		for _, syncedVenue := range sourceSync.Venues {
+			if strings.Contains(strings.ToLower(syncedVenue), venue) {
+				scope = append(scope, sourceSync)
+				break
+			}
+		}
+	}
+
+	if len(scope) == 0 {
+		return sourceSyncs
+	}
+	return scope
+}
+
+func scopeETag(key string, scope []SourceSync) string {
+	h := sha256.New()
+	fmt.Fprintln(h, key)
+	for _, sourceSync := range scope {
+		fmt.Fprintf(h, "%s|%s|%d\n", sourceSync.Source, sourceSync.LastSuccessfulSync, sourceSync.EventCount)
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil))[:32] + `"`
+}
+
+func scopeLastModified(scope []SourceSync) time.Time {
+	var lastModified time.Time
+	for _, sourceSync := range scope {
+		t, err := time.Parse(time.RFC3339, sourceSync.LastSuccessfulSync)
+		if err == nil && t.After(lastModified) {
+			lastModified = t
+		}
+	}
+	return lastModified.UTC().Truncate(time.Second)
+}
+
+// syncVersion changes whenever any source syncs, which invalidates the response cache.
+func syncVersion(sourceSyncs []SourceSync) string {
+	var b strings.Builder
+	for _, sourceSync := range sourceSyncs {
+		fmt.Fprintf(&b, "%s|%s|%d;", sourceSync.Source, sourceSync.LastSuccessfulSync, sourceSync.EventCount)
+	}
+	return b.String()
+}
+
+// isNotModified evaluates the conditional request headers as described in RFC 9110 section
+// 13.2.2: If-Modified-Since is only considered when If-None-Match is absent.
+func isNotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ifModifiedSince := r.Header.Get("If-Modified-Since"); ifModifiedSince != "" && !lastModified.IsZero() {
+		t, err := http.ParseTime(ifModifiedSince)
+		if err == nil && !lastModified.After(t) {
+			return true
+		}
+	}
+
+	return false
+}
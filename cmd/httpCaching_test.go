@@ -0,0 +1,239 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var httpCachingTestSyncs = []SourceSync{
+	{Source: sourceWynn, LastSuccessfulSync: "2026-10-18T06:00:00Z", EventCount: 1, Venues: []string{"xs nightclub"}},
+	{Source: sourceTaoGroupHospitality, LastSuccessfulSync: "2026-10-19T06:00:00Z", EventCount: 1, Venues: []string{"omnia"}},
+	{Source: sourceZouk, LastSuccessfulSync: "2026-10-17T06:00:00Z", EventCount: 1, Venues: []string{"zouk nightclub"}},
+}
+
+func newHTTPCachingTestApplication(t *testing.T) *application {
+	t.Helper()
+
+	app := newTestApplication(t, calendarTestEvents)
+	app.dbSyncStates = &mockSyncStateModel{sourceSyncs: append([]SourceSync{}, httpCachingTestSyncs...)}
+	return app
+}
+
+func getWithHeaders(t *testing.T, app *application, path string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	for key, value := range headers {
+		r.Header.Set(key, value)
+	}
+	app.routes().ServeHTTP(rr, r)
+
+	return rr
+}
+
+// TestConditionalGet_Positive tests the validators and 304 responses
+func TestConditionalGet_Positive(t *testing.T) {
+	tests := []struct {
+		name                 string
+		path                 string
+		expectedLastModified string
+	}{
+		{
+			name:                 "All sources are in scope without a venue filter",
+			path:                 "/v1/events",
+			expectedLastModified: "Mon, 19 Oct 2026 06:00:00 GMT",
+		},
+		{
+			name:                 "Venue filter narrows the scope to the sources of that venue",
+			path:                 "/v1/events?venue=xs",
+			expectedLastModified: "Sun, 18 Oct 2026 06:00:00 GMT",
+		},
+		{
+			name:                 "Venue calendar shortcut narrows the scope",
+			path:                 "/v1/venues/zouk/calendar.ics",
+			expectedLastModified: "Sat, 17 Oct 2026 06:00:00 GMT",
+		},
+		{
+			name:                 "Unknown venue keeps every source in scope",
+			path:                 "/v1/events?venue=marquee",
+			expectedLastModified: "Mon, 19 Oct 2026 06:00:00 GMT",
+		},
+		{
+			name:                 "Feeds are cacheable",
+			path:                 "/v1/feeds/new.rss",
+			expectedLastModified: "Mon, 19 Oct 2026 06:00:00 GMT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newHTTPCachingTestApplication(t)
+
+			rr := get(t, app, tt.path)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+			}
+
+			etag := rr.Header().Get("ETag")
+			if etag == "" {
+				t.Fatal("Expected an ETag header")
+			}
+			if lastModified := rr.Header().Get("Last-Modified"); lastModified != tt.expectedLastModified {
+				t.Errorf("Expected Last-Modified '%s', got '%s'", tt.expectedLastModified, lastModified)
+			}
+
+			rr = getWithHeaders(t, app, tt.path, map[string]string{"If-None-Match": etag})
+			if rr.Code != http.StatusNotModified {
+				t.Errorf("Expected If-None-Match to give status %d, got %d", http.StatusNotModified, rr.Code)
+			}
+			if rr.Body.Len() != 0 {
+				t.Errorf("Expected an empty 304 body, got %q", rr.Body.String())
+			}
+
+			rr = getWithHeaders(t, app, tt.path, map[string]string{"If-Modified-Since": tt.expectedLastModified})
+			if rr.Code != http.StatusNotModified {
+				t.Errorf("Expected If-Modified-Since to give status %d, got %d", http.StatusNotModified, rr.Code)
+			}
+		})
+	}
+}
+
+// TestConditionalGet_Negative tests requests that must get a full response
+func TestConditionalGet_Negative(t *testing.T) {
+	tests := []struct {
+		name               string
+		path               string
+		headers            map[string]string
+		expectedStatusCode int
+		expectETag         bool
+	}{
+		{
+			name:               "Stale ETag",
+			path:               "/v1/events",
+			headers:            map[string]string{"If-None-Match": `"stale"`},
+			expectedStatusCode: http.StatusOK,
+			expectETag:         true,
+		},
+		{
+			name:               "Modified since an older date",
+			path:               "/v1/events",
+			headers:            map[string]string{"If-Modified-Since": "Sun, 18 Oct 2026 06:00:00 GMT"},
+			expectedStatusCode: http.StatusOK,
+			expectETag:         true,
+		},
+		{
+			name:               "If-None-Match takes precedence over If-Modified-Since",
+			path:               "/v1/events",
+			headers:            map[string]string{"If-None-Match": `"stale"`, "If-Modified-Since": "Tue, 20 Oct 2026 06:00:00 GMT"},
+			expectedStatusCode: http.StatusOK,
+			expectETag:         true,
+		},
+		{
+			name:               "Validation errors carry no validators",
+			path:               "/v1/events?page=0",
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectETag:         false,
+		},
+		{
+			name:               "Streamed validation errors carry no validators",
+			path:               "/v1/events?format=csv&page=0",
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectETag:         false,
+		},
+		{
+			name:               "Streamed formats carry validators",
+			path:               "/v1/events?format=csv",
+			expectedStatusCode: http.StatusOK,
+			expectETag:         true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newHTTPCachingTestApplication(t)
+			rr := getWithHeaders(t, app, tt.path, tt.headers)
+
+			if rr.Code != tt.expectedStatusCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatusCode, rr.Code)
+			}
+			if hasETag := rr.Header().Get("ETag") != ""; hasETag != tt.expectETag {
+				t.Errorf("Expected ETag present to be %v", tt.expectETag)
+			}
+		})
+	}
+}
+
+// TestConditionalGet_ResponseCache tests that responses are cached until a source syncs
+func TestConditionalGet_ResponseCache(t *testing.T) {
+	app := newHTTPCachingTestApplication(t)
+	model := app.dbSnippets.(*mockSnippetModel)
+	syncStates := app.dbSyncStates.(*mockSyncStateModel)
+
+	first := get(t, app, "/v1/events?venue=xs")
+	second := get(t, app, "/v1/events?venue=xs")
+
+	if model.getAllCalls != 1 {
+		t.Errorf("Expected the second request to be served from the cache, got %d storage reads", model.getAllCalls)
+	}
+	if first.Body.String() != second.Body.String() {
+		t.Error("Expected the cached body to match the original")
+	}
+
+	// A sync of a source outside the venue's scope invalidates the cache, but not the ETag.
+	syncStates.Upsert([]SourceSync{{Source: sourceZouk, LastSuccessfulSync: "2026-10-19T07:00:00Z", EventCount: 2, Venues: []string{"zouk nightclub"}}})
+	third := get(t, app, "/v1/events?venue=xs")
+
+	if model.getAllCalls != 2 {
+		t.Errorf("Expected a sync to invalidate the cache, got %d storage reads", model.getAllCalls)
+	}
+	if third.Header().Get("ETag") != first.Header().Get("ETag") {
+		t.Error("Expected the ETag to stay the same when the venue's source didn't sync")
+	}
+
+	// A sync of the venue's own source changes the ETag.
+	syncStates.Upsert([]SourceSync{{Source: sourceWynn, LastSuccessfulSync: "2026-10-19T07:00:00Z", EventCount: 1, Venues: []string{"xs nightclub"}}})
+	fourth := get(t, app, "/v1/events?venue=xs")
+
+	if fourth.Header().Get("ETag") == first.Header().Get("ETag") {
+		t.Error("Expected a new ETag after the venue's source synced")
+	}
+	if fourth.Header().Get("Last-Modified") != "Mon, 19 Oct 2026 07:00:00 GMT" {
+		t.Errorf("Expected Last-Modified to follow the sync, got '%s'", fourth.Header().Get("Last-Modified"))
+	}
+
+	app.responseCache.invalidate()
+	get(t, app, "/v1/events?venue=xs")
+	if model.getAllCalls != 4 {
+		t.Errorf("Expected invalidate to empty the cache, got %d storage reads", model.getAllCalls)
+	}
+}
+
+// TestSourceSyncsFor tests the per source summary recorded after a sync
+func TestSourceSyncsFor(t *testing.T) {
+	edmEvents := []EdmEvent{
+		{ClubName: "xs nightclub", Source: sourceWynn},
+		{ClubName: "encore beach club", Source: sourceWynn},
+		{ClubName: "xs nightclub", Source: sourceWynn},
+		{ClubName: "omnia", Source: sourceTaoGroupHospitality},
+	}
+
+	sourceSyncs := sourceSyncsFor(edmEvents, allSources, "2026-10-19T06:00:00Z")
+	if len(sourceSyncs) != len(allSources) {
+		t.Fatalf("Expected %d sources, got %d", len(allSources), len(sourceSyncs))
+	}
+
+	wynn := sourceSyncs[0]
+	if wynn.Source != sourceWynn || wynn.EventCount != 3 {
+		t.Errorf("Expected wynn with 3 events, got %s with %d", wynn.Source, wynn.EventCount)
+	}
+	if len(wynn.Venues) != 2 || wynn.Venues[0] != "encore beach club" || wynn.Venues[1] != "xs nightclub" {
+		t.Errorf("Expected the distinct sorted venues, got %v", wynn.Venues)
+	}
+
+	zouk := sourceSyncs[1]
+	if zouk.EventCount != 0 || zouk.LastSuccessfulSync != "2026-10-19T06:00:00Z" {
+		t.Errorf("Expected an empty zouk sync to still be recorded, got %+v", zouk)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+const keysUsage = "usage: keys create -name NAME [-rate N] [-burst N] [-admin] | keys revoke ID | keys list"
+
+// keysCommand manages API keys from the command line. The plaintext key is only ever
+// printed by create, storage keeps its hash.
+func (app *application) keysCommand(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(keysUsage)
+	}
+
+	switch args[0] {
+	case "create":
+		return app.createAPIKey(args[1:], out)
+	case "revoke":
+		if len(args) != 2 {
+			return errors.New(keysUsage)
+		}
+		return app.revokeAPIKey(args[1], out)
+	case "list":
+		return app.listAPIKeys(out)
+	default:
+		return fmt.Errorf("unknown keys command %q, %s", args[0], keysUsage)
+	}
+}
+
+func (app *application) createAPIKey(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("keys create", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+
+	name := flags.String("name", "", "Who the key is for")
+	ratePerSecond := flags.Float64("rate", 5, "Requests per second refilled into the key's bucket")
+	burst := flags.Int("burst", 20, "Size of the key's bucket")
+	admin := flags.Bool("admin", false, "Allow the key to use the /v1/admin endpoints")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return fmt.Errorf("%v, %s", err, keysUsage)
+	}
+
+	v := newValidator()
+	v.Check(*name != "", "name", "must be provided")
+	v.Check(*ratePerSecond > 0, "rate", "must be greater than zero")
+	v.Check(*burst >= 1, "burst", "must be at least 1")
+	if !v.Valid() {
+		return fmt.Errorf("invalid key: %v", v.Errors)
+	}
+
+	plaintext, apiKey, err := generateAPIKey(*name, *ratePerSecond, *burst, *admin, time.Now())
+	if err != nil {
+		return err
+	}
+
+	err = app.dbAPIKeys.Insert(apiKey)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Created key %s for %s (%g requests/s, burst %d, admin %t)\n", apiKey.ID, apiKey.Name, apiKey.RatePerSecond, apiKey.Burst, apiKey.Admin)
+	fmt.Fprintf(out, "%s\n", plaintext)
+	fmt.Fprintln(out, "Store it now, it can't be shown again.")
+	return nil
+}
+
+func (app *application) revokeAPIKey(id string, out io.Writer) error {
+	err := app.dbAPIKeys.Revoke(id, time.Now().UTC().Format(time.RFC3339))
+	if errors.Is(err, errRecordNotFound) {
+		return fmt.Errorf("no api key with id %s", id)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Revoked key %s, running servers stop accepting it within %s\n", id, apiKeyCacheTTL)
+	return nil
+}
+
+func (app *application) listAPIKeys(out io.Writer) error {
+	apiKeys, err := app.dbAPIKeys.GetAll()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tPREFIX\tRATE\tBURST\tADMIN\tREQUESTS\tLAST USED\tCREATED\tREVOKED")
+	for _, apiKey := range apiKeys {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%g\t%d\t%t\t%d\t%s\t%s\t%s\n",
+			apiKey.ID, apiKey.Name, apiKey.Prefix, apiKey.RatePerSecond, apiKey.Burst, apiKey.Admin,
+			apiKey.RequestCount, orDash(apiKey.LastUsedAt), apiKey.CreatedAt, orDash(apiKey.RevokedAt))
+	}
+	return tw.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"edmEventsScraperApiGo.christiangabrielsson.net/ui"
+)
+
+// The ways of securing the connection to the SMTP server. STARTTLS upgrades a plain
+// connection, usually on port 587, while TLS connects encrypted from the start on port 465.
+const (
+	smtpTLSNone     = "none"
+	smtpTLSStartTLS = "starttls"
+	smtpTLSImplicit = "tls"
+)
+
+const (
+	// Attempts made to deliver an email before giving up on it.
+	mailerMaxAttempts = 3
+	// Timeout of each attempt, from connecting to the server to the end of the message.
+	mailerTimeout = 30 * time.Second
+)
+
+// emailFunctions are available to the email templates.
+var emailFunctions = template.FuncMap{
+	"humanNight": humanNight,
+	"startTime":  startTime,
+	"nightOf":    nightOf,
+	"markdown":   markdownEscape,
+}
+
+// markdownEscape escapes the characters Markdown would read as formatting in scraped names.
+func markdownEscape(s string) string {
+	return markdownReplacer.Replace(s)
+}
+
+var markdownReplacer = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "`", "\\`", "#", `\#`, "<", `\<`, ">", `\>`)
+
+// nightOf returns the night an event belongs to, or the zero time if its date can't be read.
+func nightOf(edmEvent EdmEvent) time.Time {
+	t, _ := time.Parse(queryDateFormat, eventNight(edmEvent))
+	return t
+}
+
+// mailer sends emails rendered from the templates in ui/email. Each template defines a
+// "subject", a "plainBody" and an "htmlBody", which are sent as a multipart/alternative
+// message.
+type mailer struct {
+	host     string
+	port     int
+	username string
+	password string
+	sender   string
+	tlsMode  string
+	// tlsConfig verifies the server's certificate, nil uses the system roots.
+	tlsConfig *tls.Config
+	// retryDelay is doubled after every failed attempt.
+	retryDelay time.Duration
+}
+
+func newMailer(host string, port int, username, password, sender, tlsMode string) (*mailer, error) {
+	switch tlsMode {
+	case smtpTLSNone, smtpTLSStartTLS, smtpTLSImplicit:
+	default:
+		return nil, fmt.Errorf("invalid SMTP TLS mode %q, expected %s, %s or %s", tlsMode, smtpTLSNone, smtpTLSStartTLS, smtpTLSImplicit)
+	}
+
+	if _, err := mail.ParseAddress(sender); err != nil {
+		return nil, fmt.Errorf("invalid SMTP sender %q: %v", sender, err)
+	}
+
+	return &mailer{
+		host:       host,
+		port:       port,
+		username:   username,
+		password:   password,
+		sender:     sender,
+		tlsMode:    tlsMode,
+		retryDelay: 2 * time.Second,
+	}, nil
+}
+
+// Send renders the template for the recipient and delivers it, retrying temporary failures.
+// It returns the number of attempts made.
+func (m *mailer) Send(recipient string, templateFile string, data any) (int, error) {
+	subject, plainBody, htmlBody, err := renderEmail(templateFile, data)
+	if err != nil {
+		return 0, err
+	}
+
+	msg, err := m.buildMessage(recipient, subject, plainBody, htmlBody, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	delay := m.retryDelay
+	for attempt := 1; ; attempt++ {
+		err = m.deliver(recipient, msg)
+		if err == nil || attempt == mailerMaxAttempts || isPermanentSMTPError(err) {
+			return attempt, err
+		}
+
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// renderEmail executes the three templates of an email. The subject and the plain text body
+// are text templates, the HTML body is escaped as HTML.
+func renderEmail(templateFile string, data any) (subject string, plainBody string, htmlBody string, err error) {
+	pattern := "email/" + templateFile
+
+	tmpl, err := template.New("email").Funcs(emailFunctions).ParseFS(ui.Files, pattern)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	var buf bytes.Buffer
+	for _, part := range []struct {
+		name string
+		dst  *string
+	}{
+		{"subject", &subject},
+		{"plainBody", &plainBody},
+	} {
+		buf.Reset()
+		err = tmpl.ExecuteTemplate(&buf, part.name, data)
+		if err != nil {
+			return "", "", "", err
+		}
+		*part.dst = strings.TrimSpace(buf.String())
+	}
+
+	htmlTmpl, err := htmltemplate.New("email").Funcs(htmltemplate.FuncMap(emailFunctions)).ParseFS(ui.Files, pattern)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	buf.Reset()
+	err = htmlTmpl.ExecuteTemplate(&buf, "htmlBody", data)
+	if err != nil {
+		return "", "", "", err
+	}
+	htmlBody = strings.TrimSpace(buf.String())
+
+	// Subjects come from scraped names, a line break would start a new header.
+	subject = strings.Join(strings.Fields(subject), " ")
+
+	return subject, plainBody, htmlBody, nil
+}
+
+func (m *mailer) buildMessage(recipient, subject, plainBody, htmlBody string, now time.Time) ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", plainBody},
+		{"text/html; charset=utf-8", htmlBody},
+	} {
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Type", part.contentType)
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+
+		pw, err := mw.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+
+		qw := quotedprintable.NewWriter(pw)
+		_, err = qw.Write([]byte(part.content))
+		if err != nil {
+			return nil, err
+		}
+		err = qw.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := mw.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	senderDomain := "localhost"
+	if address, err := mail.ParseAddress(m.sender); err == nil {
+		if _, domain, ok := strings.Cut(address.Address, "@"); ok {
+			senderDomain = domain
+		}
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", m.sender)
+	fmt.Fprintf(&msg, "To: %s\r\n", recipient)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <%s@%s>\r\n", getGUID(), senderDomain)
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%q\r\n", mw.Boundary())
+	fmt.Fprintf(&msg, "\r\n")
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+// deliver makes one attempt at sending the message.
+func (m *mailer) deliver(recipient string, msg []byte) error {
+	addr := net.JoinHostPort(m.host, strconv.Itoa(m.port))
+	dialer := &net.Dialer{Timeout: mailerTimeout}
+
+	tlsConfig := m.tlsConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	tlsConfig = tlsConfig.Clone()
+	tlsConfig.ServerName = m.host
+
+	var conn net.Conn
+	var err error
+	if m.tlsMode == smtpTLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(mailerTimeout))
+
+	c, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if m.tlsMode == smtpTLSStartTLS {
+		err = c.StartTLS(tlsConfig)
+		if err != nil {
+			return err
+		}
+	}
+
+	// PlainAuth refuses to send the password over an unencrypted connection to another host.
+	if m.username != "" {
+		err = c.Auth(smtp.PlainAuth("", m.username, m.password, m.host))
+		if err != nil {
+			return err
+		}
+	}
+
+	address, err := mail.ParseAddress(m.sender)
+	if err != nil {
+		return err
+	}
+	err = c.Mail(address.Address)
+	if err != nil {
+		return err
+	}
+	err = c.Rcpt(recipient)
+	if err != nil {
+		return err
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(msg)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// isPermanentSMTPError reports whether the server refused the email for good, with a 5xx
+// reply, in which case sending it again would only be refused again.
+func isPermanentSMTPError(err error) bool {
+	var protoErr *textproto.Error
+	return errors.As(err, &protoErr) && protoErr.Code >= 500
+}
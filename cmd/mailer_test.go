@@ -0,0 +1,474 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSMTPMessage is an email received by the fake SMTP server.
+type fakeSMTPMessage struct {
+	from string
+	to   []string
+	auth string
+	tls  bool
+	data []byte
+}
+
+// fakeSMTPServer is an in-process SMTP server speaking just enough of the protocol for
+// net/smtp. It answers DATA with a temporary 451 the first failures times, or always with
+// replyCode when it's set.
+type fakeSMTPServer struct {
+	ln        net.Listener
+	tlsConfig *tls.Config
+	rootCAs   *x509.CertPool
+
+	mu        sync.Mutex
+	failures  int
+	replyCode string
+	messages  []fakeSMTPMessage
+}
+
+// newFakeSMTPServer starts a fake SMTP server offering STARTTLS, or speaking TLS from the
+// start when implicitTLS is set.
+func newFakeSMTPServer(t *testing.T, implicitTLS bool) *fakeSMTPServer {
+	t.Helper()
+
+	// Borrow the test certificate of httptest, it's valid for 127.0.0.1.
+	ts := httptest.NewUnstartedServer(nil)
+	ts.StartTLS()
+	tlsConfig := &tls.Config{Certificates: ts.TLS.Certificates}
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(ts.Certificate())
+	ts.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if implicitTLS {
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+
+	s := &fakeSMTPServer{ln: ln, tlsConfig: tlsConfig, rootCAs: rootCAs}
+	go s.serve()
+	t.Cleanup(func() { ln.Close() })
+
+	return s
+}
+
+// mailer returns a mailer sending through the fake server without waiting between attempts.
+func (s *fakeSMTPServer) mailer(t *testing.T, tlsMode string, username string, password string) *mailer {
+	t.Helper()
+
+	addr := s.ln.Addr().(*net.TCPAddr)
+	m, err := newMailer(addr.IP.String(), addr.Port, username, password, "EDM Events <alerts@example.com>", tlsMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.tlsConfig = &tls.Config{RootCAs: s.rootCAs}
+	m.retryDelay = time.Millisecond
+
+	return m
+}
+
+func (s *fakeSMTPServer) received() []fakeSMTPMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]fakeSMTPMessage{}, s.messages...)
+}
+
+func (s *fakeSMTPServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	_, isTLS := conn.(*tls.Conn)
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 fake ESMTP")
+
+	var msg fakeSMTPMessage
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250-fake")
+			if !isTLS {
+				tp.PrintfLine("250-STARTTLS")
+			}
+			tp.PrintfLine("250 AUTH PLAIN")
+		case "STARTTLS":
+			tp.PrintfLine("220 ready to start TLS")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, isTLS = tlsConn, true
+			tp = textproto.NewConn(conn)
+		case "AUTH":
+			_, initial, _ := strings.Cut(arg, " ")
+			decoded, _ := base64.StdEncoding.DecodeString(initial)
+			msg.auth = string(decoded)
+			tp.PrintfLine("235 authenticated")
+		case "MAIL":
+			msg.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			msg.to = append(msg.to, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			s.mu.Lock()
+			replyCode := s.replyCode
+			if replyCode == "" && s.failures > 0 {
+				s.failures--
+				replyCode = "451"
+			}
+			s.mu.Unlock()
+
+			if replyCode != "" {
+				tp.PrintfLine("%s rejected", replyCode)
+				continue
+			}
+
+			tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.data = data
+			msg.tls = isTLS
+
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+
+			msg = fakeSMTPMessage{}
+			tp.PrintfLine("250 queued")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("250 ok")
+		}
+	}
+}
+
+// parsedEmail is a received email with its decoded subject and bodies.
+type parsedEmail struct {
+	header    mail.Header
+	subject   string
+	plainBody string
+	htmlBody  string
+}
+
+func parseEmail(t *testing.T, data []byte) parsedEmail {
+	t.Helper()
+
+	msg, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(string(data))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Expected a multipart/alternative email, got '%s'", msg.Header.Get("Content-Type"))
+	}
+
+	email := parsedEmail{header: msg.Header, subject: subject}
+
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		switch part.Header.Get("Content-Type") {
+		case "text/plain; charset=utf-8":
+			email.plainBody = string(body)
+		case "text/html; charset=utf-8":
+			email.htmlBody = string(body)
+		}
+	}
+
+	return email
+}
+
+var mailerTestAlerts = []Alert{
+	{ID: "a1", Type: alertTypeAnnounced, EdmEvent: EdmEvent{Id: "e1", ArtistName: "tiësto", ClubName: "xs nightclub", EventDate: "2026-11-20T00:00:00Z", TicketUrl: "https://www.wynnsocial.com/events/20261120"}},
+	{ID: "a2", Type: alertTypeAnnounced, EdmEvent: EdmEvent{Id: "e2", ArtistName: "<b>kygo</b>", ClubName: "omnia", EventDate: "2026-11-22T01:30:00Z"}},
+}
+
+// TestMailer_Send tests delivery over each kind of connection, with and without authentication
+func TestMailer_Send(t *testing.T) {
+	tests := []struct {
+		name        string
+		tlsMode     string
+		implicitTLS bool
+		username    string
+		expectTLS   bool
+	}{
+		{name: "Plain", tlsMode: smtpTLSNone},
+		{name: "Plain with auth on localhost", tlsMode: smtpTLSNone, username: "user"},
+		{name: "STARTTLS with auth", tlsMode: smtpTLSStartTLS, username: "user", expectTLS: true},
+		{name: "Implicit TLS with auth", tlsMode: smtpTLSImplicit, implicitTLS: true, username: "user", expectTLS: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeSMTPServer(t, tt.implicitTLS)
+			m := server.mailer(t, tt.tlsMode, tt.username, "secret")
+
+			attempts, err := m.Send("fan@example.com", "alerts.tmpl", map[string]any{"Alerts": mailerTestAlerts[:1]})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if attempts != 1 {
+				t.Errorf("Expected 1 attempt, got %d", attempts)
+			}
+
+			messages := server.received()
+			if len(messages) != 1 {
+				t.Fatalf("Expected 1 email, got %d", len(messages))
+			}
+			received := messages[0]
+
+			if received.from != "alerts@example.com" {
+				t.Errorf("Expected envelope sender 'alerts@example.com', got '%s'", received.from)
+			}
+			if len(received.to) != 1 || received.to[0] != "fan@example.com" {
+				t.Errorf("Expected envelope recipient 'fan@example.com', got %v", received.to)
+			}
+			if received.tls != tt.expectTLS {
+				t.Errorf("Expected TLS %v, got %v", tt.expectTLS, received.tls)
+			}
+
+			expectedAuth := ""
+			if tt.username != "" {
+				expectedAuth = "\x00user\x00secret"
+			}
+			if received.auth != expectedAuth {
+				t.Errorf("Expected auth %q, got %q", expectedAuth, received.auth)
+			}
+
+			email := parseEmail(t, received.data)
+			if email.subject != "tiësto is playing xs nightclub" {
+				t.Errorf("Expected subject 'tiësto is playing xs nightclub', got '%s'", email.subject)
+			}
+			if email.header.Get("To") != "fan@example.com" || email.header.Get("Message-Id") == "" {
+				t.Errorf("Expected To and Message-ID headers, got %v", email.header)
+			}
+		})
+	}
+}
+
+// TestMailer_Retries tests that temporary failures are retried and permanent ones aren't
+func TestMailer_Retries(t *testing.T) {
+	tests := []struct {
+		name             string
+		failures         int
+		replyCode        string
+		expectedAttempts int
+		expectError      bool
+		expectedMessages int
+	}{
+		{name: "Recovers", failures: 2, expectedAttempts: 3, expectedMessages: 1},
+		{name: "Gives up", failures: 5, expectedAttempts: mailerMaxAttempts, expectError: true},
+		{name: "Permanent failure", replyCode: "550", expectedAttempts: 1, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeSMTPServer(t, false)
+			server.failures = tt.failures
+			server.replyCode = tt.replyCode
+			m := server.mailer(t, smtpTLSNone, "", "")
+
+			attempts, err := m.Send("fan@example.com", "alerts.tmpl", map[string]any{"Alerts": mailerTestAlerts})
+			if attempts != tt.expectedAttempts {
+				t.Errorf("Expected %d attempts, got %d", tt.expectedAttempts, attempts)
+			}
+			if tt.expectError && err == nil {
+				t.Error("Expected an error, got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if messages := server.received(); len(messages) != tt.expectedMessages {
+				t.Errorf("Expected %d emails, got %d", tt.expectedMessages, len(messages))
+			}
+		})
+	}
+}
+
+// TestNewMailer tests the validation of the SMTP configuration
+func TestNewMailer(t *testing.T) {
+	_, err := newMailer("smtp.example.com", 587, "", "", "EDM Events <alerts@example.com>", "ssl")
+	if err == nil {
+		t.Error("Expected an error for an unknown TLS mode, got none")
+	}
+
+	_, err = newMailer("smtp.example.com", 587, "", "", "not an address", smtpTLSStartTLS)
+	if err == nil {
+		t.Error("Expected an error for an invalid sender, got none")
+	}
+}
+
+// TestRenderEmail_Alerts tests the subject and bodies of alert emails
+func TestRenderEmail_Alerts(t *testing.T) {
+	subject, plainBody, htmlBody, err := renderEmail("alerts.tmpl", map[string]any{"Alerts": mailerTestAlerts})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if subject != "2 new Vegas dates for artists you watch" {
+		t.Errorf("Expected subject '2 new Vegas dates for artists you watch', got '%s'", subject)
+	}
+
+	for _, expected := range []string{
+		"tiësto",
+		"Friday, November 20, 2026, xs nightclub",
+		"Tickets: https://www.wynnsocial.com/events/20261120",
+		"Saturday, November 21, 2026 at 1:30 AM, omnia",
+	} {
+		if !strings.Contains(plainBody, expected) {
+			t.Errorf("Expected the plain text body to contain '%s', got:\n%s", expected, plainBody)
+		}
+	}
+
+	if !strings.Contains(htmlBody, `<a href="https://www.wynnsocial.com/events/20261120">Tickets</a>`) {
+		t.Errorf("Expected the HTML body to link to the tickets, got:\n%s", htmlBody)
+	}
+	if strings.Contains(htmlBody, "<b>kygo</b>") || !strings.Contains(htmlBody, "&lt;b&gt;kygo&lt;/b&gt;") {
+		t.Errorf("Expected the HTML body to escape artist names, got:\n%s", htmlBody)
+	}
+}
+
+// TestEmailAlerts tests that each address gets one email and that every email is logged
+func TestEmailAlerts(t *testing.T) {
+	watchlists := []Watchlist{
+		{ID: "w1", UserID: "alice", Artists: []string{"Tiësto"}, Email: "alice@example.com"},
+		{ID: "w2", UserID: "alice", Artists: []string{"tiesto", "kygo"}, Email: "alice@example.com"},
+		{ID: "w3", UserID: "bob", Artists: []string{"Kygo"}},
+	}
+	alerts := []Alert{
+		{ID: "a1", UserID: "alice", Type: alertTypeAnnounced, WatchlistIDs: []string{"w1", "w2"}, EdmEvent: mailerTestAlerts[0].EdmEvent},
+		{ID: "a2", UserID: "alice", Type: alertTypeAnnounced, WatchlistIDs: []string{"w2"}, EdmEvent: mailerTestAlerts[1].EdmEvent},
+		{ID: "a3", UserID: "bob", Type: alertTypeAnnounced, WatchlistIDs: []string{"w3"}, EdmEvent: mailerTestAlerts[1].EdmEvent},
+	}
+
+	t.Run("Sent", func(t *testing.T) {
+		server := newFakeSMTPServer(t, false)
+
+		app := newTestApplication(t, nil)
+		app.mailer = server.mailer(t, smtpTLSNone, "", "")
+
+		app.emailAlerts(alerts, watchlists)
+
+		messages := server.received()
+		if len(messages) != 1 {
+			t.Fatalf("Expected 1 email, got %d", len(messages))
+		}
+		if email := parseEmail(t, messages[0].data); email.subject != "2 new Vegas dates for artists you watch" {
+			t.Errorf("Expected both alerts in one email, got subject '%s'", email.subject)
+		}
+
+		entries := app.dbSendLog.(*mockSendLogModel).entries
+		if len(entries) != 1 {
+			t.Fatalf("Expected 1 send log entry, got %d", len(entries))
+		}
+		entry := entries[0]
+		if entry.Channel != channelEmail || entry.Recipient != "alice@example.com" || entry.Status != sendStatusSent || entry.Attempts != 1 {
+			t.Errorf("Expected a sent email to alice@example.com, got %+v", entry)
+		}
+		if strings.Join(entry.AlertIDs, ",") != "a1,a2" {
+			t.Errorf("Expected alerts a1,a2 to be logged, got %v", entry.AlertIDs)
+		}
+	})
+
+	t.Run("Sold out", func(t *testing.T) {
+		server := newFakeSMTPServer(t, false)
+
+		app := newTestApplication(t, nil)
+		app.mailer = server.mailer(t, smtpTLSNone, "", "")
+
+		soldOut := alerts[0]
+		soldOut.ID = "a4"
+		soldOut.Type = alertTypeSoldOut
+		app.emailAlerts([]Alert{alerts[0], soldOut}, watchlists)
+
+		messages := server.received()
+		if len(messages) != 2 {
+			t.Fatalf("Expected an announcement and a sold out email, got %d", len(messages))
+		}
+		if email := parseEmail(t, messages[1].data); email.subject != "tiësto at xs nightclub is sold out" {
+			t.Errorf("Expected the sold out subject, got '%s'", email.subject)
+		}
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		server := newFakeSMTPServer(t, false)
+		server.replyCode = "554"
+
+		app := newTestApplication(t, nil)
+		app.mailer = server.mailer(t, smtpTLSNone, "", "")
+
+		app.emailAlerts(alerts, watchlists)
+
+		entries := app.dbSendLog.(*mockSendLogModel).entries
+		if len(entries) != 1 {
+			t.Fatalf("Expected 1 send log entry, got %d", len(entries))
+		}
+		if entries[0].Status != sendStatusFailed || entries[0].Error == "" {
+			t.Errorf("Expected a failed email with its error, got %+v", entries[0])
+		}
+	})
+
+	t.Run("No mailer", func(t *testing.T) {
+		app := newTestApplication(t, nil)
+
+		app.emailAlerts(alerts, watchlists)
+
+		if entries := app.dbSendLog.(*mockSendLogModel).entries; len(entries) != 0 {
+			t.Errorf("Expected nothing to be logged without a mailer, got %d entries", len(entries))
+		}
+	})
+}
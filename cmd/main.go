@@ -2,9 +2,17 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"html/template"
 	"log"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"google.golang.org/api/option"
@@ -13,17 +21,92 @@ import (
 const version = "1.0.0"
 
 type config struct {
-	port int
-	env  string
+	port    int
+	env     string
+	graphql struct {
+		allowlist string
+	}
+	chat struct {
+		channels string
+	}
+	telegram struct {
+		token  string
+		apiURL string
+	}
+	push struct {
+		vapidPrivateKey string
+		vapidSubject    string
+		services        []string
+	}
+	apiKeys struct {
+		require bool
+	}
+	status struct {
+		maxAge time.Duration
+	}
+	anomaly struct {
+		threshold   float64
+		acceptAfter int
+	}
+	ops struct {
+		emails []string
+	}
+	smtp struct {
+		host     string
+		port     int
+		username string
+		password string
+		sender   string
+		tls      string
+	}
+	cors struct {
+		trustedOrigins   []string
+		allowedMethods   []string
+		allowedHeaders   []string
+		allowCredentials bool
+		maxAge           time.Duration
+	}
+	server struct {
+		readTimeout     time.Duration
+		writeTimeout    time.Duration
+		idleTimeout     time.Duration
+		shutdownTimeout time.Duration
+	}
 }
 
 // Define an application struct to hold the dependencies for our HTTP handlers, helpers,
 // and middleware.
 type application struct {
-	config     config
-	logger     *log.Logger
-	dbConfig   DBConfig
-	dbSnippets SnippetModelInterface
+	config              config
+	logger              *log.Logger
+	dbConfig            DBConfig
+	dbSnippets          SnippetModelInterface
+	dbSyncStates        SyncStateModelInterface
+	dbAPIKeys           APIKeyModelInterface
+	dbWatchlists        WatchlistModelInterface
+	dbAlerts            AlertModelInterface
+	dbSendLog           SendLogModelInterface
+	dbDigests           DigestModelInterface
+	dbWebhooks          WebhookModelInterface
+	dbWebhookDeliveries WebhookDeliveryModelInterface
+	dbRuns              RunModelInterface
+	dbPushSubscriptions PushSubscriptionModelInterface
+	mailer              *mailer
+	chat                *chatNotifier
+	telegram            *telegramBot
+	push                *webPusher
+	webhooks            *webhookSender
+	apiKeyAuth          *apiKeyAuth
+	persistedQueries    map[string]string
+	responseCache       *responseCache
+	templateCache       map[string]*template.Template
+	scrapingURLs        ScrapingURLs
+	runs                *runManager
+	changes             *changeHub
+	syncNotify          chan struct{}
+	searchIndex         *searchIndex
+	cors                *corsPolicy
+	wg                  sync.WaitGroup
 }
 
 type DBConfig struct {
@@ -41,10 +124,90 @@ type ScrapingURLs struct {
 
 func main() {
 
+	// Declare an instance of the config struct.
+	var cfg config
+
+	// Cloud Run tells the container which port to listen on through $PORT.
+	defaultPort := 4000
+	if port, err := strconv.Atoi(os.Getenv("PORT")); err == nil {
+		defaultPort = port
+	}
+
+	flag.IntVar(&cfg.port, "port", defaultPort, "API server port")
+	flag.StringVar(&cfg.env, "env", "development", "Environment (development|staging|production)")
+	flag.StringVar(&cfg.graphql.allowlist, "graphql-allowlist", "", "JSON file of persisted GraphQL queries, only these are executed when set")
+	flag.BoolVar(&cfg.apiKeys.require, "require-api-key", false, "Reject read API requests that don't send an API key")
+	flag.DurationVar(&cfg.status.maxAge, "max-data-age", 36*time.Hour, "Age after which /v1/status reports a source's data as stale")
+	flag.DurationVar(&cfg.server.readTimeout, "read-timeout", 5*time.Second, "Maximum duration for reading a request")
+	flag.DurationVar(&cfg.server.writeTimeout, "write-timeout", 30*time.Second, "Maximum duration for writing a response, streams and exports are exempt")
+	flag.DurationVar(&cfg.server.idleTimeout, "idle-timeout", time.Minute, "Maximum time a keep-alive connection waits for the next request")
+	flag.DurationVar(&cfg.server.shutdownTimeout, "shutdown-timeout", 10*time.Second, "Time given to in-flight requests and runs to finish on shutdown")
+	flag.Float64Var(&cfg.anomaly.threshold, "anomaly-threshold", 0.5, "Fraction of its usual event count below which a source's scrape is treated as broken and its events are kept, 0 accepts any count")
+	flag.IntVar(&cfg.anomaly.acceptAfter, "anomaly-accept-after", 3, "Runs in a row a source is flagged before its lower count is accepted as its usual count, 0 never accepts it")
+	flag.Func("ops-emails", "Addresses emailed when a source's scrape looks broken, separated by spaces or commas", func(val string) error {
+		cfg.ops.emails = splitList(val)
+		for _, email := range cfg.ops.emails {
+			if !Matches(email, EmailRX) {
+				return fmt.Errorf("%q is not a valid email address", email)
+			}
+		}
+		return nil
+	})
+	flag.StringVar(&cfg.smtp.host, "smtp-host", "", "SMTP server used to email alerts, emails are disabled when empty")
+	flag.IntVar(&cfg.smtp.port, "smtp-port", 587, "SMTP server port")
+	flag.StringVar(&cfg.smtp.username, "smtp-username", "", "SMTP username, no authentication when empty")
+	flag.StringVar(&cfg.smtp.password, "smtp-password", os.Getenv("SMTP_PASSWORD"), "SMTP password (default $SMTP_PASSWORD)")
+	flag.StringVar(&cfg.smtp.sender, "smtp-sender", "EDM Events Las Vegas <no-reply@edmevents.example.com>", "From address of the emails")
+	flag.StringVar(&cfg.smtp.tls, "smtp-tls", smtpTLSStartTLS, "Securing of the SMTP connection (none|starttls|tls)")
+	flag.StringVar(&cfg.chat.channels, "chat-channels", "", "JSON file of the Discord and Slack channels new events are posted to")
+	flag.StringVar(&cfg.telegram.token, "telegram-token", os.Getenv("TELEGRAM_BOT_TOKEN"), "Telegram bot token, enables the bot command and Telegram alerts (default $TELEGRAM_BOT_TOKEN)")
+	flag.StringVar(&cfg.telegram.apiURL, "telegram-api-url", defaultTelegramAPIURL, "Base URL of the Telegram Bot API")
+	flag.StringVar(&cfg.push.vapidPrivateKey, "vapid-private-key", os.Getenv("VAPID_PRIVATE_KEY"), "Base64url VAPID private key, enables Web Push alerts (default $VAPID_PRIVATE_KEY)")
+	flag.StringVar(&cfg.push.vapidSubject, "vapid-subject", "", "mailto: or https:// contact sent to push services with every message, required with a VAPID key")
+	cfg.push.services = defaultPushServices
+	flag.Func("push-services", "Origins of the push services subscriptions may point at, separated by spaces or commas (default \""+strings.Join(defaultPushServices, ",")+"\")", func(val string) error {
+		cfg.push.services = splitList(val)
+		return nil
+	})
+
+	cfg.cors.allowedMethods = defaultCORSMethods
+	cfg.cors.allowedHeaders = defaultCORSHeaders
+	flag.Func("cors-trusted-origins", "Origins allowed to call the API from a browser, separated by spaces or commas (https://*.example.com allows subdomains, * any origin)", func(val string) error {
+		cfg.cors.trustedOrigins = splitList(val)
+		return nil
+	})
+	flag.Func("cors-allowed-methods", "Methods allowed in CORS requests (default \""+strings.Join(defaultCORSMethods, ",")+"\")", func(val string) error {
+		cfg.cors.allowedMethods = splitList(strings.ToUpper(val))
+		return nil
+	})
+	flag.Func("cors-allowed-headers", "Request headers allowed in CORS requests (default \""+strings.Join(defaultCORSHeaders, ",")+"\")", func(val string) error {
+		cfg.cors.allowedHeaders = splitList(val)
+		return nil
+	})
+	flag.BoolVar(&cfg.cors.allowCredentials, "cors-allow-credentials", false, "Let browsers send cookies and authorization headers with CORS requests, requires listed origins")
+	flag.DurationVar(&cfg.cors.maxAge, "cors-max-age", 2*time.Hour, "How long browsers may cache a preflight response")
+	flag.Parse()
+
 	// Initialize a new logger which writes messages to the standard out stream,
 	// prefixed with the current date and time.
 	logger := log.New(os.Stdout, "", log.Ldate|log.Ltime)
 
+	if cfg.anomaly.threshold < 0 || cfg.anomaly.threshold > 1 {
+		logger.Fatalf("-anomaly-threshold must be between 0 and 1, got %v", cfg.anomaly.threshold)
+	}
+	if cfg.anomaly.acceptAfter < 0 {
+		logger.Fatalf("-anomaly-accept-after must not be negative, got %d", cfg.anomaly.acceptAfter)
+	}
+
+	// Generating VAPID keys doesn't need the database.
+	if flag.Arg(0) == "vapid" {
+		err := vapidCommand(flag.Args()[1:], cfg.push.vapidPrivateKey, os.Stdout)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		return
+	}
+
 	// Debug Scraper
 	// dmEvents := getEdmEventsFromAllLasVegas()
 	//println(dmEvents)
@@ -63,8 +226,6 @@ func main() {
 	if collection == "" {
 		log.Fatalf("Environment variable not set %v", collection)
 	}
-	// Declare an instance of the config struct.
-	var cfg config
 
 	dbConfig := DBConfig{
 		projectID:  projectID,
@@ -82,9 +243,61 @@ func main() {
 	// Declare an instance of the application struct, containing the config struct and
 	// the logger.
 	app := &application{
-		config:   cfg,
-		dbConfig: dbConfig,
-		logger:   logger,
+		config:        cfg,
+		dbConfig:      dbConfig,
+		logger:        logger,
+		responseCache: newResponseCache(),
+		apiKeyAuth:    newAPIKeyAuth(),
+		scrapingURLs:  ScrapingURLs,
+		runs:          newRunManager(),
+		changes:       newChangeHub(time.Now()),
+		syncNotify:    make(chan struct{}, 1),
+		searchIndex:   newSearchIndex(),
+		webhooks:      newWebhookSender(),
+	}
+
+	templateCache, err := newTemplateCache()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	app.templateCache = templateCache
+
+	app.cors, err = newCORSPolicy(cfg)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	if cfg.smtp.host != "" {
+		app.mailer, err = newMailer(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender, cfg.smtp.tls)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	if cfg.chat.channels != "" {
+		app.chat, err = newChatNotifier(cfg.chat.channels)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	if cfg.telegram.token != "" {
+		app.telegram = newTelegramBot(cfg.telegram.apiURL, cfg.telegram.token)
+	}
+
+	if cfg.push.vapidPrivateKey != "" {
+		app.push, err = newWebPusher(cfg.push.vapidPrivateKey, cfg.push.vapidSubject, cfg.push.services)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	if cfg.graphql.allowlist != "" {
+		persistedQueries, err := loadPersistedQueries(cfg.graphql.allowlist)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		app.persistedQueries = persistedQueries
 	}
 
 	db, err := app.openDB()
@@ -100,10 +313,101 @@ func main() {
 		Collection: collection,
 	}
 
-	// This should bubble up and error in case there is a fatal error, such as a wrong scrape or something
-	// We will have to differentiate between a bad scrape that can continue scrapping other events and still fail the job
-	// And really bad ones where we stop the process
-	app.addEdmEventsToFirestore(ScrapingURLs)
+	app.dbSyncStates = &SyncStateModel{
+		Client:     db,
+		Collection: collection + "_sync",
+	}
+
+	app.dbAPIKeys = &APIKeyModel{
+		Client:     db,
+		Collection: collection + "_apikeys",
+	}
+
+	app.dbWatchlists = &WatchlistModel{
+		Client:     db,
+		Collection: collection + "_watchlists",
+	}
+
+	app.dbAlerts = &AlertModel{
+		Client:     db,
+		Collection: collection + "_alerts",
+	}
+
+	app.dbSendLog = &SendLogModel{
+		Client:     db,
+		Collection: collection + "_sendlog",
+	}
+
+	app.dbDigests = &DigestModel{
+		Client:     db,
+		Collection: collection + "_digests",
+	}
+
+	app.dbWebhooks = &WebhookModel{
+		Client:     db,
+		Collection: collection + "_webhooks",
+	}
+
+	app.dbWebhookDeliveries = &WebhookDeliveryModel{
+		Client:     db,
+		Collection: collection + "_webhookdeliveries",
+	}
+
+	app.dbRuns = &RunModel{
+		Client:     db,
+		Collection: collection + "_runs",
+	}
+
+	app.dbPushSubscriptions = &PushSubscriptionModel{
+		Client:     db,
+		Collection: collection + "_pushsubscriptions",
+	}
+
+	// Without a command the binary keeps behaving like the Cloud Run job it was built as,
+	// "serve" starts the read API on top of the same collection instead.
+	switch flag.Arg(0) {
+	case "serve":
+		err = app.serve()
+		if err != nil {
+			logger.Fatal(err)
+		}
+	case "", "scrape":
+		// This should bubble up and error in case there is a fatal error, such as a wrong scrape or something
+		// We will have to differentiate between a bad scrape that can continue scrapping other events and still fail the job
+		// And really bad ones where we stop the process
+		err = app.addEdmEventsToFirestore(os.Stdout)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	case "runs":
+		err = app.runsCommand(flag.Args()[1:], os.Stdout)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	case "keys":
+		err = app.keysCommand(flag.Args()[1:], os.Stdout)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	case "digest":
+		err = app.digestCommand(flag.Args()[1:], os.Stdout)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	case "bot":
+		if app.telegram == nil {
+			logger.Fatal("The bot command needs a Telegram bot token, set -telegram-token or $TELEGRAM_BOT_TOKEN")
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		logger.Print("Telegram bot listening for commands")
+		err = app.runTelegramBot(ctx)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	default:
+		logger.Fatalf("Unknown command %q, expected scrape, serve, keys, runs, digest, bot or vapid", flag.Arg(0))
+	}
 
 }
 
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Request ids sent by clients or load balancers are reused when they are at most this long.
+const maxRequestIDLength = 128
+
+// recoverPanic turns a panic in a handler into a 500 response, JSON for the API and plain text
+// for the pages, instead of dropping the connection.
+func (app *application) recoverPanic(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if err := recover(); err != nil {
+				// The handler aborted the response on purpose, let the server close it.
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
+				// Make the server close the connection after the response is sent.
+				w.Header().Set("Connection", "close")
+
+				if isAPIPath(r.URL.Path) {
+					app.serverErrorResponse(w, r, fmt.Errorf("%s", err))
+				} else {
+					app.serverError(w, r, fmt.Errorf("%s", err))
+				}
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestID gives every request an id, reusing the X-Request-ID header when one was sent,
+// and returns it in the X-Request-ID response header so that it can be quoted back to us.
+func (app *application) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get("X-Request-ID")
+		if !isValidRequestID(requestID) {
+			requestID = getGUID()
+		}
+
+		w.Header().Set("X-Request-ID", requestID)
+		r = app.contextSetRequestID(r, requestID)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range requestID {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// loggingResponseWriter records the status and size of a response for the access log. It
+// unwraps to the underlying writer so that http.ResponseController can still flush streams.
+type loggingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (lw *loggingResponseWriter) WriteHeader(status int) {
+	if lw.status == 0 {
+		lw.status = status
+	}
+	lw.ResponseWriter.WriteHeader(status)
+}
+
+func (lw *loggingResponseWriter) Write(p []byte) (int, error) {
+	if lw.status == 0 {
+		lw.status = http.StatusOK
+	}
+	n, err := lw.ResponseWriter.Write(p)
+	lw.bytes += n
+	return n, err
+}
+
+func (lw *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return lw.ResponseWriter
+}
+
+// logRequest writes an access log line for every request once it has been answered.
+func (app *application) logRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		lw := &loggingResponseWriter{ResponseWriter: w}
+
+		next.ServeHTTP(lw, r)
+
+		if lw.status == 0 {
+			lw.status = http.StatusOK
+		}
+
+		app.logger.Printf("request_id=%s method=%s uri=%q status=%d bytes=%d duration=%s remote_addr=%s",
+			app.contextGetRequestID(r), r.Method, r.URL.RequestURI(), lw.status, lw.bytes, time.Since(start).Round(time.Microsecond), r.RemoteAddr)
+	})
+}
+
+// Paths that never need an API key, so that clients can discover how to use the API and
+// monitoring can check on it.
+var apiKeyExemptPaths = map[string]bool{
+	"/v1/openapi.json": true,
+	"/v1/status":       true,
+}
+
+// authenticate identifies the API key sent with an API request, if any, and applies its rate
+// limit. Keys are optional unless the server runs with -require-api-key, but a key that is
+// sent must always be valid. The HTML pages are for browsers and never need a key.
+func (app *application) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isAPIPath(r.URL.Path) || apiKeyExemptPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		plaintext, err := requestAPIKey(r)
+		if err != nil {
+			app.invalidAPIKeyResponse(w, r)
+			return
+		}
+
+		if plaintext == "" {
+			if app.config.apiKeys.require {
+				app.apiKeyRequiredResponse(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		now := time.Now()
+
+		apiKey, found, err := app.apiKeyAuth.lookup(app.dbAPIKeys, hashAPIKey(plaintext), now)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if !found || apiKey.revoked() {
+			app.invalidAPIKeyResponse(w, r)
+			return
+		}
+
+		if ok, retryAfter := app.apiKeyAuth.allow(apiKey, now); !ok {
+			app.rateLimitExceededResponse(w, r, retryAfter)
+			return
+		}
+		app.apiKeyAuth.recordRequest(apiKey)
+
+		r = app.contextSetAPIKey(r, apiKey)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireAPIKey only lets requests through that were authenticated with a key, for the
+// endpoints that keep data per key such as watchlists.
+func (app *application) requireAPIKey(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, ok := app.contextGetAPIKey(r)
+		if !ok {
+			app.apiKeyRequiredResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// requireAdminAPIKey only lets requests through that were authenticated with an admin key.
+func (app *application) requireAdminAPIKey(next http.HandlerFunc) http.HandlerFunc {
+	return app.requireAPIKey(func(w http.ResponseWriter, r *http.Request) {
+		apiKey, _ := app.contextGetAPIKey(r)
+		if !apiKey.Admin {
+			app.notPermittedResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestAPIKey reads the key from the Authorization or X-API-Key header, or from the api_key
+// query parameter for clients such as calendar apps that can't send headers.
+func requestAPIKey(r *http.Request) (string, error) {
+	if authorization := r.Header.Get("Authorization"); authorization != "" {
+		scheme, token, ok := strings.Cut(authorization, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+			return "", errors.New("malformed Authorization header")
+		}
+		return strings.TrimSpace(token), nil
+	}
+
+	if apiKey := r.Header.Get("X-API-Key"); apiKey != "" {
+		return strings.TrimSpace(apiKey), nil
+	}
+
+	return strings.TrimSpace(r.URL.Query().Get("api_key")), nil
+}
+
+func retryAfterSeconds(retryAfter time.Duration) string {
+	return strconv.Itoa(int(math.Max(1, math.Ceil(retryAfter.Seconds()))))
+}
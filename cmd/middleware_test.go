@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestRecoverPanic tests that a panicking handler is answered with a 500, in JSON for the API
+func TestRecoverPanic(t *testing.T) {
+	tests := []struct {
+		name                string
+		path                string
+		expectedContentType string
+	}{
+		{name: "API", path: "/v1/events", expectedContentType: "application/json"},
+		{name: "Page", path: "/events", expectedContentType: "text/plain; charset=utf-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, nil)
+
+			handler := app.recoverPanic(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				panic("something went wrong")
+			}))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rr.Code != http.StatusInternalServerError {
+				t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, rr.Code)
+			}
+			if contentType := rr.Header().Get("Content-Type"); contentType != tt.expectedContentType {
+				t.Errorf("Expected content type '%s', got '%s'", tt.expectedContentType, contentType)
+			}
+			if connection := rr.Header().Get("Connection"); connection != "close" {
+				t.Errorf("Expected Connection 'close', got '%s'", connection)
+			}
+		})
+	}
+}
+
+// TestRecoverPanic_JSONError tests that the JSON error of a recovered panic doesn't leak the panic value
+func TestRecoverPanic_JSONError(t *testing.T) {
+	app := newTestApplication(t, nil)
+
+	ts := httptest.NewServer(app.requestID(app.recoverPanic(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("secret detail")
+	}))))
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/v1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	var body map[string]any
+	err = json.NewDecoder(res.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message, _ := body["error"].(string)
+	if message == "" || strings.Contains(message, "secret detail") {
+		t.Errorf("Expected a generic error message, got '%v'", body["error"])
+	}
+}
+
+// TestRequestID tests that request ids are taken from the request when valid and generated otherwise
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name       string
+		requestID  string
+		expectSame bool
+	}{
+		{name: "Sent", requestID: "abc-123", expectSame: true},
+		{name: "Missing", requestID: ""},
+		{name: "Too long", requestID: strings.Repeat("a", maxRequestIDLength+1)},
+		{name: "Invalid characters", requestID: "abc 123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, nil)
+
+			var contextRequestID string
+			handler := app.requestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				contextRequestID = app.contextGetRequestID(r)
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/v1/events", nil)
+			if tt.requestID != "" {
+				r.Header.Set("X-Request-ID", tt.requestID)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, r)
+
+			headerRequestID := rr.Header().Get("X-Request-ID")
+			if headerRequestID == "" {
+				t.Fatal("Expected an X-Request-ID header, got none")
+			}
+			if headerRequestID != contextRequestID {
+				t.Errorf("Expected the request id '%s' in the context, got '%s'", headerRequestID, contextRequestID)
+			}
+			if tt.expectSame && headerRequestID != tt.requestID {
+				t.Errorf("Expected request id '%s', got '%s'", tt.requestID, headerRequestID)
+			}
+			if !tt.expectSame && headerRequestID == tt.requestID {
+				t.Errorf("Expected a generated request id, got '%s'", headerRequestID)
+			}
+		})
+	}
+}
+
+// TestLogRequest tests the access log line written for a request
+func TestLogRequest(t *testing.T) {
+	app := newTestApplication(t, nil)
+
+	var buf bytes.Buffer
+	app.logger = log.New(&buf, "", 0)
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/v1/events?page=2", nil)
+	r.Header.Set("X-Request-ID", "abc-123")
+	app.routes().ServeHTTP(rr, r)
+
+	logLine := buf.String()
+	for _, expected := range []string{
+		"request_id=abc-123",
+		"method=GET",
+		`uri="/v1/events?page=2"`,
+		"status=200",
+		"bytes=",
+	} {
+		if !strings.Contains(logLine, expected) {
+			t.Errorf("Expected the access log to contain '%s', got '%s'", expected, logLine)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const newEventsFeedTitle = "Newly announced Las Vegas EDM events"
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	AtomLink      atomLink  `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link,omitempty"`
+	Description string  `xml:"description"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Updated   string     `xml:"updated"`
+	Published string     `xml:"published"`
+	Links     []atomLink `xml:"link,omitempty"`
+	Summary   string     `xml:"summary"`
+}
+
+// newlyAnnouncedEdmEvents returns the requested page of the events matching the filters that
+// have a first-seen timestamp, most recently announced first.
+func (app *application) newlyAnnouncedEdmEvents(f EventFilters) ([]EdmEvent, error) {
+	edmEvents, err := app.queryEdmEvents(f)
+	if err != nil {
+		return nil, err
+	}
+
+	announced := []EdmEvent{}
+	for _, edmEvent := range edmEvents {
+		if edmEvent.FirstSeen != "" {
+			announced = append(announced, edmEvent)
+		}
+	}
+
+	// FirstSeen is RFC3339 in UTC, so the strings sort chronologically.
+	sort.SliceStable(announced, func(i, j int) bool {
+		return announced[i].FirstSeen > announced[j].FirstSeen
+	})
+
+	announced, _ = f.paginate(announced)
+
+	return announced, nil
+}
+
+func (app *application) newEventsRSSHandler(w http.ResponseWriter, r *http.Request) {
+	v := newValidator()
+	filters := app.readEventFilters(r.URL.Query(), v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	edmEvents, err := app.newlyAnnouncedEdmEvents(filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	feed := rssFeed{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:       newEventsFeedTitle,
+			Link:        baseURL(r) + "/v1/events",
+			Description: "EDM events in Las Vegas, in the order they were first scraped.",
+			AtomLink:    atomLink{Href: baseURL(r) + r.URL.RequestURI(), Rel: "self", Type: "application/rss+xml"},
+			Items:       []rssItem{},
+		},
+	}
+
+	for i, edmEvent := range edmEvents {
+		firstSeen, err := time.Parse(time.RFC3339, edmEvent.FirstSeen)
+		if err != nil {
+			continue
+		}
+		if i == 0 {
+			feed.Channel.LastBuildDate = firstSeen.Format(time.RFC1123Z)
+		}
+
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       feedEntryTitle(edmEvent),
+			Link:        edmEvent.TicketUrl,
+			Description: feedEntrySummary(edmEvent),
+			GUID:        rssGUID{IsPermaLink: "false", Value: edmEvent.Id},
+			PubDate:     firstSeen.Format(time.RFC1123Z),
+		})
+	}
+
+	app.writeXML(w, r, "application/rss+xml; charset=utf-8", feed)
+}
+
+func (app *application) newEventsAtomHandler(w http.ResponseWriter, r *http.Request) {
+	v := newValidator()
+	filters := app.readEventFilters(r.URL.Query(), v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	edmEvents, err := app.newlyAnnouncedEdmEvents(filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	selfURL := baseURL(r) + r.URL.RequestURI()
+	feed := atomFeed{
+		ID:      selfURL,
+		Title:   newEventsFeedTitle,
+		Updated: time.Unix(0, 0).UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: selfURL, Rel: "self", Type: "application/atom+xml"},
+			{Href: baseURL(r) + "/v1/events", Rel: "alternate", Type: "application/json"},
+		},
+		Entries: []atomEntry{},
+	}
+
+	// The feed was last updated when its most recently updated entry was, which isn't
+	// necessarily the most recently announced one.
+	for i, edmEvent := range edmEvents {
+		updated := edmEvent.LastModified
+		if updated == "" {
+			updated = edmEvent.FirstSeen
+		}
+		if i == 0 || updated > feed.Updated {
+			feed.Updated = updated
+		}
+
+		entry := atomEntry{
+			ID:        "urn:uuid:" + edmEvent.Id,
+			Title:     feedEntryTitle(edmEvent),
+			Updated:   updated,
+			Published: edmEvent.FirstSeen,
+			Summary:   feedEntrySummary(edmEvent),
+		}
+		if edmEvent.TicketUrl != "" {
+			entry.Links = []atomLink{{Href: edmEvent.TicketUrl, Rel: "alternate", Type: "text/html"}}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	app.writeXML(w, r, "application/atom+xml; charset=utf-8", feed)
+}
+
+func feedEntryTitle(edmEvent EdmEvent) string {
+	return edmEvent.ArtistName + " @ " + edmEvent.ClubName
+}
+
+func feedEntrySummary(edmEvent EdmEvent) string {
+	summary := edmEvent.ArtistName + " plays " + edmEvent.ClubName + " in Las Vegas"
+	if len(edmEvent.EventDate) >= len(queryDateFormat) {
+		summary += " on " + edmEvent.EventDate[:len(queryDateFormat)]
+	}
+	return summary + "."
+}
+
+// writeXML encodes the data as an XML document with the given content type.
+func (app *application) writeXML(w http.ResponseWriter, r *http.Request, contentType string, data any) {
+	xmlDoc, err := xml.MarshalIndent(data, "", "  ")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(xml.Header))
+	w.Write(xmlDoc)
+	w.Write([]byte("\n"))
+}
+
+// baseURL returns the scheme and host the client used to reach us, honouring the
+// X-Forwarded-Proto header set by the Cloud Run front end.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = strings.ToLower(strings.Split(proto, ",")[0])
+	}
+	return scheme + "://" + r.Host
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+var newEventsFeedTestEvents = []EdmEvent{
+	{Id: "11111111-1111-1111-1111-111111111111", ClubName: "xs nightclub", ArtistName: "tiësto", EventDate: "2026-11-20T00:00:00Z", TicketUrl: "https://www.wynnsocial.com/events/20261120", FirstSeen: "2026-10-01T08:00:00Z"},
+	{Id: "22222222-2222-2222-2222-222222222222", ClubName: "omnia", ArtistName: "martin garrix", EventDate: "2026-12-31T00:00:00Z", TicketUrl: "https://taogroup.com/event/martin-garrix", FirstSeen: "2026-10-15T08:00:00Z"},
+	{Id: "33333333-3333-3333-3333-333333333333", ClubName: "zouk nightclub", ArtistName: "alesso", EventDate: "2026-11-01T00:00:00Z", FirstSeen: "2026-10-10T08:00:00Z"},
+	{Id: "44444444-4444-4444-4444-444444444444", ClubName: "zouk nightclub", ArtistName: "scraped before first-seen existed", EventDate: "2026-11-02T00:00:00Z"},
+}
+
+// TestNewEventsRSSHandler tests that the RSS feed lists events by when they were first seen
+func TestNewEventsRSSHandler(t *testing.T) {
+	tests := []struct {
+		name          string
+		path          string
+		expectedGUIDs []string
+	}{
+		{
+			name:          "Most recently announced first",
+			path:          "/v1/feeds/new.rss",
+			expectedGUIDs: []string{"22222222-2222-2222-2222-222222222222", "33333333-3333-3333-3333-333333333333", "11111111-1111-1111-1111-111111111111"},
+		},
+		{
+			name:          "Filtered by venue",
+			path:          "/v1/feeds/new.rss?venue=zouk",
+			expectedGUIDs: []string{"33333333-3333-3333-3333-333333333333"},
+		},
+		{
+			name:          "Filtered by artist",
+			path:          "/v1/feeds/new.rss?artist=tiësto",
+			expectedGUIDs: []string{"11111111-1111-1111-1111-111111111111"},
+		},
+		{
+			name:          "Limited by page size",
+			path:          "/v1/feeds/new.rss?page_size=1",
+			expectedGUIDs: []string{"22222222-2222-2222-2222-222222222222"},
+		},
+		{
+			name:          "Second page",
+			path:          "/v1/feeds/new.rss?page_size=1&page=2",
+			expectedGUIDs: []string{"33333333-3333-3333-3333-333333333333"},
+		},
+		{
+			name:          "Page past the end",
+			path:          "/v1/feeds/new.rss?page_size=2&page=3",
+			expectedGUIDs: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, newEventsFeedTestEvents)
+			rr := get(t, app, tt.path)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+			}
+			if contentType := rr.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "application/rss+xml") {
+				t.Errorf("Expected RSS content type, got '%s'", contentType)
+			}
+
+			var feed rssFeed
+			if err := xml.Unmarshal(rr.Body.Bytes(), &feed); err != nil {
+				t.Fatalf("Expected valid XML, got %v", err)
+			}
+
+			if len(feed.Channel.Items) != len(tt.expectedGUIDs) {
+				t.Fatalf("Expected %d items, got %d", len(tt.expectedGUIDs), len(feed.Channel.Items))
+			}
+			for i, guid := range tt.expectedGUIDs {
+				if feed.Channel.Items[i].GUID.Value != guid {
+					t.Errorf("Expected item %d to be '%s', got '%s'", i, guid, feed.Channel.Items[i].GUID.Value)
+				}
+			}
+		})
+	}
+}
+
+// TestNewEventsAtomHandler tests the Atom version of the newly announced events feed
+func TestNewEventsAtomHandler(t *testing.T) {
+	app := newTestApplication(t, newEventsFeedTestEvents)
+	rr := get(t, app, "/v1/feeds/new.atom?venue=omnia")
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	if contentType := rr.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "application/atom+xml") {
+		t.Errorf("Expected Atom content type, got '%s'", contentType)
+	}
+
+	var feed atomFeed
+	if err := xml.Unmarshal(rr.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("Expected valid XML, got %v", err)
+	}
+
+	if feed.Updated != "2026-10-15T08:00:00Z" {
+		t.Errorf("Expected feed updated '2026-10-15T08:00:00Z', got '%s'", feed.Updated)
+	}
+	if len(feed.Entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(feed.Entries))
+	}
+
+	entry := feed.Entries[0]
+	if entry.ID != "urn:uuid:22222222-2222-2222-2222-222222222222" {
+		t.Errorf("Expected entry id 'urn:uuid:22222222-2222-2222-2222-222222222222', got '%s'", entry.ID)
+	}
+	if entry.Published != "2026-10-15T08:00:00Z" {
+		t.Errorf("Expected published '2026-10-15T08:00:00Z', got '%s'", entry.Published)
+	}
+	if len(entry.Links) != 1 || entry.Links[0].Href != "https://taogroup.com/event/martin-garrix" {
+		t.Errorf("Expected a link to the ticket url, got %v", entry.Links)
+	}
+}
+
+// TestNewEventsAtomHandler_Updated tests that the feed's updated timestamp is the newest of its
+// entries', even when that entry isn't the most recently announced
+func TestNewEventsAtomHandler_Updated(t *testing.T) {
+	tests := []struct {
+		name            string
+		edmEvents       []EdmEvent
+		expectedUpdated string
+	}{
+		{
+			name: "Most recently announced entry",
+			edmEvents: []EdmEvent{
+				{Id: "e1", ClubName: "omnia", ArtistName: "martin garrix", FirstSeen: "2026-10-15T08:00:00Z"},
+				{Id: "e2", ClubName: "xs nightclub", ArtistName: "tiësto", FirstSeen: "2026-10-01T08:00:00Z", LastModified: "2026-10-10T08:00:00Z"},
+			},
+			expectedUpdated: "2026-10-15T08:00:00Z",
+		},
+		{
+			name: "Older entry updated since",
+			edmEvents: []EdmEvent{
+				{Id: "e1", ClubName: "omnia", ArtistName: "martin garrix", FirstSeen: "2026-10-15T08:00:00Z"},
+				{Id: "e2", ClubName: "xs nightclub", ArtistName: "tiësto", FirstSeen: "2026-10-01T08:00:00Z", LastModified: "2026-10-18T08:00:00Z"},
+			},
+			expectedUpdated: "2026-10-18T08:00:00Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, tt.edmEvents)
+			rr := get(t, app, "/v1/feeds/new.atom")
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+			}
+
+			var feed atomFeed
+			if err := xml.Unmarshal(rr.Body.Bytes(), &feed); err != nil {
+				t.Fatalf("Expected valid XML, got %v", err)
+			}
+			if feed.Updated != tt.expectedUpdated {
+				t.Errorf("Expected feed updated '%s', got '%s'", tt.expectedUpdated, feed.Updated)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const monthFormat = "2006-01"
+
+// calendarNight is one night of the month, with its events grouped by venue.
+type calendarNight struct {
+	Date       string          `json:"date"`
+	EventCount int             `json:"event_count"`
+	Venues     []calendarVenue `json:"venues"`
+}
+
+type calendarVenue struct {
+	Venue      string     `json:"venue"`
+	EventCount int        `json:"event_count"`
+	Events     []EdmEvent `json:"events"`
+}
+
+// nightCalendarHandler returns every night of a month with its events grouped by venue. The
+// month defaults to the current one in Las Vegas, artist and venue filter the events as they
+// do on /v1/events.
+func (app *application) nightCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	v := newValidator()
+
+	month := app.readString(qs, "month", lasVegasToday(time.Now())[:len(monthFormat)])
+	first, err := time.Parse(monthFormat, month)
+	v.Check(err == nil, "month", "must be a month in the format YYYY-MM")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// The last night of the month goes on past midnight into the next one.
+	last := first.AddDate(0, 1, -1)
+	filters := EventFilters{
+		Artist: strings.ToLower(strings.TrimSpace(app.readString(qs, "artist", ""))),
+		Venue:  strings.ToLower(strings.TrimSpace(app.readString(qs, "venue", ""))),
+		From:   first.Format(queryDateFormat),
+		To:     last.AddDate(0, 0, 1).Format(queryDateFormat),
+	}
+
+	edmEvents, err := app.queryEdmEvents(filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	nights := nightsOfMonth(first, groupByNight(edmEvents))
+
+	totalEvents := 0
+	for _, n := range nights {
+		totalEvents += n.EventCount
+	}
+
+	env := envelope{
+		"month":       first.Format(monthFormat),
+		"event_count": totalEvents,
+		"nights":      nights,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// nightsOfMonth lays the grouped nights out over every day of the month starting on first,
+// nights without events included, so that clients can draw the month grid as is.
+func nightsOfMonth(first time.Time, nights []night) []calendarNight {
+	byDate := make(map[string]night, len(nights))
+	for _, n := range nights {
+		byDate[n.Date.Format(queryDateFormat)] = n
+	}
+
+	calendarNights := []calendarNight{}
+	for day := first; day.Month() == first.Month(); day = day.AddDate(0, 0, 1) {
+		date := day.Format(queryDateFormat)
+		n := byDate[date]
+
+		calendarNights = append(calendarNights, calendarNight{
+			Date:       date,
+			EventCount: len(n.Events),
+			Venues:     groupByVenue(n.Events),
+		})
+	}
+
+	return calendarNights
+}
+
+// groupByVenue groups the events of a night by venue, venues sorted by name and their events
+// keeping the order they came in.
+func groupByVenue(edmEvents []EdmEvent) []calendarVenue {
+	venues := []calendarVenue{}
+	venueIndex := make(map[string]int)
+
+	for _, edmEvent := range edmEvents {
+		i, ok := venueIndex[edmEvent.ClubName]
+		if !ok {
+			i = len(venues)
+			venueIndex[edmEvent.ClubName] = i
+			venues = append(venues, calendarVenue{Venue: edmEvent.ClubName})
+		}
+		venues[i].EventCount++
+		venues[i].Events = append(venues[i].Events, edmEvent)
+	}
+
+	sort.Slice(venues, func(i, j int) bool {
+		return venues[i].Venue < venues[j].Venue
+	})
+
+	return venues
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// TestEventNight tests which night an event belongs to
+func TestEventNight(t *testing.T) {
+	tests := []struct {
+		eventDate     string
+		expectedNight string
+	}{
+		{eventDate: "2026-11-20T00:00:00Z", expectedNight: "2026-11-20"},
+		{eventDate: "2026-11-20T22:30:00Z", expectedNight: "2026-11-20"},
+		{eventDate: "2026-11-21T00:30:00Z", expectedNight: "2026-11-20"},
+		{eventDate: "2026-11-21T05:59:00Z", expectedNight: "2026-11-20"},
+		{eventDate: "2026-11-21T06:00:00Z", expectedNight: "2026-11-21"},
+		{eventDate: "2026-12-01T02:00:00Z", expectedNight: "2026-11-30"},
+		{eventDate: "2026-11-20", expectedNight: "2026-11-20"},
+		{eventDate: "soon", expectedNight: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.eventDate, func(t *testing.T) {
+			if got := eventNight(EdmEvent{EventDate: tt.eventDate}); got != tt.expectedNight {
+				t.Errorf("Expected night '%s', got '%s'", tt.expectedNight, got)
+			}
+		})
+	}
+}
+
+var nightCalendarTestEvents = []EdmEvent{
+	{Id: "oct-31-late", ClubName: "omnia", ArtistName: "kaskade", EventDate: "2026-11-01T01:00:00Z"},
+	{Id: "nov-20-xs", ClubName: "xs nightclub", ArtistName: "tiësto", EventDate: "2026-11-20T00:00:00Z"},
+	{Id: "nov-20-omnia", ClubName: "omnia", ArtistName: "martin garrix", EventDate: "2026-11-20T00:00:00Z"},
+	{Id: "nov-20-omnia-late", ClubName: "omnia", ArtistName: "alesso", EventDate: "2026-11-21T02:00:00Z"},
+	{Id: "nov-21", ClubName: "zouk nightclub", ArtistName: "zedd", EventDate: "2026-11-21T00:00:00Z"},
+	{Id: "nov-30-late", ClubName: "marquee", ArtistName: "diplo", EventDate: "2026-12-01T03:00:00Z"},
+	{Id: "dec-01", ClubName: "marquee", ArtistName: "diplo", EventDate: "2026-12-01T00:00:00Z"},
+}
+
+type nightCalendarResponse struct {
+	Month      string          `json:"month"`
+	EventCount int             `json:"event_count"`
+	Nights     []calendarNight `json:"nights"`
+}
+
+// TestNightCalendarHandler tests the nights of a month grouped by venue
+func TestNightCalendarHandler(t *testing.T) {
+	app := newTestApplication(t, nightCalendarTestEvents)
+
+	rr := get(t, app, "/v1/calendar?month=2026-11")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var response nightCalendarResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Month != "2026-11" || len(response.Nights) != 30 {
+		t.Fatalf("Expected the 30 nights of 2026-11, got %d nights of %s", len(response.Nights), response.Month)
+	}
+	if response.EventCount != 5 {
+		t.Errorf("Expected 5 events in the month, got %d", response.EventCount)
+	}
+
+	first := response.Nights[0]
+	if first.Date != "2026-11-01" || first.EventCount != 0 || len(first.Venues) != 0 {
+		t.Errorf("Expected the 1st to be empty, the 1am show belongs to October, got %+v", first)
+	}
+
+	friday := response.Nights[19]
+	if friday.Date != "2026-11-20" || friday.EventCount != 3 || len(friday.Venues) != 2 {
+		t.Fatalf("Expected 3 events at 2 venues on the 20th, got %+v", friday)
+	}
+	if friday.Venues[0].Venue != "omnia" || friday.Venues[0].EventCount != 2 {
+		t.Errorf("Expected omnia first with 2 events, got %+v", friday.Venues[0])
+	}
+	if friday.Venues[1].Venue != "xs nightclub" || friday.Venues[1].EventCount != 1 {
+		t.Errorf("Expected xs nightclub second with 1 event, got %+v", friday.Venues[1])
+	}
+
+	last := response.Nights[29]
+	if last.Date != "2026-11-30" || last.EventCount != 1 || last.Venues[0].Events[0].Id != "nov-30-late" {
+		t.Errorf("Expected the 3am show of December 1st on the 30th, got %+v", last)
+	}
+}
+
+// TestNightCalendarHandler_Filters tests the filters and validation
+func TestNightCalendarHandler_Filters(t *testing.T) {
+	tests := []struct {
+		name               string
+		path               string
+		expectedStatusCode int
+		expectedEventCount int
+	}{
+		{name: "Venue", path: "/v1/calendar?month=2026-11&venue=omnia", expectedStatusCode: http.StatusOK, expectedEventCount: 2},
+		{name: "Artist", path: "/v1/calendar?month=2026-12&artist=diplo", expectedStatusCode: http.StatusOK, expectedEventCount: 1},
+		{name: "Invalid month", path: "/v1/calendar?month=2026-13", expectedStatusCode: http.StatusUnprocessableEntity},
+		{name: "Date instead of month", path: "/v1/calendar?month=2026-11-20", expectedStatusCode: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, nightCalendarTestEvents)
+
+			rr := get(t, app, tt.path)
+			if rr.Code != tt.expectedStatusCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatusCode, rr.Code)
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+
+			var response nightCalendarResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if response.EventCount != tt.expectedEventCount {
+				t.Errorf("Expected %d events, got %d", tt.expectedEventCount, response.EventCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpecification describes every endpoint of the serve command. The tests in
+// openapi_test.go validate the real handler responses against it, so a handler change that
+// isn't reflected here fails the build.
+//
+//go:embed openapi.json
+var openAPISpecification []byte
+
+func (app *application) openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(openAPISpecification)
+}
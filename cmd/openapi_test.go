@@ -0,0 +1,458 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// openAPIDocument is the parsed specification, kept as generic JSON so the validator below
+// can walk any schema in it.
+type openAPIDocument map[string]any
+
+func loadOpenAPIDocument(t *testing.T) openAPIDocument {
+	t.Helper()
+
+	var doc openAPIDocument
+	if err := json.Unmarshal(openAPISpecification, &doc); err != nil {
+		t.Fatalf("Expected openapi.json to be valid JSON, got %v", err)
+	}
+	return doc
+}
+
+// resolve follows a local "#/components/..." reference, returning the object unchanged when
+// it isn't a reference.
+func (doc openAPIDocument) resolve(node map[string]any) map[string]any {
+	ref, ok := node["$ref"].(string)
+	if !ok {
+		return node
+	}
+
+	var current any = map[string]any(doc)
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		current = current.(map[string]any)[part]
+	}
+	return doc.resolve(current.(map[string]any))
+}
+
+// validate checks a decoded JSON value against the subset of JSON Schema used in
+// openapi.json: type, nullable, format, required, properties, additionalProperties and items.
+func (doc openAPIDocument) validate(schema map[string]any, value any, path string) error {
+	schema = doc.resolve(schema)
+
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); nullable {
+			return nil
+		}
+		return fmt.Errorf("%s: must not be null", path)
+	}
+
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected object, got %T", path, value)
+		}
+		if required, ok := schema["required"].([]any); ok {
+			for _, name := range required {
+				if _, ok := object[name.(string)]; !ok {
+					return fmt.Errorf("%s: missing required property %q", path, name)
+				}
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		for name, propertyValue := range object {
+			if propertySchema, ok := properties[name].(map[string]any); ok {
+				if err := doc.validate(propertySchema, propertyValue, path+"."+name); err != nil {
+					return err
+				}
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					return fmt.Errorf("%s: property %q is not in the specification", path, name)
+				}
+			case map[string]any:
+				if err := doc.validate(additional, propertyValue, path+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected array, got %T", path, value)
+		}
+		items, _ := schema["items"].(map[string]any)
+		for i, item := range array {
+			if err := doc.validate(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected string, got %T", path, value)
+		}
+		switch schema["format"] {
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339, s); err != nil {
+				return fmt.Errorf("%s: expected date-time, got %q", path, s)
+			}
+		case "date":
+			if _, err := time.Parse(queryDateFormat, s); err != nil {
+				return fmt.Errorf("%s: expected date, got %q", path, s)
+			}
+		}
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != float64(int64(n)) {
+			return fmt.Errorf("%s: expected integer, got %v", path, value)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s: expected number, got %T", path, value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: expected boolean, got %T", path, value)
+		}
+	}
+
+	return nil
+}
+
+// validateResponse checks the recorded response against the operation's documented
+// responses: the status code, the content type and, for JSON, the body.
+func (doc openAPIDocument) validateResponse(specPath string, method string, rr *httptest.ResponseRecorder) error {
+	pathItem, ok := doc["paths"].(map[string]any)[specPath].(map[string]any)
+	if !ok {
+		return fmt.Errorf("path %s is not documented", specPath)
+	}
+	operation, ok := pathItem[strings.ToLower(method)].(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s %s is not documented", method, specPath)
+	}
+
+	responses := operation["responses"].(map[string]any)
+	response, ok := responses[fmt.Sprint(rr.Code)].(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s %s: status %d is not documented", method, specPath, rr.Code)
+	}
+	response = doc.resolve(response)
+
+	mediaType, _, err := mime.ParseMediaType(rr.Header().Get("Content-Type"))
+	if err != nil {
+		return fmt.Errorf("%s %s: invalid Content-Type %q", method, specPath, rr.Header().Get("Content-Type"))
+	}
+	content, ok := response["content"].(map[string]any)[mediaType].(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s %s: content type %s is not documented for status %d", method, specPath, mediaType, rr.Code)
+	}
+
+	if mediaType != "application/json" {
+		return nil
+	}
+
+	var body any
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		return fmt.Errorf("%s %s: body is not JSON: %v", method, specPath, err)
+	}
+	return doc.validate(content["schema"].(map[string]any), body, "body")
+}
+
+var openAPITestEvents = []EdmEvent{
+	{Id: "id-1", ClubName: "xs nightclub", ArtistName: "tiësto", EventDate: "2026-11-20T00:00:00Z", TicketUrl: "https://www.wynnsocial.com/events/20261120", ArtistImageUrl: "https://www.wynnsocial.com/tiesto.jpg", Availability: availabilityLimited, Sequence: 2, LastModified: "2026-10-01T08:00:00Z", FirstSeen: "2026-09-01T08:00:00Z"},
+	{Id: "id-2", ClubName: "omnia", ArtistName: "martin garrix", EventDate: "2026-11-21T00:00:00Z"},
+}
+
+var openAPITestSyncs = []SourceSync{
+	{Source: sourceWynn, LastSuccessfulSync: "2026-10-18T06:00:00Z", EventCount: 1, Venues: []string{"xs nightclub"}, LastAttempt: "2026-10-19T06:00:00Z", LastError: "error scraping https://www.wynnsocial.com/events/: Not Found", LastErrorAt: "2026-10-19T06:00:00Z"},
+	{Source: sourceTaoGroupHospitality, LastSuccessfulSync: "2026-10-19T06:00:00Z", EventCount: 1, Venues: []string{"omnia"}, LastAttempt: "2026-10-19T06:00:00Z"},
+}
+
+// TestOpenAPI_HandlerResponses tests real handler responses against openapi.json
+func TestOpenAPI_HandlerResponses(t *testing.T) {
+	doc := loadOpenAPIDocument(t)
+
+	tests := []struct {
+		name     string
+		specPath string
+		method   string
+		path     string
+		body     string
+		admin    bool
+		noPush   bool
+	}{
+		{name: "Events", specPath: "/v1/events", method: http.MethodGet, path: "/v1/events"},
+		{name: "Events page past the end", specPath: "/v1/events", method: http.MethodGet, path: "/v1/events?page=9"},
+		{name: "Events with no match", specPath: "/v1/events", method: http.MethodGet, path: "/v1/events?artist=nobody"},
+		{name: "Events failed validation", specPath: "/v1/events", method: http.MethodGet, path: "/v1/events?page_size=0"},
+		{name: "Events as CSV", specPath: "/v1/events", method: http.MethodGet, path: "/v1/events?format=csv"},
+		{name: "Events as NDJSON", specPath: "/v1/events", method: http.MethodGet, path: "/v1/events?format=ndjson"},
+		{name: "Events as calendar", specPath: "/v1/events", method: http.MethodGet, path: "/v1/events?format=ics"},
+		{name: "Events in an unknown format", specPath: "/v1/events", method: http.MethodGet, path: "/v1/events?format=xml"},
+		{name: "Search", specPath: "/v1/search", method: http.MethodGet, path: "/v1/search?q=tiesto"},
+		{name: "Search failed validation", specPath: "/v1/search", method: http.MethodGet, path: "/v1/search"},
+		{name: "Stream failed validation", specPath: "/v1/stream", method: http.MethodGet, path: "/v1/stream?types=event.moved"},
+		{name: "Night calendar", specPath: "/v1/calendar", method: http.MethodGet, path: "/v1/calendar?month=2026-11"},
+		{name: "Night calendar failed validation", specPath: "/v1/calendar", method: http.MethodGet, path: "/v1/calendar?month=november"},
+		{name: "Calendar", specPath: "/v1/calendar.ics", method: http.MethodGet, path: "/v1/calendar.ics"},
+		{name: "Calendar failed validation", specPath: "/v1/calendar.ics", method: http.MethodGet, path: "/v1/calendar.ics?to=soon"},
+		{name: "Artist calendar", specPath: "/v1/artists/{artist}/calendar.ics", method: http.MethodGet, path: "/v1/artists/tiesto/calendar.ics"},
+		{name: "Venue calendar", specPath: "/v1/venues/{venue}/calendar.ics", method: http.MethodGet, path: "/v1/venues/omnia/calendar.ics"},
+		{name: "RSS", specPath: "/v1/feeds/new.rss", method: http.MethodGet, path: "/v1/feeds/new.rss"},
+		{name: "Atom", specPath: "/v1/feeds/new.atom", method: http.MethodGet, path: "/v1/feeds/new.atom"},
+		{name: "Digest", specPath: "/v1/digest", method: http.MethodGet, path: "/v1/digest"},
+		{name: "Digest as Markdown", specPath: "/v1/digest", method: http.MethodGet, path: "/v1/digest?format=markdown"},
+		{name: "Digest as HTML", specPath: "/v1/digest", method: http.MethodGet, path: "/v1/digest?format=html"},
+		{name: "Digest failed validation", specPath: "/v1/digest", method: http.MethodGet, path: "/v1/digest?format=pdf"},
+		{name: "GraphQL GET", specPath: "/graphql", method: http.MethodGet, path: "/graphql?query=%7B%20venues%20%7B%20name%20%7D%20%7D"},
+		{name: "GraphQL GET bad variables", specPath: "/graphql", method: http.MethodGet, path: "/graphql?query=%7B%7D&variables=x"},
+		{name: "GraphQL POST", specPath: "/graphql", method: http.MethodPost, path: "/graphql", body: `{"query": "{ events { events { id } } }"}`},
+		{name: "GraphQL POST with errors", specPath: "/graphql", method: http.MethodPost, path: "/graphql", body: `{"query": "{ nothing }"}`},
+		{name: "GraphQL POST bad body", specPath: "/graphql", method: http.MethodPost, path: "/graphql", body: `{"query": 1}`},
+		{name: "OpenAPI", specPath: "/v1/openapi.json", method: http.MethodGet, path: "/v1/openapi.json"},
+		{name: "Status", specPath: "/v1/status", method: http.MethodGet, path: "/v1/status"},
+		{name: "Healthz", specPath: "/healthz", method: http.MethodGet, path: "/healthz"},
+		{name: "Readyz", specPath: "/readyz", method: http.MethodGet, path: "/readyz"},
+		{name: "List runs", specPath: "/v1/admin/runs", method: http.MethodGet, path: "/v1/admin/runs", admin: true},
+		{name: "List runs without a key", specPath: "/v1/admin/runs", method: http.MethodGet, path: "/v1/admin/runs"},
+		{name: "List runs with a limit", specPath: "/v1/admin/runs", method: http.MethodGet, path: "/v1/admin/runs?limit=5", admin: true},
+		{name: "List runs failed validation", specPath: "/v1/admin/runs", method: http.MethodGet, path: "/v1/admin/runs?limit=0", admin: true},
+		{name: "Create run", specPath: "/v1/admin/runs", method: http.MethodPost, path: "/v1/admin/runs", body: `{"sources": ["liv"]}`, admin: true},
+		{name: "Create run failed validation", specPath: "/v1/admin/runs", method: http.MethodPost, path: "/v1/admin/runs", body: `{"sources": ["xs"]}`, admin: true},
+		{name: "Show run", specPath: "/v1/admin/runs/{id}", method: http.MethodGet, path: "/v1/admin/runs/{id}", admin: true},
+		{name: "Show unknown run", specPath: "/v1/admin/runs/{id}", method: http.MethodGet, path: "/v1/admin/runs/unknown", admin: true},
+		{name: "List watchlists", specPath: "/v1/watchlists", method: http.MethodGet, path: "/v1/watchlists", admin: true},
+		{name: "List watchlists without a key", specPath: "/v1/watchlists", method: http.MethodGet, path: "/v1/watchlists"},
+		{name: "Create watchlist", specPath: "/v1/watchlists", method: http.MethodPost, path: "/v1/watchlists", body: `{"name": "Favourites", "artists": ["Tiësto"]}`, admin: true},
+		{name: "Create watchlist failed validation", specPath: "/v1/watchlists", method: http.MethodPost, path: "/v1/watchlists", body: `{"name": "", "artists": []}`, admin: true},
+		{name: "Create watchlist bad body", specPath: "/v1/watchlists", method: http.MethodPost, path: "/v1/watchlists", body: `{"name": 1}`, admin: true},
+		{name: "Show watchlist", specPath: "/v1/watchlists/{id}", method: http.MethodGet, path: "/v1/watchlists/{watchlist}", admin: true},
+		{name: "Show unknown watchlist", specPath: "/v1/watchlists/{id}", method: http.MethodGet, path: "/v1/watchlists/unknown", admin: true},
+		{name: "Update watchlist", specPath: "/v1/watchlists/{id}", method: http.MethodPatch, path: "/v1/watchlists/{watchlist}", body: `{"artists": ["Kygo"]}`, admin: true},
+		{name: "Update watchlist failed validation", specPath: "/v1/watchlists/{id}", method: http.MethodPatch, path: "/v1/watchlists/{watchlist}", body: `{"name": ""}`, admin: true},
+		{name: "Delete watchlist", specPath: "/v1/watchlists/{id}", method: http.MethodDelete, path: "/v1/watchlists/{watchlist}", admin: true},
+		{name: "Delete unknown watchlist", specPath: "/v1/watchlists/{id}", method: http.MethodDelete, path: "/v1/watchlists/unknown", admin: true},
+		{name: "Alerts", specPath: "/v1/alerts", method: http.MethodGet, path: "/v1/alerts", admin: true},
+		{name: "Alerts failed validation", specPath: "/v1/alerts", method: http.MethodGet, path: "/v1/alerts?page_size=0", admin: true},
+		{name: "Sold out alerts", specPath: "/v1/alerts", method: http.MethodGet, path: "/v1/alerts?type=sold_out", admin: true},
+		{name: "List webhooks", specPath: "/v1/webhooks", method: http.MethodGet, path: "/v1/webhooks", admin: true},
+		{name: "Create webhook", specPath: "/v1/webhooks", method: http.MethodPost, path: "/v1/webhooks", body: `{"url": "https://partner.example.com/hooks", "events": ["cancelled"]}`, admin: true},
+		{name: "Create webhook failed validation", specPath: "/v1/webhooks", method: http.MethodPost, path: "/v1/webhooks", body: `{"url": "partner"}`, admin: true},
+		{name: "Create webhook bad body", specPath: "/v1/webhooks", method: http.MethodPost, path: "/v1/webhooks", body: `{"url": 1}`, admin: true},
+		{name: "Show webhook", specPath: "/v1/webhooks/{id}", method: http.MethodGet, path: "/v1/webhooks/{webhook}", admin: true},
+		{name: "Show unknown webhook", specPath: "/v1/webhooks/{id}", method: http.MethodGet, path: "/v1/webhooks/unknown", admin: true},
+		{name: "Delete webhook", specPath: "/v1/webhooks/{id}", method: http.MethodDelete, path: "/v1/webhooks/{webhook}", admin: true},
+		{name: "Delete unknown webhook", specPath: "/v1/webhooks/{id}", method: http.MethodDelete, path: "/v1/webhooks/unknown", admin: true},
+		{name: "Webhook deliveries", specPath: "/v1/webhooks/{id}/deliveries", method: http.MethodGet, path: "/v1/webhooks/{webhook}/deliveries", admin: true},
+		{name: "Webhook dead letters", specPath: "/v1/webhooks/{id}/deliveries", method: http.MethodGet, path: "/v1/webhooks/{webhook}/deliveries?status=failed", admin: true},
+		{name: "Webhook deliveries failed validation", specPath: "/v1/webhooks/{id}/deliveries", method: http.MethodGet, path: "/v1/webhooks/{webhook}/deliveries?status=lost", admin: true},
+		{name: "Replay delivery", specPath: "/v1/webhooks/{id}/deliveries/{delivery}/replay", method: http.MethodPost, path: "/v1/webhooks/{webhook}/deliveries/d1/replay", admin: true},
+		{name: "Replay delivered delivery", specPath: "/v1/webhooks/{id}/deliveries/{delivery}/replay", method: http.MethodPost, path: "/v1/webhooks/{webhook}/deliveries/d2/replay", admin: true},
+		{name: "Replay unknown delivery", specPath: "/v1/webhooks/{id}/deliveries/{delivery}/replay", method: http.MethodPost, path: "/v1/webhooks/{webhook}/deliveries/unknown/replay", admin: true},
+		{name: "VAPID public key", specPath: "/v1/push/vapid-public-key", method: http.MethodGet, path: "/v1/push/vapid-public-key"},
+		{name: "VAPID public key without push", specPath: "/v1/push/vapid-public-key", method: http.MethodGet, path: "/v1/push/vapid-public-key", noPush: true},
+		{name: "List push subscriptions", specPath: "/v1/push/subscriptions", method: http.MethodGet, path: "/v1/push/subscriptions", admin: true},
+		{name: "Create push subscription", specPath: "/v1/push/subscriptions", method: http.MethodPost, path: "/v1/push/subscriptions", body: `{"endpoint": "https://fcm.googleapis.com/fcm/send/new", "expirationTime": null, "keys": {"p256dh": "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4", "auth": "BTBZMqHH6r4Tts7J_aSIgg"}}`, admin: true},
+		{name: "Create existing push subscription", specPath: "/v1/push/subscriptions", method: http.MethodPost, path: "/v1/push/subscriptions", body: `{"endpoint": "https://fcm.googleapis.com/fcm/send/abc", "expirationTime": null, "keys": {"p256dh": "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4", "auth": "BTBZMqHH6r4Tts7J_aSIgg"}}`, admin: true},
+		{name: "Create push subscription failed validation", specPath: "/v1/push/subscriptions", method: http.MethodPost, path: "/v1/push/subscriptions", body: `{"endpoint": "https://example.com/push"}`, admin: true},
+		{name: "Create push subscription bad body", specPath: "/v1/push/subscriptions", method: http.MethodPost, path: "/v1/push/subscriptions", body: `{"endpoint": 1}`, admin: true},
+		{name: "Create push subscription without push", specPath: "/v1/push/subscriptions", method: http.MethodPost, path: "/v1/push/subscriptions", body: `{"endpoint": "https://fcm.googleapis.com/fcm/send/abc", "expirationTime": null, "keys": {"p256dh": "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4", "auth": "BTBZMqHH6r4Tts7J_aSIgg"}}`, admin: true, noPush: true},
+		{name: "Delete push subscription", specPath: "/v1/push/subscriptions/{id}", method: http.MethodDelete, path: "/v1/push/subscriptions/{subscription}", admin: true},
+		{name: "Delete unknown push subscription", specPath: "/v1/push/subscriptions/{id}", method: http.MethodDelete, path: "/v1/push/subscriptions/unknown", admin: true},
+	}
+
+	covered := make(map[string]bool)
+
+	for _, tt := range tests {
+		covered[tt.method+" "+tt.specPath] = true
+
+		t.Run(tt.name, func(t *testing.T) {
+			app, adminKey, _ := newAdminRunsTestApplication(t)
+			app.dbSnippets = &mockSnippetModel{edmEvents: openAPITestEvents}
+			app.dbSyncStates = &mockSyncStateModel{sourceSyncs: openAPITestSyncs}
+
+			path := tt.path
+			if strings.Contains(path, "{id}") {
+				run, err := app.runs.start(allSources, runTriggerAdmin, time.Now())
+				if err != nil {
+					t.Fatal(err)
+				}
+				app.runs.finish(run.ID, syncChanges{}, nil, time.Now(), nil)
+				path = strings.Replace(path, "{id}", run.ID, 1)
+			}
+
+			if strings.Contains(path, "{watchlist}") || tt.specPath == "/v1/alerts" {
+				apiKeys, _ := app.dbAPIKeys.GetAll()
+				userID := apiKeys[0].ID
+				app.dbWatchlists = &mockWatchlistModel{watchlists: []Watchlist{
+					{ID: "w1", UserID: userID, Name: "Favourites", Artists: []string{"Tiësto"}, CreatedAt: "2026-10-19T06:00:00Z", UpdatedAt: "2026-10-19T06:00:00Z"},
+				}}
+				app.dbAlerts = &mockAlertModel{alerts: matchWatchlists(app.dbWatchlists.(*mockWatchlistModel).watchlists, openAPITestEvents, alertTypeAnnounced, time.Now())}
+				path = strings.Replace(path, "{watchlist}", "w1", 1)
+			}
+
+			if strings.Contains(path, "{webhook}") {
+				receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+				defer receiver.Close()
+
+				apiKeys, _ := app.dbAPIKeys.GetAll()
+				userID := apiKeys[0].ID
+				app.dbWebhooks = &mockWebhookModel{webhooks: []Webhook{
+					{ID: "h1", UserID: userID, URL: receiver.URL, Secret: "whsec_test", Events: webhookEventTypes, CreatedAt: "2026-10-19T06:00:00Z"},
+				}}
+				payload := `{"id":"d1","created_at":"2026-10-19T06:00:00Z","changes":[]}`
+				app.dbWebhookDeliveries = &mockWebhookDeliveryModel{deliveries: []WebhookDelivery{
+					{ID: "d1", WebhookID: "h1", UserID: userID, Payload: payload, Status: deliveryStatusFailed, Attempts: 5, ResponseStatus: 503, Error: "webhook responded with 503 Service Unavailable", CreatedAt: "2026-10-19T06:00:00Z", UpdatedAt: "2026-10-19T06:00:15Z"},
+					{ID: "d2", WebhookID: "h1", UserID: userID, Payload: payload, Status: deliveryStatusDelivered, Attempts: 1, ResponseStatus: 200, CreatedAt: "2026-10-18T06:00:00Z", UpdatedAt: "2026-10-18T06:00:00Z"},
+				}}
+				path = strings.Replace(path, "{webhook}", "h1", 1)
+			}
+
+			if strings.HasPrefix(tt.specPath, "/v1/push/") && !tt.noPush {
+				app.push = newTestWebPusher(t, defaultPushServices...)
+			}
+
+			if strings.HasPrefix(tt.specPath, "/v1/push/subscriptions") {
+				apiKeys, _ := app.dbAPIKeys.GetAll()
+				app.dbPushSubscriptions = &mockPushSubscriptionModel{subscriptions: []PushSubscription{
+					{ID: "s1", UserID: apiKeys[0].ID, Endpoint: "https://fcm.googleapis.com/fcm/send/abc", CreatedAt: "2026-10-19T06:00:00Z"},
+				}}
+				path = strings.Replace(path, "{subscription}", "s1", 1)
+			}
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(tt.method, path, bytes.NewBufferString(tt.body))
+			if tt.admin {
+				r.Header.Set("Authorization", "Bearer "+adminKey)
+			}
+			app.routes().ServeHTTP(rr, r)
+
+			if rr.Code == http.StatusAccepted {
+				var created struct {
+					Run scrapeRun `json:"run"`
+				}
+				if err := json.Unmarshal(rr.Body.Bytes(), &created); err == nil {
+					waitForRun(t, app, created.Run.ID)
+				}
+			}
+
+			if err := doc.validateResponse(tt.specPath, tt.method, rr); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	// Every documented operation must be exercised above, otherwise it could drift unnoticed.
+	var undocumented []string
+	for specPath, pathItem := range doc["paths"].(map[string]any) {
+		for method := range pathItem.(map[string]any) {
+			operation := strings.ToUpper(method) + " " + specPath
+			if !covered[operation] {
+				undocumented = append(undocumented, operation)
+			}
+		}
+	}
+	sort.Strings(undocumented)
+	for _, operation := range undocumented {
+		t.Errorf("Expected a response validation test for %s", operation)
+	}
+}
+
+// TestOpenAPI_ServerErrorResponse tests the documented responses when storage fails
+func TestOpenAPI_ServerErrorResponse(t *testing.T) {
+	doc := loadOpenAPIDocument(t)
+	app := newTestApplication(t, nil)
+	app.dbSnippets = &mockSnippetModel{err: fmt.Errorf("firestore unavailable")}
+
+	rr := get(t, app, "/v1/events")
+	if err := doc.validateResponse("/v1/events", http.MethodGet, rr); err != nil {
+		t.Error(err)
+	}
+
+	app.dbSyncStates = &mockSyncStateModel{err: fmt.Errorf("firestore unavailable")}
+	for _, path := range []string{"/v1/status", "/readyz"} {
+		rr := get(t, app, path)
+		if err := doc.validateResponse(path, http.MethodGet, rr); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// TestOpenAPI_EdmEventSchema tests that the EdmEvent schema matches the struct's json tags
+func TestOpenAPI_EdmEventSchema(t *testing.T) {
+	doc := loadOpenAPIDocument(t)
+	schema := doc["components"].(map[string]any)["schemas"].(map[string]any)["EdmEvent"].(map[string]any)
+	properties := schema["properties"].(map[string]any)
+
+	required := make(map[string]bool)
+	if list, ok := schema["required"].([]any); ok {
+		for _, name := range list {
+			required[name.(string)] = true
+		}
+	}
+
+	edmEventType := reflect.TypeOf(EdmEvent{})
+	tagged := make(map[string]bool)
+	for i := 0; i < edmEventType.NumField(); i++ {
+		tag := edmEventType.Field(i).Tag.Get("json")
+		name, options, _ := strings.Cut(tag, ",")
+		tagged[name] = true
+
+		if _, ok := properties[name]; !ok {
+			t.Errorf("Expected field %s (%q) to be documented", edmEventType.Field(i).Name, name)
+		}
+		if strings.Contains(options, "omitempty") && required[name] {
+			t.Errorf("Expected omitempty field %q not to be required", name)
+		}
+		if !strings.Contains(options, "omitempty") && !required[name] {
+			t.Errorf("Expected field %q without omitempty to be required", name)
+		}
+	}
+
+	for name := range properties {
+		if !tagged[name] {
+			t.Errorf("Expected documented property %q to exist on EdmEvent", name)
+		}
+	}
+
+	info := doc["info"].(map[string]any)
+	if info["version"] != version {
+		t.Errorf("Expected info.version %q, got %q", version, info["version"])
+	}
+}
+
+// TestOpenAPI_APIKeyResponses tests the documented 401 and 429 response shapes
+func TestOpenAPI_APIKeyResponses(t *testing.T) {
+	doc := loadOpenAPIDocument(t)
+	app, plaintext := newAPIKeyTestApplication(t)
+
+	rr := getWithHeaders(t, app, "/v1/feeds/new.atom", map[string]string{"X-API-Key": "edm_unknown"})
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status %d, got %d", http.StatusUnauthorized, rr.Code)
+	}
+	if err := doc.validateResponse("/v1/feeds/new.atom", http.MethodGet, rr); err != nil {
+		t.Error(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		rr = getWithHeaders(t, app, "/v1/calendar.ics", map[string]string{"X-API-Key": plaintext})
+	}
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status %d, got %d", http.StatusTooManyRequests, rr.Code)
+	}
+	if err := doc.validateResponse("/v1/calendar.ics", http.MethodGet, rr); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The browser pages list this many events per page.
+const pageSizeHTML = 100
+
+func (app *application) homeHandler(w http.ResponseWriter, r *http.Request) {
+	app.renderEventsPage(w, r, "index.html", app.newTemplateData(r), EventFilters{})
+}
+
+func (app *application) artistPageHandler(w http.ResponseWriter, r *http.Request) {
+	artist := strings.ToLower(strings.TrimSpace(r.PathValue("artist")))
+
+	data := app.newTemplateData(r)
+	data.Heading = artist
+
+	app.renderEventsPage(w, r, "artist.html", data, EventFilters{Artist: artist})
+}
+
+func (app *application) venuePageHandler(w http.ResponseWriter, r *http.Request) {
+	venue := strings.ToLower(strings.TrimSpace(r.PathValue("venue")))
+
+	data := app.newTemplateData(r)
+	data.Heading = venue
+
+	app.renderEventsPage(w, r, "venue.html", data, EventFilters{Venue: venue})
+}
+
+func (app *application) searchPageHandler(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	if data.Query == "" {
+		app.render(w, r, http.StatusOK, "search.html", data)
+		return
+	}
+
+	app.renderEventsPage(w, r, "search.html", data, EventFilters{Search: strings.ToLower(data.Query)})
+}
+
+// renderEventsPage runs the filters through the same query layer as /v1/events and renders
+// the page of upcoming events grouped by night. The page and date range come from the query
+// string, without a from date only tonight and later nights are listed.
+func (app *application) renderEventsPage(w http.ResponseWriter, r *http.Request, page string, data templateData, f EventFilters) {
+	qs := r.URL.Query()
+	v := newValidator()
+
+	f.From = app.readString(qs, "from", lasVegasToday(time.Now()))
+	f.To = app.readString(qs, "to", "")
+	f.Page = app.readInt(qs, "page", 1, v)
+	f.PageSize = pageSizeHTML
+
+	validateEventFilters(v, f)
+	if !v.Valid() {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	edmEvents, err := app.queryEdmEvents(f)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	edmEvents, metadata := f.paginate(edmEvents)
+
+	data.Nights = groupByNight(edmEvents)
+	data.Metadata = metadata
+	data.Pagination = newPagination(r.URL, metadata)
+
+	app.render(w, r, http.StatusOK, page, data)
+}
+
+// newPagination links to the previous and next pages by rewriting the page parameter of the
+// current URL, so the other filters are kept.
+func newPagination(u *url.URL, metadata Metadata) *pagination {
+	pageURL := func(page int) string {
+		qs := u.Query()
+		qs.Set("page", strconv.Itoa(page))
+		return u.Path + "?" + qs.Encode()
+	}
+
+	var p pagination
+	if metadata.CurrentPage > metadata.FirstPage {
+		p.PreviousURL = pageURL(metadata.CurrentPage - 1)
+	}
+	if metadata.CurrentPage < metadata.LastPage {
+		p.NextURL = pageURL(metadata.CurrentPage + 1)
+	}
+
+	if p.PreviousURL == "" && p.NextURL == "" {
+		return nil
+	}
+	return &p
+}
+
+// lasVegasToday returns the current date in Las Vegas, which is the date the events are
+// stored with.
+func lasVegasToday(now time.Time) string {
+	location, err := time.LoadLocation(icsTimeZone)
+	if err != nil {
+		location = time.FixedZone("PST", -8*60*60)
+	}
+	return now.In(location).Format(queryDateFormat)
+}
+
+// notFoundHandler answers unknown routes, with JSON under the API prefixes and with the 404
+// page everywhere else.
+func (app *application) notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	if isAPIPath(r.URL.Path) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	app.render(w, r, http.StatusNotFound, "404.html", app.newTemplateData(r))
+}
+
+func isAPIPath(path string) bool {
+	return strings.HasPrefix(path, "/v1/") || path == "/graphql"
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pagesTestEvents returns events relative to today, since the pages only list upcoming nights.
+func pagesTestEvents() []EdmEvent {
+	day := func(offset int) string {
+		today, _ := time.Parse(queryDateFormat, lasVegasToday(time.Now()))
+		return today.AddDate(0, 0, offset).Format(time.RFC3339)
+	}
+
+	return []EdmEvent{
+		{Id: "id-past", ClubName: "xs nightclub", ArtistName: "past artist", EventDate: day(-1)},
+		{Id: "id-1", ClubName: "xs nightclub", ArtistName: "tiësto", EventDate: day(1), TicketUrl: "https://www.wynnsocial.com/events/1", Availability: availabilitySoldOut},
+		{Id: "id-2", ClubName: "omnia", ArtistName: "martin garrix", EventDate: day(1)},
+		{Id: "id-3", ClubName: "zouk nightclub", ArtistName: "martin garrix, alesso", EventDate: day(2)},
+		{Id: "id-4", ClubName: "encore beach club", ArtistName: "<script>alert(1)</script>", EventDate: day(3)},
+	}
+}
+
+// TestPages_Positive tests the HTML pages rendered from the query layer
+func TestPages_Positive(t *testing.T) {
+	tests := []struct {
+		name          string
+		path          string
+		expected      []string
+		notExpected   []string
+		expectedNight int
+	}{
+		{
+			name:          "Home lists upcoming nights",
+			path:          "/",
+			expected:      []string{"About The Project", "tiësto", "martin garrix, alesso", `href="/artists/martin%20garrix"`, `href="/venues/zouk%20nightclub"`, `href="https://www.wynnsocial.com/events/1"`},
+			notExpected:   []string{"past artist", "Cheerio", "mongoDB", "heroku"},
+			expectedNight: 3,
+		},
+		{
+			name:          "Artist page",
+			path:          "/artists/martin%20garrix",
+			expected:      []string{"<h2>martin garrix</h2>", "omnia", "zouk nightclub", `href="/v1/artists/martin%20garrix/calendar.ics"`},
+			notExpected:   []string{"tiësto"},
+			expectedNight: 2,
+		},
+		{
+			name:          "Venue page",
+			path:          "/venues/XS%20Nightclub",
+			expected:      []string{"<h2>xs nightclub</h2>", "tiësto", `<span class="availability sold-out">Sold out</span>`},
+			notExpected:   []string{"omnia", "past artist"},
+			expectedNight: 1,
+		},
+		{
+			name:          "Search matches artists",
+			path:          "/search?q=Garrix",
+			expected:      []string{"martin garrix, alesso", `value="Garrix"`},
+			notExpected:   []string{"tiësto"},
+			expectedNight: 2,
+		},
+		{
+			name:          "Search matches venues",
+			path:          "/search?q=omnia",
+			expected:      []string{"martin garrix"},
+			notExpected:   []string{"alesso"},
+			expectedNight: 1,
+		},
+		{
+			name:          "Search without a query",
+			path:          "/search",
+			expected:      []string{"Search upcoming events by artist or venue name."},
+			expectedNight: 0,
+		},
+		{
+			name:          "Scraped names are escaped",
+			path:          "/venues/encore",
+			expected:      []string{"&lt;script&gt;alert(1)&lt;/script&gt;"},
+			notExpected:   []string{"<script>"},
+			expectedNight: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, pagesTestEvents())
+			app.config.apiKeys.require = true
+
+			rr := get(t, app, tt.path)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+			}
+			if contentType := rr.Header().Get("Content-Type"); contentType != "text/html; charset=utf-8" {
+				t.Errorf("Expected an HTML page, got '%s'", contentType)
+			}
+
+			body := rr.Body.String()
+			for _, expected := range tt.expected {
+				if !strings.Contains(body, expected) {
+					t.Errorf("Expected the page to contain %q", expected)
+				}
+			}
+			for _, notExpected := range tt.notExpected {
+				if strings.Contains(body, notExpected) {
+					t.Errorf("Expected the page not to contain %q", notExpected)
+				}
+			}
+			if nights := strings.Count(body, `<section class="night">`); nights != tt.expectedNight {
+				t.Errorf("Expected %d nights, got %d", tt.expectedNight, nights)
+			}
+		})
+	}
+}
+
+// TestPages_Negative tests unknown routes and invalid requests
+func TestPages_Negative(t *testing.T) {
+	tests := []struct {
+		name                string
+		path                string
+		expectedStatusCode  int
+		expectedContentType string
+		expected            string
+	}{
+		{
+			name:                "Unknown page renders the 404 page",
+			path:                "/home",
+			expectedStatusCode:  http.StatusNotFound,
+			expectedContentType: "text/html; charset=utf-8",
+			expected:            "Houston, we have a problem!",
+		},
+		{
+			name:                "Unknown API route stays JSON",
+			path:                "/v1/nothing",
+			expectedStatusCode:  http.StatusNotFound,
+			expectedContentType: "application/json",
+			expected:            `"error":"the requested resource could not be found"`,
+		},
+		{
+			name:                "Invalid page number",
+			path:                "/?page=0",
+			expectedStatusCode:  http.StatusBadRequest,
+			expectedContentType: "text/plain; charset=utf-8",
+			expected:            "Bad Request",
+		},
+		{
+			name:                "Invalid date",
+			path:                "/artists/tiesto?from=tomorrow",
+			expectedStatusCode:  http.StatusBadRequest,
+			expectedContentType: "text/plain; charset=utf-8",
+			expected:            "Bad Request",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, pagesTestEvents())
+			rr := get(t, app, tt.path)
+
+			if rr.Code != tt.expectedStatusCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatusCode, rr.Code)
+			}
+			if contentType := rr.Header().Get("Content-Type"); contentType != tt.expectedContentType {
+				t.Errorf("Expected Content-Type '%s', got '%s'", tt.expectedContentType, contentType)
+			}
+			if !strings.Contains(rr.Body.String(), tt.expected) {
+				t.Errorf("Expected the body to contain %q, got %q", tt.expected, rr.Body.String())
+			}
+		})
+	}
+}
+
+// TestPages_ServerError tests that storage errors don't leak into the page
+func TestPages_ServerError(t *testing.T) {
+	app := newTestApplication(t, nil)
+	app.dbSnippets = &mockSnippetModel{err: fmt.Errorf("firestore unavailable")}
+
+	rr := get(t, app, "/")
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status %d, got %d", http.StatusInternalServerError, rr.Code)
+	}
+	if strings.Contains(rr.Body.String(), "firestore") {
+		t.Errorf("Expected the error to stay in the logs, got %q", rr.Body.String())
+	}
+}
+
+// TestPages_Pagination tests the links between pages of events
+func TestPages_Pagination(t *testing.T) {
+	today, _ := time.Parse(queryDateFormat, lasVegasToday(time.Now()))
+
+	edmEvents := []EdmEvent{}
+	for i := 0; i < pageSizeHTML+1; i++ {
+		edmEvents = append(edmEvents, EdmEvent{
+			Id:         fmt.Sprintf("id-%d", i),
+			ClubName:   "omnia",
+			ArtistName: fmt.Sprintf("artist %03d", i),
+			EventDate:  today.AddDate(0, 0, i/10).Format(time.RFC3339),
+		})
+	}
+
+	app := newTestApplication(t, edmEvents)
+
+	first := get(t, app, "/venues/omnia").Body.String()
+	if !strings.Contains(first, `href="/venues/omnia?page=2"`) || strings.Contains(first, "Earlier") {
+		t.Error("Expected the first page to only link to the next page")
+	}
+
+	second := get(t, app, "/venues/omnia?page=2").Body.String()
+	if !strings.Contains(second, `href="/venues/omnia?page=1"`) || strings.Contains(second, "Later") {
+		t.Error("Expected the last page to only link to the previous page")
+	}
+	if !strings.Contains(second, fmt.Sprintf("artist %03d", pageSizeHTML)) {
+		t.Error("Expected the second page to hold the remaining event")
+	}
+}
+
+// TestStaticFiles tests that the embedded assets are served
+func TestStaticFiles(t *testing.T) {
+	app := newTestApplication(t, nil)
+
+	rr := get(t, app, "/static/css/main.css")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	if !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/css") {
+		t.Errorf("Expected a stylesheet, got '%s'", rr.Header().Get("Content-Type"))
+	}
+}
+
+// TestGroupByNight tests grouping sorted events into nights
+func TestGroupByNight(t *testing.T) {
+	nights := groupByNight([]EdmEvent{
+		{Id: "1", EventDate: "2026-11-20T00:00:00Z"},
+		{Id: "2", EventDate: "2026-11-20T22:00:00Z"},
+		{Id: "3", EventDate: "2026-11-21T00:00:00Z"},
+		{Id: "4", EventDate: "not a date"},
+		{Id: "5", EventDate: "2026-11-21T02:00:00Z"},
+	})
+
+	if len(nights) != 2 {
+		t.Fatalf("Expected 2 nights, got %d", len(nights))
+	}
+	if humanNight(nights[0].Date) != "Friday, November 20, 2026" || len(nights[0].Events) != 3 {
+		t.Errorf("Expected 3 events on Friday, November 20, 2026, got %d on %s", len(nights[0].Events), humanNight(nights[0].Date))
+	}
+	if nights[0].Events[2].Id != "5" {
+		t.Errorf("Expected the 2am event to close the previous night, got %v", nights[0].Events)
+	}
+	if len(nights[1].Events) != 1 || nights[1].Events[0].Id != "3" {
+		t.Errorf("Expected event 3 alone on the second night, got %v", nights[1].Events)
+	}
+
+	if startTime("2026-11-20T22:00:00Z") != "10:00 PM" || startTime("2026-11-20T00:00:00Z") != "" {
+		t.Error("Expected only events with a known time to show one")
+	}
+}
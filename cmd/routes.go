@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+
+	"edmEventsScraperApiGo.christiangabrielsson.net/ui"
+)
+
+func (app *application) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/", app.notFoundHandler)
+
+	mux.Handle("GET /static/", http.FileServerFS(ui.Files))
+
+	mux.HandleFunc("GET /{$}", app.homeHandler)
+	mux.HandleFunc("GET /artists/{artist}", app.artistPageHandler)
+	mux.HandleFunc("GET /venues/{venue}", app.venuePageHandler)
+	mux.HandleFunc("GET /search", app.searchPageHandler)
+
+	mux.HandleFunc("GET /healthz", app.healthzHandler)
+	mux.HandleFunc("GET /readyz", app.readyzHandler)
+
+	mux.HandleFunc("GET /v1/openapi.json", app.openAPIHandler)
+	mux.HandleFunc("GET /v1/status", app.statusHandler)
+
+	mux.HandleFunc("GET /v1/events", app.conditionalGetStreaming(app.listEdmEventsHandler, isStreamedEventFormat))
+	mux.HandleFunc("GET /v1/search", app.conditionalGet(app.searchEdmEventsHandler))
+	mux.HandleFunc("GET /v1/stream", app.streamHandler)
+
+	mux.HandleFunc("GET /v1/calendar", app.conditionalGet(app.nightCalendarHandler))
+	mux.HandleFunc("GET /v1/calendar.ics", app.conditionalGet(app.calendarFeedHandler))
+	mux.HandleFunc("GET /v1/artists/{artist}/calendar.ics", app.conditionalGet(app.calendarFeedHandler))
+	mux.HandleFunc("GET /v1/venues/{venue}/calendar.ics", app.conditionalGet(app.calendarFeedHandler))
+
+	mux.HandleFunc("GET /v1/feeds/new.rss", app.conditionalGet(app.newEventsRSSHandler))
+	mux.HandleFunc("GET /v1/feeds/new.atom", app.conditionalGet(app.newEventsAtomHandler))
+	mux.HandleFunc("GET /v1/digest", app.digestHandler)
+
+	mux.HandleFunc("GET /v1/watchlists", app.requireAPIKey(app.listWatchlistsHandler))
+	mux.HandleFunc("POST /v1/watchlists", app.requireAPIKey(app.createWatchlistHandler))
+	mux.HandleFunc("GET /v1/watchlists/{id}", app.requireAPIKey(app.showWatchlistHandler))
+	mux.HandleFunc("PATCH /v1/watchlists/{id}", app.requireAPIKey(app.updateWatchlistHandler))
+	mux.HandleFunc("DELETE /v1/watchlists/{id}", app.requireAPIKey(app.deleteWatchlistHandler))
+	mux.HandleFunc("GET /v1/alerts", app.requireAPIKey(app.listAlertsHandler))
+
+	mux.HandleFunc("GET /v1/webhooks", app.requireAPIKey(app.listWebhooksHandler))
+	mux.HandleFunc("POST /v1/webhooks", app.requireAPIKey(app.createWebhookHandler))
+	mux.HandleFunc("GET /v1/webhooks/{id}", app.requireAPIKey(app.showWebhookHandler))
+	mux.HandleFunc("DELETE /v1/webhooks/{id}", app.requireAPIKey(app.deleteWebhookHandler))
+	mux.HandleFunc("GET /v1/webhooks/{id}/deliveries", app.requireAPIKey(app.listWebhookDeliveriesHandler))
+	mux.HandleFunc("POST /v1/webhooks/{id}/deliveries/{delivery}/replay", app.requireAPIKey(app.replayWebhookDeliveryHandler))
+
+	mux.HandleFunc("GET /v1/push/vapid-public-key", app.vapidPublicKeyHandler)
+	mux.HandleFunc("GET /v1/push/subscriptions", app.requireAPIKey(app.listPushSubscriptionsHandler))
+	mux.HandleFunc("POST /v1/push/subscriptions", app.requireAPIKey(app.createPushSubscriptionHandler))
+	mux.HandleFunc("DELETE /v1/push/subscriptions/{id}", app.requireAPIKey(app.deletePushSubscriptionHandler))
+
+	mux.HandleFunc("GET /v1/admin/runs", app.requireAdminAPIKey(app.listRunsHandler))
+	mux.HandleFunc("POST /v1/admin/runs", app.requireAdminAPIKey(app.createRunHandler))
+	mux.HandleFunc("GET /v1/admin/runs/{id}", app.requireAdminAPIKey(app.showRunHandler))
+
+	graphqlSchema := app.newGraphQLSchema()
+	mux.HandleFunc("GET /graphql", app.graphqlHandler(graphqlSchema))
+	mux.HandleFunc("POST /graphql", app.graphqlHandler(graphqlSchema))
+
+	return app.requestID(app.logRequest(app.recoverPanic(app.enableCORS(app.authenticate(mux)))))
+}
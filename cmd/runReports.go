@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The number of runs the history lists by default, and at most.
+const (
+	defaultRunHistoryLimit = 50
+	maxRunHistoryLimit     = 500
+)
+
+type RunModelInterface interface {
+	Insert(run scrapeRun) error
+	Get(id string) (scrapeRun, error)
+	Latest(limit int) ([]scrapeRun, error)
+}
+
+// RunModel stores one document per finished scrape run, keyed by the run's id.
+type RunModel struct {
+	Client     *firestore.Client
+	Collection string
+}
+
+func (m *RunModel) Insert(run scrapeRun) error {
+	ctx := context.Background()
+	_, err := m.Client.Collection(m.Collection).Doc(run.ID).Set(ctx, run)
+	if err != nil {
+		return fmt.Errorf("failed to insert scrape run %s: %v", run.ID, err)
+	}
+	return nil
+}
+
+func (m *RunModel) Get(id string) (scrapeRun, error) {
+	ctx := context.Background()
+
+	doc, err := m.Client.Collection(m.Collection).Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return scrapeRun{}, errRecordNotFound
+	}
+	if err != nil {
+		return scrapeRun{}, fmt.Errorf("failed to get scrape run %s: %v", id, err)
+	}
+
+	var run scrapeRun
+	if err := doc.DataTo(&run); err != nil {
+		return scrapeRun{}, fmt.Errorf("failed to decode scrape run %s: %v", id, err)
+	}
+	return run, nil
+}
+
+// Latest returns the most recent runs, most recent first.
+func (m *RunModel) Latest(limit int) ([]scrapeRun, error) {
+	ctx := context.Background()
+	runs := []scrapeRun{}
+
+	iter := m.Client.Collection(m.Collection).OrderBy("StartedAt", firestore.Desc).Limit(limit).Documents(ctx)
+	defer iter.Stop()
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate scrape runs: %v", err)
+		}
+
+		var run scrapeRun
+		if err := doc.DataTo(&run); err != nil {
+			return nil, fmt.Errorf("failed to decode scrape run %s: %v", doc.Ref.ID, err)
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, nil
+}
+
+// runHistory returns the most recent runs, most recent first. The runs of this instance are
+// taken from memory, which also has the one still running, the others from the store.
+func (app *application) runHistory(limit int) ([]scrapeRun, error) {
+	stored, err := app.dbRuns.Latest(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := app.runs.list()
+	for _, run := range stored {
+		if !slices.ContainsFunc(runs, func(r scrapeRun) bool { return r.ID == run.ID }) {
+			runs = append(runs, run)
+		}
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].StartedAt > runs[j].StartedAt
+	})
+	if len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs, nil
+}
+
+// findRun returns the run from memory when this instance has it, from the store otherwise.
+func (app *application) findRun(id string) (scrapeRun, error) {
+	if run, ok := app.runs.get(id); ok {
+		return run, nil
+	}
+	return app.dbRuns.Get(id)
+}
+
+const runsUsage = "usage: runs list [-limit N] [-source SOURCE] | runs show ID"
+
+// runsCommand prints the run history from the command line: list shows a line per run, or
+// the counts of one source over the runs with -source, show prints the report of a run.
+func (app *application) runsCommand(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(runsUsage)
+	}
+
+	switch args[0] {
+	case "list":
+		return app.listRuns(args[1:], out)
+	case "show":
+		if len(args) != 2 {
+			return errors.New(runsUsage)
+		}
+		run, err := app.findRun(args[1])
+		if errors.Is(err, errRecordNotFound) {
+			return fmt.Errorf("no scrape run with id %s", args[1])
+		}
+		if err != nil {
+			return err
+		}
+		writeRunReport(out, run)
+		return nil
+	default:
+		return fmt.Errorf("unknown runs command %q, %s", args[0], runsUsage)
+	}
+}
+
+func (app *application) listRuns(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("runs list", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+
+	limit := flags.Int("limit", 20, "Number of runs to list")
+	source := flags.String("source", "", "Only list the counts of this source")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return fmt.Errorf("%v, %s", err, runsUsage)
+	}
+
+	v := newValidator()
+	v.Check(*limit >= 1 && *limit <= maxRunHistoryLimit, "limit", fmt.Sprintf("must be between 1 and %d", maxRunHistoryLimit))
+	v.Check(*source == "" || slices.Contains(allSources, *source), "source", "must be one of "+strings.Join(allSources, ", "))
+	if !v.Valid() {
+		return fmt.Errorf("invalid runs list: %v", v.Errors)
+	}
+
+	runs, err := app.runHistory(*limit)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	if *source != "" {
+		fmt.Fprintln(tw, "ID\tSTARTED\tSTATUS\tEVENTS\tRAW\tPARSE FAILED\tPAST\tFILTERED\tHTTP ERRORS")
+		for _, run := range runs {
+			for _, report := range run.Sources {
+				if report.Source != *source {
+					continue
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t%s\n",
+					run.ID, run.StartedAt, report.Status, report.EventCount, report.Stats.Raw,
+					report.Stats.ParseFailed, report.Stats.PastDropped, report.Stats.Filtered, httpErrorSummary(report.Stats.HTTPErrors))
+			}
+		}
+		return tw.Flush()
+	}
+
+	fmt.Fprintln(tw, "ID\tTRIGGER\tSTARTED\tDURATION\tSTATUS\tEXIT\tEVENTS\tCREATED\tUPDATED\tREMOVED\tHTTP ERRORS")
+	for _, run := range runs {
+		events := []string{}
+		for _, report := range run.Sources {
+			events = append(events, fmt.Sprintf("%s=%d", report.Source, report.EventCount))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\t%s\n",
+			run.ID, run.Trigger, run.StartedAt, runDuration(run), run.Status, exitStatusText(run),
+			strings.Join(events, " "), run.Created, run.Updated, run.Removed, httpErrorSummary(run.HTTPErrors))
+	}
+	return tw.Flush()
+}
+
+// writeRunReport prints a run along with the counts of each of its sources.
+func writeRunReport(out io.Writer, run scrapeRun) {
+	fmt.Fprintf(out, "Run %s (%s) %s, exit status %s\n", run.ID, run.Trigger, run.Status, exitStatusText(run))
+	fmt.Fprintf(out, "Started %s, finished %s, took %s\n", run.StartedAt, orDash(run.FinishedAt), runDuration(run))
+	if run.Error != "" {
+		fmt.Fprintf(out, "Error: %s\n", run.Error)
+	}
+	fmt.Fprintf(out, "Sync: %d created, %d updated, %d removed\n", run.Created, run.Updated, run.Removed)
+	fmt.Fprintf(out, "HTTP errors: %s\n\n", httpErrorSummary(run.HTTPErrors))
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SOURCE\tSTATUS\tEVENTS\tRAW\tPARSE FAILED\tPAST\tFILTERED\tREQUESTS\tHTTP ERRORS\tERROR")
+	for _, report := range run.Sources {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%s\t%s\n",
+			report.Source, report.Status, report.EventCount, report.Stats.Raw, report.Stats.ParseFailed,
+			report.Stats.PastDropped, report.Stats.Filtered, report.Stats.Requests,
+			httpErrorSummary(report.Stats.HTTPErrors), orDash(report.Error))
+	}
+	tw.Flush()
+}
+
+// httpErrorSummary prints the HTTP errors by count, such as "503 Service Unavailable x2".
+func httpErrorSummary(httpErrors map[string]int) string {
+	if len(httpErrors) == 0 {
+		return "-"
+	}
+
+	names := make([]string, 0, len(httpErrors))
+	for name := range httpErrors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	summary := make([]string, 0, len(names))
+	for _, name := range names {
+		summary = append(summary, fmt.Sprintf("%s x%d", name, httpErrors[name]))
+	}
+	return strings.Join(summary, ", ")
+}
+
+func runDuration(run scrapeRun) string {
+	startedAt, err := time.Parse(time.RFC3339, run.StartedAt)
+	if err != nil {
+		return "-"
+	}
+	finishedAt, err := time.Parse(time.RFC3339, run.FinishedAt)
+	if err != nil {
+		return "-"
+	}
+	return finishedAt.Sub(startedAt).String()
+}
+
+func exitStatusText(run scrapeRun) string {
+	if run.ExitStatus == nil {
+		return "-"
+	}
+	return fmt.Sprint(*run.ExitStatus)
+}
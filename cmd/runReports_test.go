@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func exitStatus(code int) *int {
+	return &code
+}
+
+var runReportsTestRuns = []scrapeRun{
+	{
+		ID: "run-1", Trigger: runTriggerJob, Status: runStatusSucceeded, ExitStatus: exitStatus(0),
+		StartedAt: "2025-10-18T06:00:00Z", FinishedAt: "2025-10-18T06:01:30Z",
+		Sources: []sourceRunReport{
+			{Source: sourceWynn, Status: sourceRunScraped, EventCount: 41, Stats: scrapeStats{Requests: 1, Raw: 45, PastDropped: 2, Filtered: 2}},
+			{Source: sourceLiv, Status: sourceRunFailed, Error: "error scraping: status recieved was 503", Stats: scrapeStats{Requests: 1, HTTPErrors: map[string]int{"503 Service Unavailable": 1}}},
+		},
+		HTTPErrors: map[string]int{"503 Service Unavailable": 1},
+		Created:    3, Updated: 1, Removed: 2,
+	},
+	{
+		ID: "run-2", Trigger: runTriggerAdmin, Status: runStatusFailed, ExitStatus: exitStatus(1), Error: "error reading documents from Firestore",
+		StartedAt: "2025-10-19T06:00:00Z", FinishedAt: "2025-10-19T06:00:10Z",
+		Sources: []sourceRunReport{
+			{Source: sourceWynn, Status: sourceRunScraped, EventCount: 40, Stats: scrapeStats{Requests: 1, Raw: 40}},
+		},
+	},
+}
+
+// TestAddEdmEventsToFirestore_RunReport tests that the job records its run with the counts
+// and HTTP errors of every source
+func TestAddEdmEventsToFirestore_RunReport(t *testing.T) {
+	app, _, _ := newAdminRunsTestApplication(t)
+
+	var out bytes.Buffer
+	err := app.addEdmEventsToFirestore(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runs := app.dbRuns.(*mockRunModel).runs
+	if len(runs) != 1 {
+		t.Fatalf("Expected the run to be recorded, got %d runs", len(runs))
+	}
+	run := runs[0]
+	if run.Trigger != runTriggerJob || run.Status != runStatusSucceeded || run.ExitStatus == nil || *run.ExitStatus != 0 || run.FinishedAt == "" {
+		t.Errorf("Expected a finished job run with exit status 0, got %+v", run)
+	}
+	if run.HTTPErrors["503 Service Unavailable"] != len(allSources) {
+		t.Errorf("Expected a 503 per source, got %v", run.HTTPErrors)
+	}
+	for _, report := range run.Sources {
+		if report.Status != sourceRunFailed || report.Stats.Requests < 1 {
+			t.Errorf("Expected %s to report its failed request, got %+v", report.Source, report)
+		}
+	}
+
+	for _, expected := range []string{"Run " + run.ID + " (job) succeeded, exit status 0", "503 Service Unavailable x4", "SOURCE"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected the report to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+
+	// A sync that fails is recorded with exit status 1 and fails the job.
+	app.dbSnippets = &mockSnippetModel{err: errors.New("unavailable")}
+	err = app.addEdmEventsToFirestore(&out)
+	if err == nil {
+		t.Fatal("Expected the job to fail")
+	}
+
+	runs = app.dbRuns.(*mockRunModel).runs
+	if len(runs) != 2 || runs[1].Status != runStatusFailed || runs[1].ExitStatus == nil || *runs[1].ExitStatus != 1 || runs[1].Error == "" {
+		t.Errorf("Expected the failed run to be recorded with exit status 1, got %+v", runs)
+	}
+}
+
+// TestRunsCommand tests listing and showing the run history from the command line
+func TestRunsCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		expected    []string
+		notExpected []string
+		expectedErr string
+	}{
+		{name: "List", args: []string{"list"}, expected: []string{"run-2", "admin", "failed", "10s", "run-1", "wynn=41 liv=0", "503 Service Unavailable x1"}},
+		{name: "List is most recent first", args: []string{"list", "-limit", "1"}, expected: []string{"run-2"}, notExpected: []string{"run-1"}},
+		{name: "List a source", args: []string{"list", "-source", "wynn"}, expected: []string{"RAW", "run-1", "41", "45", "run-2"}, notExpected: []string{"liv"}},
+		{name: "Show", args: []string{"show", "run-1"}, expected: []string{"Run run-1 (job) succeeded, exit status 0", "took 1m30s", "Sync: 3 created, 1 updated, 2 removed", "status recieved was 503"}},
+		{name: "Show unknown", args: []string{"show", "run-3"}, expectedErr: "no scrape run with id run-3"},
+		{name: "Invalid limit", args: []string{"list", "-limit", "0"}, expectedErr: "limit"},
+		{name: "Unknown source", args: []string{"list", "-source", "xs"}, expectedErr: "source"},
+		{name: "Unknown command", args: []string{"delete"}, expectedErr: runsUsage},
+		{name: "No command", args: nil, expectedErr: runsUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, nil)
+			app.dbRuns = &mockRunModel{runs: runReportsTestRuns}
+
+			var out bytes.Buffer
+			err := app.runsCommand(tt.args, &out)
+
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Errorf("Expected an error containing '%s', got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(out.String(), expected) {
+					t.Errorf("Expected the output to contain %q, got:\n%s", expected, out.String())
+				}
+			}
+			for _, notExpected := range tt.notExpected {
+				if strings.Contains(out.String(), notExpected) {
+					t.Errorf("Expected the output not to contain %q, got:\n%s", notExpected, out.String())
+				}
+			}
+		})
+	}
+}
+
+// TestAdminRuns_History tests that the admin endpoints serve stored runs along with the ones
+// of this instance
+func TestAdminRuns_History(t *testing.T) {
+	app, adminKey, _ := newAdminRunsTestApplication(t)
+	app.dbRuns = &mockRunModel{runs: runReportsTestRuns}
+
+	rr := adminRequest(t, app, http.MethodPost, "/v1/admin/runs", adminKey, `{"sources": ["wynn"]}`)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("Expected status %d, got %d", http.StatusAccepted, rr.Code)
+	}
+	var created struct {
+		Run scrapeRun `json:"run"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	waitForRun(t, app, created.Run.ID)
+
+	rr = adminRequest(t, app, http.MethodGet, "/v1/admin/runs?limit=2", adminKey, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	var listed struct {
+		Runs []scrapeRun `json:"runs"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &listed); err != nil {
+		t.Fatal(err)
+	}
+	if len(listed.Runs) != 2 || listed.Runs[0].ID != created.Run.ID || listed.Runs[1].ID != "run-2" {
+		t.Errorf("Expected the new run then run-2, got %+v", listed.Runs)
+	}
+
+	rr = adminRequest(t, app, http.MethodGet, "/v1/admin/runs/run-1", adminKey, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	var shown struct {
+		Run scrapeRun `json:"run"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &shown); err != nil {
+		t.Fatal(err)
+	}
+	if shown.Run.Sources[0].Stats.Raw != 45 || shown.Run.HTTPErrors["503 Service Unavailable"] != 1 {
+		t.Errorf("Expected the stored report of run-1, got %+v", shown.Run)
+	}
+
+	app.dbRuns = &mockRunModel{err: errors.New("unavailable")}
+	rr = adminRequest(t, app, http.MethodGet, "/v1/admin/runs", adminKey, "")
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d when the history can't be read, got %d", http.StatusInternalServerError, rr.Code)
+	}
+}
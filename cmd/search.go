@@ -0,0 +1,281 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Relevance of a query token matching an event token, venues count for less than artists.
+const (
+	searchScoreExact  = 1.0
+	searchScorePrefix = 0.8
+	searchScoreTypo   = 0.6
+	searchVenueWeight = 0.6
+	// Added when the whole query appears as is in the artist name.
+	searchPhraseBonus = 0.5
+)
+
+const maxSearchQueryLength = 100
+
+// searchEntry is an event along with its normalized tokens.
+type searchEntry struct {
+	edmEvent     EdmEvent
+	artist       string
+	artistTokens []string
+	venueTokens  []string
+}
+
+// searchResult is an event matching a search, with its relevance.
+type searchResult struct {
+	Score    float64  `json:"score"`
+	EdmEvent EdmEvent `json:"event"`
+}
+
+// searchIndex holds the normalized tokens of every stored event. It's rebuilt whenever the
+// sync state moves on, so queries never have to load and normalize the events themselves.
+type searchIndex struct {
+	mu      sync.RWMutex
+	version string
+	built   bool
+	entries []searchEntry
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{}
+}
+
+// rebuild replaces the indexed events with those of the given sync version.
+func (idx *searchIndex) rebuild(version string, edmEvents []EdmEvent) {
+	entries := make([]searchEntry, 0, len(edmEvents))
+	for _, edmEvent := range edmEvents {
+		artist := normalizeSearchText(edmEvent.ArtistName)
+		entries = append(entries, searchEntry{
+			edmEvent:     edmEvent,
+			artist:       artist,
+			artistTokens: strings.Fields(artist),
+			venueTokens:  strings.Fields(normalizeSearchText(edmEvent.ClubName)),
+		})
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.version = version
+	idx.built = true
+	idx.entries = entries
+}
+
+func (idx *searchIndex) current(version string) bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return idx.built && version != "" && idx.version == version
+}
+
+// search returns the events matching every token of the query and the filters, the most
+// relevant first and, among equally relevant ones, the soonest first.
+func (idx *searchIndex) search(query string, f EventFilters) []searchResult {
+	queryTokens := strings.Fields(normalizeSearchText(query))
+	if len(queryTokens) == 0 {
+		return []searchResult{}
+	}
+	phrase := strings.Join(queryTokens, " ")
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	results := []searchResult{}
+	for _, entry := range idx.entries {
+		if !f.matches(entry.edmEvent) {
+			continue
+		}
+
+		score, ok := entry.score(queryTokens)
+		if !ok {
+			continue
+		}
+		if strings.Contains(entry.artist, phrase) {
+			score += searchPhraseBonus
+		}
+
+		results = append(results, searchResult{Score: score, EdmEvent: entry.edmEvent})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if results[i].EdmEvent.EventDate != results[j].EdmEvent.EventDate {
+			return results[i].EdmEvent.EventDate < results[j].EdmEvent.EventDate
+		}
+		return results[i].EdmEvent.ArtistName < results[j].EdmEvent.ArtistName
+	})
+
+	return results
+}
+
+// score averages how well each query token matches the entry. Every token has to match an
+// artist or venue token for the entry to match at all.
+func (entry searchEntry) score(queryTokens []string) (float64, bool) {
+	total := 0.0
+	for _, queryToken := range queryTokens {
+		best := bestTokenScore(queryToken, entry.artistTokens)
+		best = max(best, searchVenueWeight*bestTokenScore(queryToken, entry.venueTokens))
+		if best == 0 {
+			return 0, false
+		}
+		total += best
+	}
+
+	return total / float64(len(queryTokens)), true
+}
+
+// bestTokenScore matches a query token against tokens exactly, as a prefix of one of them,
+// or with a few typos depending on the length of the query token.
+func bestTokenScore(queryToken string, tokens []string) float64 {
+	best := 0.0
+	for _, token := range tokens {
+		switch {
+		case token == queryToken:
+			return searchScoreExact
+		case len(queryToken) >= 2 && strings.HasPrefix(token, queryToken):
+			best = max(best, searchScorePrefix)
+		default:
+			maxDistance := allowedTypos(queryToken)
+			if maxDistance == 0 {
+				continue
+			}
+			if distance := levenshtein(queryToken, token, maxDistance); distance <= maxDistance {
+				best = max(best, searchScoreTypo*(1-0.25*float64(distance-1)))
+			}
+		}
+	}
+	return best
+}
+
+// allowedTypos is the edit distance tolerated for a query token: none for short tokens,
+// where a single edit matches too many unrelated names.
+func allowedTypos(queryToken string) int {
+	switch n := len([]rune(queryToken)); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// levenshtein returns the edit distance between a and b, or maxDistance+1 as soon as it's
+// known to be larger than maxDistance.
+func levenshtein(a string, b string, maxDistance int) int {
+	ra, rb := []rune(a), []rune(b)
+	if abs(len(ra)-len(rb)) > maxDistance {
+		return maxDistance + 1
+	}
+
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		rowMin := current[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			rowMin = min(rowMin, current[j])
+		}
+		if rowMin > maxDistance {
+			return maxDistance + 1
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// normalizeSearchText lowercases s, strips its diacritics, so that "tiësto" is indexed as
+// "tiesto", and replaces punctuation with spaces.
+func normalizeSearchText(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, s)
+	if err != nil {
+		folded = s
+	}
+
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return ' '
+	}, folded)
+}
+
+// searchEdmEvents runs the query against the index, rebuilding it first if a sync happened
+// since it was last built.
+func (app *application) searchEdmEvents(query string, f EventFilters) ([]searchResult, error) {
+	sourceSyncs, err := app.dbSyncStates.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	version := syncVersion(sourceSyncs)
+	if !app.searchIndex.current(version) {
+		edmEvents, err := app.dbSnippets.GetAll()
+		if err != nil {
+			return nil, err
+		}
+		app.searchIndex.rebuild(version, edmEvents)
+	}
+
+	return app.searchIndex.search(query, f), nil
+}
+
+func (app *application) searchEdmEventsHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	v := newValidator()
+
+	query := strings.TrimSpace(app.readString(qs, "q", ""))
+	v.Check(strings.TrimSpace(normalizeSearchText(query)) != "", "q", "must contain a letter or digit")
+	v.Check(len(query) <= maxSearchQueryLength, "q", fmt.Sprintf("must not be more than %d bytes long", maxSearchQueryLength))
+
+	filters := app.readEventFilters(qs, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	results, err := app.searchEdmEvents(query, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	results, metadata := paginateItems(filters, results)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"results": results, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+var searchTestEvents = []EdmEvent{
+	{Id: "tiesto-xs", ClubName: "xs nightclub", ArtistName: "tiësto", EventDate: "2026-11-21T00:00:00Z"},
+	{Id: "tiesto-omnia", ClubName: "omnia", ArtistName: "tiësto", EventDate: "2026-11-20T00:00:00Z"},
+	{Id: "garrix-b2b", ClubName: "xs nightclub", ArtistName: "martin garrix b2b alesso", EventDate: "2026-11-22T00:00:00Z"},
+	{Id: "garrix", ClubName: "omnia", ArtistName: "martin garrix", EventDate: "2026-12-01T00:00:00Z"},
+	{Id: "kaskade", ClubName: "zouk nightclub", ArtistName: "kaskade", EventDate: "2026-11-23T00:00:00Z"},
+	{Id: "rufus", ClubName: "marquee", ArtistName: "rüfüs du sol", EventDate: "2026-11-24T00:00:00Z"},
+}
+
+// TestNormalizeSearchText tests the folding of diacritics and punctuation
+func TestNormalizeSearchText(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "tiësto", expected: "tiesto"},
+		{input: "RÜFÜS DU SOL", expected: "rufus du sol"},
+		{input: "martin garrix b2b alesso", expected: "martin garrix b2b alesso"},
+		{input: "above & beyond", expected: "above   beyond"},
+		{input: "zhu's \"blacklizt\"", expected: "zhu s  blacklizt "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := normalizeSearchText(tt.input); got != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestLevenshtein tests the bounded edit distance
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b        string
+		maxDistance int
+		expected    int
+	}{
+		{a: "tiesto", b: "tiesto", maxDistance: 1, expected: 0},
+		{a: "tiestto", b: "tiesto", maxDistance: 1, expected: 1},
+		{a: "kaskde", b: "kaskade", maxDistance: 1, expected: 1},
+		{a: "garix", b: "garrix", maxDistance: 1, expected: 1},
+		{a: "marquee", b: "marque", maxDistance: 1, expected: 1},
+		{a: "calvin", b: "kaskade", maxDistance: 2, expected: 3},
+		{a: "omnia", b: "omnia nightclub", maxDistance: 2, expected: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			if got := levenshtein(tt.a, tt.b, tt.maxDistance); got != tt.expected {
+				t.Errorf("Expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestSearchHandler tests the matches and their ranking
+func TestSearchHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		expectedIDs []string
+	}{
+		{name: "Without diacritics", query: "tiesto", expectedIDs: []string{"tiesto-omnia", "tiesto-xs"}},
+		{name: "Typo", query: "kaskde", expectedIDs: []string{"kaskade"}},
+		{name: "Typo and accents", query: "Rufus+Du+Sool", expectedIDs: []string{"rufus"}},
+		{name: "Partial name", query: "garr", expectedIDs: []string{"garrix-b2b", "garrix"}},
+		{name: "Artist inside a multi-artist title", query: "alesso", expectedIDs: []string{"garrix-b2b"}},
+		{name: "Exact title ranks first", query: "martin+garrix", expectedIDs: []string{"garrix-b2b", "garrix"}},
+		{name: "Artist and venue", query: "tiesto+xs", expectedIDs: []string{"tiesto-xs"}},
+		{name: "Venue", query: "omnia", expectedIDs: []string{"tiesto-omnia", "garrix"}},
+		{name: "Short tokens need to be exact", query: "xz", expectedIDs: []string{}},
+		{name: "Filtered by date", query: "garrix&from=2026-11-30", expectedIDs: []string{"garrix"}},
+		{name: "No match", query: "deadmau5", expectedIDs: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, searchTestEvents)
+
+			rr := get(t, app, "/v1/search?q="+tt.query)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+			}
+
+			var response struct {
+				Results  []searchResult `json:"results"`
+				Metadata Metadata       `json:"metadata"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(response.Results) != len(tt.expectedIDs) {
+				t.Fatalf("Expected %d results, got %+v", len(tt.expectedIDs), response.Results)
+			}
+			for i, id := range tt.expectedIDs {
+				if response.Results[i].EdmEvent.Id != id {
+					t.Errorf("Expected result %d to be '%s', got '%s'", i, id, response.Results[i].EdmEvent.Id)
+				}
+			}
+			if response.Metadata.TotalRecords != len(tt.expectedIDs) {
+				t.Errorf("Expected %d total records, got %d", len(tt.expectedIDs), response.Metadata.TotalRecords)
+			}
+		})
+	}
+}
+
+// TestSearchHandler_Negative tests the query validation
+func TestSearchHandler_Negative(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "Missing query", path: "/v1/search"},
+		{name: "Punctuation only", path: "/v1/search?q=%26%26"},
+		{name: "Query too long", path: "/v1/search?q=" + strings.Repeat("a", 101)},
+		{name: "Invalid page", path: "/v1/search?q=tiesto&page=0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, searchTestEvents)
+
+			rr := get(t, app, tt.path)
+			if rr.Code != http.StatusUnprocessableEntity {
+				t.Errorf("Expected status %d, got %d", http.StatusUnprocessableEntity, rr.Code)
+			}
+		})
+	}
+}
+
+// TestSearchIndex_Rebuild tests that the index follows the syncs
+func TestSearchIndex_Rebuild(t *testing.T) {
+	app := newTestApplication(t, searchTestEvents)
+	syncStates := app.dbSyncStates.(*mockSyncStateModel)
+	syncStates.sourceSyncs = []SourceSync{{Source: sourceWynn, LastSuccessfulSync: "2026-10-18T06:00:00Z"}}
+	model := app.dbSnippets.(*mockSnippetModel)
+
+	if results, err := app.searchEdmEvents("kaskade", EventFilters{}); err != nil || len(results) != 1 {
+		t.Fatalf("Expected kaskade to be found, got %d results (%v)", len(results), err)
+	}
+
+	model.edmEvents = []EdmEvent{{Id: "zedd", ClubName: "xs nightclub", ArtistName: "zedd"}}
+	calls := model.getAllCalls
+
+	if results, _ := app.searchEdmEvents("kaskade", EventFilters{}); len(results) != 1 || model.getAllCalls != calls {
+		t.Errorf("Expected the index to be reused until the next sync, got %d results and %d loads", len(results), model.getAllCalls-calls)
+	}
+
+	syncStates.sourceSyncs[0].LastSuccessfulSync = "2026-10-19T06:00:00Z"
+
+	if results, _ := app.searchEdmEvents("kaskade", EventFilters{}); len(results) != 0 {
+		t.Errorf("Expected kaskade to be gone after the sync, got %d results", len(results))
+	}
+	if results, _ := app.searchEdmEvents("zed", EventFilters{}); len(results) != 1 {
+		t.Errorf("Expected zedd to be found after the sync, got %d results", len(results))
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+)
+
+// The notification channels deliveries are recorded for.
+const (
+	channelEmail    = "email"
+	channelTelegram = "telegram"
+	channelPush     = "push"
+)
+
+const (
+	sendStatusSent   = "sent"
+	sendStatusFailed = "failed"
+)
+
+// SendLogEntry records one notification sent, or given up on, along with the alerts or the
+// digest it told the recipient about.
+type SendLogEntry struct {
+	ID        string   `json:"id"`
+	Channel   string   `json:"channel"`
+	Recipient string   `json:"recipient"`
+	AlertIDs  []string `json:"alert_ids,omitempty"`
+	DigestID  string   `json:"digest_id,omitempty"`
+	Status    string   `json:"status"`
+	Attempts  int      `json:"attempts"`
+	Error     string   `json:"error,omitempty"`
+	CreatedAt string   `json:"created_at"`
+}
+
+type SendLogModelInterface interface {
+	Insert(entry SendLogEntry) error
+}
+
+// SendLogModel stores one document per delivery, keyed by the entry's id.
+type SendLogModel struct {
+	Client     *firestore.Client
+	Collection string
+}
+
+func (m *SendLogModel) Insert(entry SendLogEntry) error {
+	ctx := context.Background()
+	_, err := m.Client.Collection(m.Collection).Doc(entry.ID).Create(ctx, entry)
+	if err != nil {
+		return fmt.Errorf("failed to insert send log entry: %v", err)
+	}
+	return nil
+}
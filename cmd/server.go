@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// apiKeyUsageFlushInterval is how often request counts are written to storage, batching them
+// keeps the number of writes independent of traffic.
+const apiKeyUsageFlushInterval = time.Minute
+
+func (app *application) serve() error {
+	srv := app.newServer()
+
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+
+	go app.flushAPIKeyUsage(time.NewTicker(apiKeyUsageFlushInterval).C)
+	go app.watchSyncs(time.NewTicker(syncWatchInterval).C)
+
+	// Cloud Run sends SIGTERM before stopping an instance, SIGINT is Ctrl-C when run locally.
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	app.logger.Printf("starting %s server on %s", app.config.env, srv.Addr)
+
+	return app.runServer(srv, ln, quit)
+}
+
+func (app *application) newServer() *http.Server {
+	return &http.Server{
+		Addr:         fmt.Sprintf(":%d", app.config.port),
+		Handler:      app.routes(),
+		ErrorLog:     app.logger,
+		ReadTimeout:  app.config.server.readTimeout,
+		WriteTimeout: app.config.server.writeTimeout,
+		IdleTimeout:  app.config.server.idleTimeout,
+	}
+}
+
+// runServer serves on ln until a signal is received on quit, then stops accepting
+// connections and waits, up to the shutdown timeout, for the in-flight requests and the
+// background runs to finish.
+func (app *application) runServer(srv *http.Server, ln net.Listener, quit <-chan os.Signal) error {
+	// Streams never finish on their own, end them so that Shutdown doesn't wait on them.
+	srv.RegisterOnShutdown(app.changes.close)
+
+	shutdownError := make(chan error)
+
+	go func() {
+		s := <-quit
+
+		app.logger.Printf("shutting down server, caught signal %s", s)
+
+		ctx, cancel := context.WithTimeout(context.Background(), app.config.server.shutdownTimeout)
+		defer cancel()
+
+		err := srv.Shutdown(ctx)
+		if err != nil {
+			shutdownError <- err
+			return
+		}
+
+		app.logger.Printf("waiting for background tasks to finish")
+
+		done := make(chan struct{})
+		go func() {
+			app.wg.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			shutdownError <- ctx.Err()
+			return
+		}
+
+		// Don't lose the request counts since the last periodic flush.
+		shutdownError <- app.apiKeyAuth.flushUsage(app.dbAPIKeys, time.Now())
+	}()
+
+	err := srv.Serve(ln)
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	err = <-shutdownError
+	if err != nil {
+		return err
+	}
+
+	app.logger.Printf("stopped server on %s", srv.Addr)
+
+	return nil
+}
+
+// flushAPIKeyUsage writes the per-key request counts every time tick fires.
+func (app *application) flushAPIKeyUsage(tick <-chan time.Time) {
+	for now := range tick {
+		err := app.apiKeyAuth.flushUsage(app.dbAPIKeys, now)
+		if err != nil {
+			app.logger.Printf("failed to flush api key usage: %v", err)
+		}
+	}
+}
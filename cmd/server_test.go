@@ -0,0 +1,138 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestRunServer_GracefulShutdown tests that a shutdown lets in-flight requests and background tasks finish
+func TestRunServer_GracefulShutdown(t *testing.T) {
+	app := newTestApplication(t, nil)
+	app.config.server.shutdownTimeout = 5 * time.Second
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	backgroundDone := make(chan struct{})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: mux, ErrorLog: app.logger}
+
+	quit := make(chan os.Signal, 1)
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- app.runServer(srv, ln, quit)
+	}()
+
+	app.background(func() {
+		<-release
+		time.Sleep(50 * time.Millisecond)
+		close(backgroundDone)
+	})
+
+	type response struct {
+		body string
+		err  error
+	}
+	responses := make(chan response, 1)
+	go func() {
+		res, err := http.Get("http://" + ln.Addr().String() + "/slow")
+		if err != nil {
+			responses <- response{err: err}
+			return
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		responses <- response{body: string(body), err: err}
+	}()
+
+	<-started
+	quit <- syscall.SIGTERM
+
+	// New connections are refused once the shutdown started.
+	time.Sleep(50 * time.Millisecond)
+	_, err = net.DialTimeout("tcp", ln.Addr().String(), time.Second)
+	if err == nil {
+		t.Error("Expected new connections to be refused during shutdown")
+	}
+
+	select {
+	case err := <-serverErr:
+		t.Fatalf("Expected the server to wait for the in-flight request, it returned %v", err)
+	default:
+	}
+
+	close(release)
+
+	res := <-responses
+	if res.err != nil {
+		t.Fatalf("Expected the in-flight request to complete, got %v", res.err)
+	}
+	if res.body != "done" {
+		t.Errorf("Expected body 'done', got '%s'", res.body)
+	}
+
+	select {
+	case err := <-serverErr:
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the server to stop")
+	}
+
+	select {
+	case <-backgroundDone:
+	default:
+		t.Error("Expected the server to wait for the background task")
+	}
+}
+
+// TestRunServer_ClosesStreams tests that open event streams end when the server shuts down
+func TestRunServer_ClosesStreams(t *testing.T) {
+	app := newTestApplication(t, nil)
+	app.config.server.shutdownTimeout = 5 * time.Second
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: app.routes(), ErrorLog: app.logger}
+
+	quit := make(chan os.Signal, 1)
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- app.runServer(srv, ln, quit)
+	}()
+
+	res, err := http.Get("http://" + ln.Addr().String() + "/v1/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	quit <- syscall.SIGTERM
+
+	select {
+	case err := <-serverErr:
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the server to stop with a stream open")
+	}
+}
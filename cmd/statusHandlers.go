@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// The freshness verdicts reported by /v1/status.
+const (
+	freshnessFresh = "fresh"
+	freshnessStale = "stale"
+	freshnessNever = "never"
+)
+
+// sourceStatus is the freshness of one source's data, as reported by /v1/status.
+type sourceStatus struct {
+	Source             string `json:"source"`
+	Freshness          string `json:"freshness"`
+	LastSuccessfulSync string `json:"last_successful_sync,omitempty"`
+	AgeSeconds         *int   `json:"age_seconds,omitempty"`
+	EventCount         int    `json:"event_count"`
+	LastAttempt        string `json:"last_attempt,omitempty"`
+	LastError          string `json:"last_error,omitempty"`
+	LastErrorAt        string `json:"last_error_at,omitempty"`
+}
+
+// healthzHandler reports that the process is up, without touching storage.
+func (app *application) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	env := envelope{
+		"status": "available",
+		"system_info": map[string]string{
+			"environment": app.config.env,
+			"version":     version,
+		},
+	}
+
+	err := app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readyzHandler reports whether storage can be read, so that traffic is only routed to
+// instances that can serve it.
+func (app *application) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := app.dbSyncStates.GetAll()
+	if err != nil {
+		app.logError(r, err)
+		app.errorResponse(w, r, http.StatusServiceUnavailable, "storage is unreachable")
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"status": "ready"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// statusHandler reports every source's last sync and whether its data is older than the
+// configured maximum age. The overall status is "stale" as soon as one source isn't fresh.
+func (app *application) statusHandler(w http.ResponseWriter, r *http.Request) {
+	sourceSyncs, err := app.dbSyncStates.GetAll()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	sourceStatuses := sourceStatusesFor(sourceSyncs, allSources, app.config.status.maxAge, time.Now())
+
+	status := freshnessFresh
+	for _, s := range sourceStatuses {
+		if s.Freshness != freshnessFresh {
+			status = freshnessStale
+		}
+	}
+
+	env := envelope{
+		"status":          status,
+		"max_age_seconds": int(app.config.status.maxAge.Seconds()),
+		"sources":         sourceStatuses,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// sourceStatusesFor returns the freshness of every source, in the order of sources. A source
+// without a recorded sync has never been scraped successfully.
+func sourceStatusesFor(sourceSyncs []SourceSync, sources []string, maxAge time.Duration, now time.Time) []sourceStatus {
+	bySource := make(map[string]SourceSync)
+	for _, sourceSync := range sourceSyncs {
+		bySource[sourceSync.Source] = sourceSync
+	}
+
+	sourceStatuses := make([]sourceStatus, 0, len(sources))
+	for _, source := range sources {
+		sourceSync := bySource[source]
+
+		s := sourceStatus{
+			Source:             source,
+			Freshness:          freshnessNever,
+			LastSuccessfulSync: sourceSync.LastSuccessfulSync,
+			EventCount:         sourceSync.EventCount,
+			LastAttempt:        sourceSync.LastAttempt,
+			LastError:          sourceSync.LastError,
+			LastErrorAt:        sourceSync.LastErrorAt,
+		}
+
+		age, err := syncAge(sourceSync.LastSuccessfulSync, now)
+		if err == nil {
+			ageSeconds := int(age.Seconds())
+			s.AgeSeconds = &ageSeconds
+			s.Freshness = freshnessFresh
+			if age > maxAge {
+				s.Freshness = freshnessStale
+			}
+		}
+
+		sourceStatuses = append(sourceStatuses, s)
+	}
+
+	return sourceStatuses
+}
+
+func syncAge(lastSuccessfulSync string, now time.Time) (time.Duration, error) {
+	if lastSuccessfulSync == "" {
+		return 0, errors.New("never synced")
+	}
+
+	t, err := time.Parse(time.RFC3339, lastSuccessfulSync)
+	if err != nil {
+		return 0, err
+	}
+
+	return max(now.Sub(t), 0), nil
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestSourceStatusesFor tests the freshness verdict of each source
+func TestSourceStatusesFor(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	sourceSyncs := []SourceSync{
+		{Source: sourceWynn, LastSuccessfulSync: "2026-10-19T06:00:00Z", EventCount: 12},
+		{Source: sourceZouk, LastSuccessfulSync: "2026-10-17T06:00:00Z", EventCount: 4, LastAttempt: "2026-10-19T06:00:00Z", LastError: "error scraping: Not Found", LastErrorAt: "2026-10-19T06:00:00Z"},
+		{Source: sourceLiv, LastSuccessfulSync: "yesterday"},
+	}
+
+	tests := []struct {
+		source             string
+		expectedFreshness  string
+		expectedAgeSeconds int
+		expectedLastError  string
+	}{
+		{source: sourceWynn, expectedFreshness: freshnessFresh, expectedAgeSeconds: 6 * 60 * 60},
+		{source: sourceZouk, expectedFreshness: freshnessStale, expectedAgeSeconds: 54 * 60 * 60, expectedLastError: "error scraping: Not Found"},
+		{source: sourceTaoGroupHospitality, expectedFreshness: freshnessNever},
+		{source: sourceLiv, expectedFreshness: freshnessNever},
+	}
+
+	sourceStatuses := sourceStatusesFor(sourceSyncs, []string{sourceWynn, sourceZouk, sourceTaoGroupHospitality, sourceLiv}, 36*time.Hour, now)
+	if len(sourceStatuses) != len(tests) {
+		t.Fatalf("Expected %d sources, got %d", len(tests), len(sourceStatuses))
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			s := sourceStatuses[i]
+
+			if s.Source != tt.source {
+				t.Fatalf("Expected source '%s', got '%s'", tt.source, s.Source)
+			}
+			if s.Freshness != tt.expectedFreshness {
+				t.Errorf("Expected freshness '%s', got '%s'", tt.expectedFreshness, s.Freshness)
+			}
+			if tt.expectedFreshness == freshnessNever && s.AgeSeconds != nil {
+				t.Errorf("Expected no age, got %d", *s.AgeSeconds)
+			}
+			if tt.expectedFreshness != freshnessNever && (s.AgeSeconds == nil || *s.AgeSeconds != tt.expectedAgeSeconds) {
+				t.Errorf("Expected age %d seconds, got %v", tt.expectedAgeSeconds, s.AgeSeconds)
+			}
+			if s.LastError != tt.expectedLastError {
+				t.Errorf("Expected last error '%s', got '%s'", tt.expectedLastError, s.LastError)
+			}
+		})
+	}
+}
+
+// TestStatusHandler tests the overall verdict of /v1/status
+func TestStatusHandler(t *testing.T) {
+	recent := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name           string
+		sourceSyncs    []SourceSync
+		expectedStatus string
+	}{
+		{
+			name: "Every source synced recently",
+			sourceSyncs: []SourceSync{
+				{Source: sourceWynn, LastSuccessfulSync: recent},
+				{Source: sourceZouk, LastSuccessfulSync: recent},
+				{Source: sourceTaoGroupHospitality, LastSuccessfulSync: recent},
+				{Source: sourceLiv, LastSuccessfulSync: recent},
+			},
+			expectedStatus: freshnessFresh,
+		},
+		{
+			name: "One source never synced",
+			sourceSyncs: []SourceSync{
+				{Source: sourceWynn, LastSuccessfulSync: recent},
+				{Source: sourceZouk, LastSuccessfulSync: recent},
+				{Source: sourceTaoGroupHospitality, LastSuccessfulSync: recent},
+			},
+			expectedStatus: freshnessStale,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, nil)
+			app.config.status.maxAge = 36 * time.Hour
+			app.config.apiKeys.require = true
+			app.dbSyncStates = &mockSyncStateModel{sourceSyncs: tt.sourceSyncs}
+
+			rr := get(t, app, "/v1/status")
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+			}
+
+			var response struct {
+				Status        string         `json:"status"`
+				MaxAgeSeconds int            `json:"max_age_seconds"`
+				Sources       []sourceStatus `json:"sources"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+
+			if response.Status != tt.expectedStatus {
+				t.Errorf("Expected status '%s', got '%s'", tt.expectedStatus, response.Status)
+			}
+			if response.MaxAgeSeconds != 36*60*60 {
+				t.Errorf("Expected max age %d, got %d", 36*60*60, response.MaxAgeSeconds)
+			}
+			if len(response.Sources) != len(allSources) {
+				t.Errorf("Expected every source to be reported, got %d", len(response.Sources))
+			}
+		})
+	}
+}
+
+// TestProbes tests /healthz and /readyz
+func TestProbes(t *testing.T) {
+	tests := []struct {
+		name               string
+		path               string
+		storageErr         error
+		expectedStatusCode int
+	}{
+		{name: "Healthz", path: "/healthz", expectedStatusCode: http.StatusOK},
+		{name: "Healthz ignores storage", path: "/healthz", storageErr: errors.New("firestore unavailable"), expectedStatusCode: http.StatusOK},
+		{name: "Readyz", path: "/readyz", expectedStatusCode: http.StatusOK},
+		{name: "Readyz with storage down", path: "/readyz", storageErr: errors.New("firestore unavailable"), expectedStatusCode: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, nil)
+			app.config.apiKeys.require = true
+			app.dbSyncStates = &mockSyncStateModel{err: tt.storageErr}
+
+			rr := get(t, app, tt.path)
+			if rr.Code != tt.expectedStatusCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatusCode, rr.Code)
+			}
+		})
+	}
+}
+
+// TestSourceSyncsAfterRun tests the sync states recorded for synced and failed sources
+func TestSourceSyncsAfterRun(t *testing.T) {
+	previous := []SourceSync{
+		{Source: sourceWynn, LastSuccessfulSync: "2026-10-18T06:00:00Z", EventCount: 2, Venues: []string{"xs nightclub"}, LastError: "old error", LastErrorAt: "2026-10-10T06:00:00Z", CountHistory: []int{2, 2}},
+		{Source: sourceZouk, LastSuccessfulSync: "2026-10-18T06:00:00Z", EventCount: 3, Venues: []string{"zouk nightclub"}, CountHistory: []int{3}},
+	}
+	edmEvents := []EdmEvent{
+		{ClubName: "xs nightclub", Source: sourceWynn},
+		{ClubName: "zouk nightclub", Source: sourceZouk},
+	}
+	scrapeErrors := map[string]error{
+		sourceZouk: errors.New("error scraping: Not Found"),
+		sourceLiv:  errors.New("error scraping: connection refused"),
+	}
+
+	sourceSyncs := sourceSyncsAfterRun(previous, edmEvents, scrapeErrors, nil, allSources, "2026-10-19T06:00:00Z")
+
+	bySource := make(map[string]SourceSync)
+	for _, sourceSync := range sourceSyncs {
+		bySource[sourceSync.Source] = sourceSync
+	}
+
+	wynn := bySource[sourceWynn]
+	if wynn.LastSuccessfulSync != "2026-10-19T06:00:00Z" || wynn.EventCount != 1 {
+		t.Errorf("Expected wynn to sync 1 event, got %+v", wynn)
+	}
+	if fmt.Sprint(wynn.CountHistory) != "[2 2 1]" {
+		t.Errorf("Expected wynn to add its count to its history, got %v", wynn.CountHistory)
+	}
+	if wynn.LastError != "old error" {
+		t.Errorf("Expected wynn to keep its last error, got '%s'", wynn.LastError)
+	}
+
+	zouk := bySource[sourceZouk]
+	if zouk.LastSuccessfulSync != "2026-10-18T06:00:00Z" || zouk.EventCount != 3 {
+		t.Errorf("Expected zouk to keep its previous sync, got %+v", zouk)
+	}
+	if fmt.Sprint(zouk.CountHistory) != "[3]" {
+		t.Errorf("Expected zouk to keep its history, got %v", zouk.CountHistory)
+	}
+	if zouk.LastError != "error scraping: Not Found" || zouk.LastErrorAt != "2026-10-19T06:00:00Z" || zouk.LastAttempt != "2026-10-19T06:00:00Z" {
+		t.Errorf("Expected zouk to record the failed attempt, got %+v", zouk)
+	}
+
+	liv := bySource[sourceLiv]
+	if liv.LastSuccessfulSync != "" || liv.LastError == "" {
+		t.Errorf("Expected liv to have never synced, got %+v", liv)
+	}
+
+	taoGroup := bySource[sourceTaoGroupHospitality]
+	if taoGroup.LastSuccessfulSync != "2026-10-19T06:00:00Z" || taoGroup.LastError != "" {
+		t.Errorf("Expected an empty tao group sync to succeed, got %+v", taoGroup)
+	}
+}
+
+// TestAddEdmEventsToFirestore_UnreachableSource tests that a source that can't be scraped
+// keeps its stored events
+func TestAddEdmEventsToFirestore_UnreachableSource(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	stored := []EdmEvent{
+		{Id: "id-1", ClubName: "xs nightclub", ArtistName: "tiësto", EventDate: "2099-11-20T00:00:00Z", TicketUrl: "https://www.wynnsocial.com/events/20991120", Source: sourceWynn, Sequence: 2},
+		{Id: "id-2", ClubName: "omnia", ArtistName: "martin garrix", EventDate: "2099-11-21T00:00:00Z", TicketUrl: "https://taogroup.com/events/1", Source: sourceTaoGroupHospitality},
+	}
+
+	app := newTestApplication(t, stored)
+	syncStates := app.dbSyncStates.(*mockSyncStateModel)
+	syncStates.sourceSyncs = []SourceSync{{Source: sourceWynn, LastSuccessfulSync: "2026-10-18T06:00:00Z", EventCount: 1, Venues: []string{"xs nightclub"}}}
+
+	app.scrapingURLs = ScrapingURLs{
+		Wynn:                down.URL,
+		Zouk:                down.URL + "?date=",
+		TaoGroupHospitality: down.URL + "?",
+		Liv:                 down.URL + "?date=",
+	}
+	err := app.addEdmEventsToFirestore(io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	model := app.dbSnippets.(*mockSnippetModel)
+	if len(model.edmEvents) != 2 {
+		t.Fatalf("Expected the stored events to be kept, got %d", len(model.edmEvents))
+	}
+	for _, edmEvent := range model.edmEvents {
+		if edmEvent.Id == "id-1" && edmEvent.Sequence != 2 {
+			t.Errorf("Expected the kept event to be unchanged, got sequence %d", edmEvent.Sequence)
+		}
+	}
+
+	for _, sourceSync := range syncStates.sourceSyncs {
+		if sourceSync.LastError == "" {
+			t.Errorf("Expected %s to record its error", sourceSync.Source)
+		}
+		if sourceSync.Source == sourceWynn && sourceSync.LastSuccessfulSync != "2026-10-18T06:00:00Z" {
+			t.Errorf("Expected wynn's last successful sync to stay, got '%s'", sourceSync.LastSuccessfulSync)
+		}
+	}
+}
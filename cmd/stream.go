@@ -0,0 +1,343 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The message types sent on /v1/stream. stream.reset tells a resuming client that the
+// messages it missed are no longer buffered and it should reload the events instead.
+const (
+	streamEventCreated = "event.created"
+	streamEventUpdated = "event.updated"
+	streamEventRemoved = "event.removed"
+	streamReset        = "stream.reset"
+)
+
+var streamEventTypes = []string{streamEventCreated, streamEventUpdated, streamEventRemoved}
+
+const (
+	// Messages kept for clients resuming with Last-Event-ID.
+	streamReplayBufferSize = 1000
+	// Messages queued for one client before it's disconnected as too slow.
+	streamSubscriberBuffer = 256
+	// Comments sent on idle connections so that proxies don't close them.
+	streamHeartbeatInterval = 15 * time.Second
+	// How often the stored sync state is checked for syncs made by the scrape job.
+	syncWatchInterval = 30 * time.Second
+)
+
+// changeMessage is one change to an event, numbered in the order it was published.
+type changeMessage struct {
+	ID       uint64
+	Type     string
+	EdmEvent EdmEvent
+}
+
+// changeHub fans sync changes out to the connected /v1/stream clients and keeps the most
+// recent ones so that reconnecting clients can catch up.
+type changeHub struct {
+	mu          sync.Mutex
+	lastID      uint64
+	buffer      []changeMessage
+	subscribers map[chan changeMessage]struct{}
+	closed      bool
+}
+
+// newChangeHub numbers messages from the current time in milliseconds, so that a client
+// resuming against a restarted server has an id below any the new server issues.
+func newChangeHub(now time.Time) *changeHub {
+	return &changeHub{
+		lastID:      uint64(now.UnixMilli()),
+		subscribers: make(map[chan changeMessage]struct{}),
+	}
+}
+
+// publish numbers the changes and sends them to every subscriber. A subscriber whose queue
+// is full is dropped, its client reconnects with Last-Event-ID and replays what it missed.
+func (h *changeHub) publish(changes syncChanges) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, change := range []struct {
+		messageType string
+		edmEvents   []EdmEvent
+	}{
+		{streamEventCreated, changes.Created},
+		{streamEventUpdated, changes.Updated},
+		{streamEventRemoved, changes.Removed},
+	} {
+		for _, edmEvent := range change.edmEvents {
+			h.lastID++
+			message := changeMessage{ID: h.lastID, Type: change.messageType, EdmEvent: edmEvent}
+
+			h.buffer = append(h.buffer, message)
+			if len(h.buffer) > streamReplayBufferSize {
+				h.buffer = h.buffer[len(h.buffer)-streamReplayBufferSize:]
+			}
+
+			for ch := range h.subscribers {
+				select {
+				case ch <- message:
+				default:
+					delete(h.subscribers, ch)
+					close(ch)
+				}
+			}
+		}
+	}
+}
+
+// subscribe registers a new subscriber. When lastEventID is set, the buffered messages after
+// it are returned for replay, and complete is false if some of them were already dropped or
+// the id was issued by another server.
+func (h *changeHub) subscribe(lastEventID uint64, resuming bool) (ch chan changeMessage, replay []changeMessage, complete bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch = make(chan changeMessage, streamSubscriberBuffer)
+	if h.closed {
+		close(ch)
+		return ch, nil, true
+	}
+	h.subscribers[ch] = struct{}{}
+
+	if !resuming {
+		return ch, nil, true
+	}
+	if lastEventID > h.lastID {
+		return ch, nil, false
+	}
+
+	complete = lastEventID == h.lastID || (len(h.buffer) > 0 && h.buffer[0].ID <= lastEventID+1)
+	for _, message := range h.buffer {
+		if message.ID > lastEventID {
+			replay = append(replay, message)
+		}
+	}
+
+	return ch, replay, complete
+}
+
+func (h *changeHub) unsubscribe(ch chan changeMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscribers[ch]; ok {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+// close disconnects every subscriber, and those subscribing afterwards, so that open streams
+// end when the server shuts down.
+func (h *changeHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for ch := range h.subscribers {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+// diffEdmEvents compares two snapshots of the stored events. Ids are kept across syncs, and
+// Sequence only increases when an event changed, so they are enough to tell the changes apart.
+func diffEdmEvents(previous []EdmEvent, current []EdmEvent) syncChanges {
+	var changes syncChanges
+
+	previousByID := make(map[string]EdmEvent, len(previous))
+	for _, edmEvent := range previous {
+		previousByID[edmEvent.Id] = edmEvent
+	}
+
+	currentIDs := make(map[string]bool, len(current))
+	for _, edmEvent := range current {
+		currentIDs[edmEvent.Id] = true
+
+		stored, ok := previousByID[edmEvent.Id]
+		switch {
+		case !ok:
+			changes.Created = append(changes.Created, edmEvent)
+		case stored.Sequence != edmEvent.Sequence:
+			changes.Updated = append(changes.Updated, edmEvent)
+		}
+	}
+
+	for _, edmEvent := range previous {
+		if !currentIDs[edmEvent.Id] {
+			changes.Removed = append(changes.Removed, edmEvent)
+		}
+	}
+
+	return changes
+}
+
+// syncWatcher publishes the changes of every sync it notices, whether the sync ran in this
+// process or in the scrape job, and rebuilds the search index for it.
+type syncWatcher struct {
+	version   string
+	edmEvents []EdmEvent
+	loaded    bool
+}
+
+// checkSyncs compares the stored sync state against the last one seen and publishes the
+// changes to the events if it moved on. The first check only records the current state.
+func (app *application) checkSyncs(watcher *syncWatcher) error {
+	sourceSyncs, err := app.dbSyncStates.GetAll()
+	if err != nil {
+		return err
+	}
+
+	version := syncVersion(sourceSyncs)
+	if watcher.loaded && version == watcher.version {
+		return nil
+	}
+
+	edmEvents, err := app.dbSnippets.GetAll()
+	if err != nil {
+		return err
+	}
+
+	if watcher.loaded {
+		app.changes.publish(diffEdmEvents(watcher.edmEvents, edmEvents))
+	}
+	app.searchIndex.rebuild(version, edmEvents)
+
+	watcher.version = version
+	watcher.edmEvents = edmEvents
+	watcher.loaded = true
+	return nil
+}
+
+// watchSyncs checks for new syncs every time tick fires, and right away when a run of this
+// process finishes.
+func (app *application) watchSyncs(tick <-chan time.Time) {
+	watcher := &syncWatcher{}
+
+	for {
+		err := app.checkSyncs(watcher)
+		if err != nil {
+			app.logger.Printf("failed to check for new syncs: %v", err)
+		}
+
+		select {
+		case <-tick:
+		case <-app.syncNotify:
+		}
+	}
+}
+
+// notifySync wakes up the sync watcher without waiting for its next tick.
+func (app *application) notifySync() {
+	select {
+	case app.syncNotify <- struct{}{}:
+	default:
+	}
+}
+
+// streamHandler sends the changes of every sync as Server-Sent Events. The artist, venue,
+// from and to filters apply as they do to /v1/events, types limits the kinds of change.
+// Clients resume with the Last-Event-ID header, or the last_event_id parameter for the
+// first connection of an EventSource.
+func (app *application) streamHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	v := newValidator()
+
+	filters := app.readEventFilters(qs, v)
+
+	types := streamEventTypes
+	if value := app.readString(qs, "types", ""); value != "" {
+		types = strings.Split(value, ",")
+		for _, t := range types {
+			v.Check(slices.Contains(streamEventTypes, t), "types", fmt.Sprintf("must be a comma separated list of %s", strings.Join(streamEventTypes, ", ")))
+		}
+	}
+
+	lastEventIDValue := r.Header.Get("Last-Event-ID")
+	if lastEventIDValue == "" {
+		lastEventIDValue = app.readString(qs, "last_event_id", "")
+	}
+	var lastEventID uint64
+	if lastEventIDValue != "" {
+		var err error
+		lastEventID, err = strconv.ParseUint(lastEventIDValue, 10, 64)
+		v.Check(err == nil, "last_event_id", "must be a positive integer")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	ch, replay, complete := app.changes.subscribe(lastEventID, lastEventIDValue != "")
+	defer app.changes.unsubscribe(ch)
+
+	rc := http.NewResponseController(w)
+	// Streams stay open for as long as the client wants, past the server's write timeout.
+	rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprint(w, "retry: 5000\n\n")
+	if !complete {
+		fmt.Fprintf(w, "event: %s\ndata: {}\n\n", streamReset)
+	}
+
+	send := func(message changeMessage) error {
+		if !slices.Contains(types, message.Type) || !filters.matches(message.EdmEvent) {
+			return nil
+		}
+
+		data, err := json.Marshal(envelope{"event": message.EdmEvent})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", message.ID, message.Type, data)
+		return err
+	}
+
+	for _, message := range replay {
+		if err := send(message); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case message, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := send(message); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
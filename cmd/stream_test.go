@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestDiffEdmEvents tests the changes found between two snapshots of the stored events
+func TestDiffEdmEvents(t *testing.T) {
+	previous := []EdmEvent{
+		{Id: "kept", Sequence: 1},
+		{Id: "changed", Sequence: 1},
+		{Id: "removed"},
+	}
+	current := []EdmEvent{
+		{Id: "kept", Sequence: 1},
+		{Id: "changed", Sequence: 2},
+		{Id: "created"},
+	}
+
+	changes := diffEdmEvents(previous, current)
+
+	if len(changes.Created) != 1 || changes.Created[0].Id != "created" {
+		t.Errorf("Expected 'created' to be created, got %+v", changes.Created)
+	}
+	if len(changes.Updated) != 1 || changes.Updated[0].Id != "changed" {
+		t.Errorf("Expected 'changed' to be updated, got %+v", changes.Updated)
+	}
+	if len(changes.Removed) != 1 || changes.Removed[0].Id != "removed" {
+		t.Errorf("Expected 'removed' to be removed, got %+v", changes.Removed)
+	}
+}
+
+// TestChangeHub_Subscribe tests what a resuming subscriber is replayed
+func TestChangeHub_Subscribe(t *testing.T) {
+	hub := newChangeHub(time.UnixMilli(1000))
+	for i := 0; i < streamReplayBufferSize+10; i++ {
+		hub.publish(syncChanges{Created: []EdmEvent{{Id: strconv.Itoa(i)}}})
+	}
+	lastID := hub.lastID
+
+	tests := []struct {
+		name             string
+		lastEventID      uint64
+		resuming         bool
+		expectedReplay   int
+		expectedComplete bool
+	}{
+		{name: "New subscriber", expectedReplay: 0, expectedComplete: true},
+		{name: "Up to date", lastEventID: lastID, resuming: true, expectedReplay: 0, expectedComplete: true},
+		{name: "A few behind", lastEventID: lastID - 3, resuming: true, expectedReplay: 3, expectedComplete: true},
+		{name: "Oldest buffered", lastEventID: lastID - streamReplayBufferSize, resuming: true, expectedReplay: streamReplayBufferSize, expectedComplete: true},
+		{name: "Behind the buffer", lastEventID: 1000, resuming: true, expectedReplay: streamReplayBufferSize, expectedComplete: false},
+		{name: "From another server", lastEventID: lastID + 1, resuming: true, expectedReplay: 0, expectedComplete: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch, replay, complete := hub.subscribe(tt.lastEventID, tt.resuming)
+			defer hub.unsubscribe(ch)
+
+			if len(replay) != tt.expectedReplay {
+				t.Errorf("Expected %d messages replayed, got %d", tt.expectedReplay, len(replay))
+			}
+			if complete != tt.expectedComplete {
+				t.Errorf("Expected complete %t, got %t", tt.expectedComplete, complete)
+			}
+		})
+	}
+}
+
+// TestCheckSyncs tests that a new sync publishes its changes
+func TestCheckSyncs(t *testing.T) {
+	app := newTestApplication(t, []EdmEvent{{Id: "id-1", ClubName: "omnia"}})
+	syncStates := app.dbSyncStates.(*mockSyncStateModel)
+	syncStates.sourceSyncs = []SourceSync{{Source: sourceTaoGroupHospitality, LastSuccessfulSync: "2026-10-18T06:00:00Z"}}
+
+	ch, _, _ := app.changes.subscribe(0, false)
+	defer app.changes.unsubscribe(ch)
+
+	watcher := &syncWatcher{}
+	if err := app.checkSyncs(watcher); err != nil {
+		t.Fatal(err)
+	}
+	if len(ch) != 0 {
+		t.Fatalf("Expected the first check to publish nothing, got %d messages", len(ch))
+	}
+
+	app.dbSnippets.(*mockSnippetModel).edmEvents = []EdmEvent{{Id: "id-2", ClubName: "omnia"}}
+	if err := app.checkSyncs(watcher); err != nil {
+		t.Fatal(err)
+	}
+	if len(ch) != 0 {
+		t.Fatalf("Expected nothing to be published before the sync is recorded, got %d messages", len(ch))
+	}
+
+	syncStates.sourceSyncs[0].LastSuccessfulSync = "2026-10-19T06:00:00Z"
+	if err := app.checkSyncs(watcher); err != nil {
+		t.Fatal(err)
+	}
+	if len(ch) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(ch))
+	}
+	if message := <-ch; message.Type != streamEventCreated || message.EdmEvent.Id != "id-2" {
+		t.Errorf("Expected id-2 to be created, got %s of %s", message.Type, message.EdmEvent.Id)
+	}
+	if message := <-ch; message.Type != streamEventRemoved || message.EdmEvent.Id != "id-1" {
+		t.Errorf("Expected id-1 to be removed, got %s of %s", message.Type, message.EdmEvent.Id)
+	}
+}
+
+// readStreamMessage reads the next message with an event type from the stream.
+func readStreamMessage(t *testing.T, scanner *bufio.Scanner) (id string, messageType string, data string) {
+	t.Helper()
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			messageType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		case line == "" && messageType != "":
+			return id, messageType, data
+		}
+	}
+
+	t.Fatalf("Expected a message, the stream ended: %v", scanner.Err())
+	return "", "", ""
+}
+
+func openStream(t *testing.T, ts *httptest.Server, path string, lastEventID string) *bufio.Scanner {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, res.StatusCode)
+	}
+	if res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("Expected an event stream, got '%s'", res.Header.Get("Content-Type"))
+	}
+
+	return bufio.NewScanner(res.Body)
+}
+
+// waitForSubscribers blocks until the hub has n subscribers.
+func waitForSubscribers(t *testing.T, hub *changeHub, n int) {
+	t.Helper()
+
+	for i := 0; i < 200; i++ {
+		hub.mu.Lock()
+		count := len(hub.subscribers)
+		hub.mu.Unlock()
+		if count == n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Expected %d stream subscribers", n)
+}
+
+// TestStreamHandler tests that filtered changes are streamed and can be resumed
+func TestStreamHandler(t *testing.T) {
+	app := newTestApplication(t, nil)
+	ts := httptest.NewServer(app.routes())
+	// Registered before the streams' cleanups so that they are closed first.
+	t.Cleanup(ts.Close)
+
+	scanner := openStream(t, ts, "/v1/stream?venue=omnia&types=event.created,event.removed", "")
+	waitForSubscribers(t, app.changes, 1)
+
+	app.changes.publish(syncChanges{
+		Created: []EdmEvent{{Id: "xs-1", ClubName: "xs nightclub"}, {Id: "omnia-1", ClubName: "omnia"}},
+		Updated: []EdmEvent{{Id: "omnia-2", ClubName: "omnia"}},
+		Removed: []EdmEvent{{Id: "omnia-3", ClubName: "omnia"}},
+	})
+
+	id, messageType, data := readStreamMessage(t, scanner)
+	if messageType != streamEventCreated || !strings.Contains(data, `"id":"omnia-1"`) {
+		t.Errorf("Expected omnia-1 to be created, got %s %s", messageType, data)
+	}
+
+	_, messageType, data = readStreamMessage(t, scanner)
+	if messageType != streamEventRemoved || !strings.Contains(data, `"id":"omnia-3"`) {
+		t.Errorf("Expected omnia-3 to be removed, got %s %s", messageType, data)
+	}
+
+	resumed := openStream(t, ts, "/v1/stream", id)
+	_, messageType, data = readStreamMessage(t, resumed)
+	if messageType != streamEventUpdated || !strings.Contains(data, `"id":"omnia-2"`) {
+		t.Errorf("Expected the resumed stream to replay omnia-2, got %s %s", messageType, data)
+	}
+
+	reset := openStream(t, ts, "/v1/stream", "1")
+	if _, messageType, _ = readStreamMessage(t, reset); messageType != streamReset {
+		t.Errorf("Expected a stream reset for an unknown id, got %s", messageType)
+	}
+}
+
+// TestStreamHandler_Negative tests the stream's parameter validation
+func TestStreamHandler_Negative(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		headers map[string]string
+	}{
+		{name: "Unknown type", path: "/v1/stream?types=event.created,event.deleted"},
+		{name: "Invalid date", path: "/v1/stream?from=tomorrow"},
+		{name: "Invalid last event id", path: "/v1/stream?last_event_id=abc"},
+		{name: "Invalid Last-Event-ID", path: "/v1/stream", headers: map[string]string{"Last-Event-ID": "-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, nil)
+
+			rr := getWithHeaders(t, app, tt.path, tt.headers)
+			if rr.Code != http.StatusUnprocessableEntity {
+				t.Errorf("Expected status %d, got %d", http.StatusUnprocessableEntity, rr.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// syncChanges holds what a scrape changed compared to the events already stored. SoldOut
+// lists the updated events that sold out since the previous scrape.
+type syncChanges struct {
+	Created []EdmEvent
+	Updated []EdmEvent
+	Removed []EdmEvent
+	SoldOut []EdmEvent
+
+	// Anomalies are the sources whose events were kept because the scrape looked broken.
+	Anomalies []sourceAnomaly
+}
+
+// edmEventKey identifies the same event across scrapes. Ids are generated fresh on every
+// scrape, so we match on the venue, the night and the ticket link instead.
+func edmEventKey(edmEvent EdmEvent) string {
+	return strings.Join([]string{
+		strings.TrimSpace(strings.ToLower(edmEvent.ClubName)),
+		edmEvent.EventDate,
+		strings.TrimSpace(edmEvent.TicketUrl),
+	}, "|")
+}
+
+// hasEdmEventChanged reports whether the scraped version of an event differs from the stored
+// one in anything a calendar client would show, or in its availability. Only a change between
+// two known availabilities counts: events stored before availability was scraped, or whose
+// badge a scrape missed, would otherwise all change at once.
+func hasEdmEventChanged(stored EdmEvent, scraped EdmEvent) bool {
+	return stored.ArtistName != scraped.ArtistName ||
+		stored.ArtistImageUrl != scraped.ArtistImageUrl ||
+		hasAvailabilityChanged(stored, scraped)
+}
+
+func hasAvailabilityChanged(stored EdmEvent, scraped EdmEvent) bool {
+	storedAvailability, scrapedAvailability := eventAvailability(stored), eventAvailability(scraped)
+	if storedAvailability == availabilityUnknown || scrapedAvailability == availabilityUnknown {
+		return false
+	}
+	return storedAvailability != scrapedAvailability
+}
+
+// mergeEdmEvents carries the Id, Sequence, LastModified and FirstSeen of already stored events
+// over to the freshly scraped ones, so that calendar UIDs stay stable between runs, SEQUENCE
+// only increases when an event actually changed and FirstSeen records when a show was announced.
+func mergeEdmEvents(existing []EdmEvent, scraped []EdmEvent, now time.Time) ([]EdmEvent, syncChanges) {
+	var changes syncChanges
+	syncedAt := now.UTC().Format(time.RFC3339)
+
+	storedEvents := make(map[string]EdmEvent, len(existing))
+	for _, edmEvent := range existing {
+		storedEvents[edmEventKey(edmEvent)] = edmEvent
+	}
+
+	seen := make(map[string]bool, len(scraped))
+	merged := make([]EdmEvent, 0, len(scraped))
+
+	for _, edmEvent := range scraped {
+		key := edmEventKey(edmEvent)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		stored, ok := storedEvents[key]
+		if !ok {
+			edmEvent.Sequence = 0
+			edmEvent.LastModified = syncedAt
+			edmEvent.FirstSeen = syncedAt
+			changes.Created = append(changes.Created, edmEvent)
+			merged = append(merged, edmEvent)
+			continue
+		}
+
+		edmEvent.Id = stored.Id
+		edmEvent.Sequence = stored.Sequence
+		edmEvent.LastModified = stored.LastModified
+		edmEvent.FirstSeen = stored.FirstSeen
+
+		if hasEdmEventChanged(stored, edmEvent) {
+			edmEvent.Sequence++
+			edmEvent.LastModified = syncedAt
+			changes.Updated = append(changes.Updated, edmEvent)
+
+			if eventAvailability(edmEvent) == availabilitySoldOut && eventAvailability(stored) != availabilitySoldOut {
+				changes.SoldOut = append(changes.SoldOut, edmEvent)
+			}
+		}
+
+		merged = append(merged, edmEvent)
+	}
+
+	for _, edmEvent := range existing {
+		if !seen[edmEventKey(edmEvent)] {
+			changes.Removed = append(changes.Removed, edmEvent)
+		}
+	}
+
+	return merged, changes
+}
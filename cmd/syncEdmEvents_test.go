@@ -0,0 +1,181 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestMergeEdmEvents tests that stored ids and sequences survive a new scrape
+func TestMergeEdmEvents(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	stored := EdmEvent{
+		Id:           "stored-id",
+		ClubName:     "xs nightclub",
+		ArtistName:   "tba",
+		EventDate:    "2026-11-20T00:00:00Z",
+		TicketUrl:    "https://www.wynnsocial.com/events/20261120",
+		Sequence:     2,
+		LastModified: "2026-10-01T00:00:00Z",
+	}
+
+	tests := []struct {
+		name             string
+		existing         []EdmEvent
+		scraped          []EdmEvent
+		expectedMerged   int
+		expectedCreated  int
+		expectedUpdated  int
+		expectedRemoved  int
+		expectedSoldOut  int
+		expectedId       string
+		expectedSequence int
+		expectedModified string
+		// expectedAvailability is only checked when set.
+		expectedAvailability string
+	}{
+		{
+			name:             "New event gets sequence zero",
+			scraped:          []EdmEvent{{Id: "new-id", ClubName: "xs nightclub", ArtistName: "tiësto", EventDate: "2026-11-20T00:00:00Z"}},
+			expectedMerged:   1,
+			expectedCreated:  1,
+			expectedId:       "new-id",
+			expectedSequence: 0,
+			expectedModified: "2026-10-19T12:00:00Z",
+		},
+		{
+			name:             "Unchanged event keeps its id and sequence",
+			existing:         []EdmEvent{stored},
+			scraped:          []EdmEvent{{Id: "fresh-guid", ClubName: stored.ClubName, ArtistName: stored.ArtistName, EventDate: stored.EventDate, TicketUrl: stored.TicketUrl}},
+			expectedMerged:   1,
+			expectedId:       "stored-id",
+			expectedSequence: 2,
+			expectedModified: "2026-10-01T00:00:00Z",
+		},
+		{
+			name:             "Changed artist bumps the sequence",
+			existing:         []EdmEvent{stored},
+			scraped:          []EdmEvent{{Id: "fresh-guid", ClubName: stored.ClubName, ArtistName: "tiësto", EventDate: stored.EventDate, TicketUrl: stored.TicketUrl}},
+			expectedMerged:   1,
+			expectedUpdated:  1,
+			expectedId:       "stored-id",
+			expectedSequence: 3,
+			expectedModified: "2026-10-19T12:00:00Z",
+		},
+		{
+			name:             "Selling out bumps the sequence",
+			existing:         []EdmEvent{{Id: stored.Id, ClubName: stored.ClubName, ArtistName: stored.ArtistName, EventDate: stored.EventDate, TicketUrl: stored.TicketUrl, Sequence: 2, LastModified: stored.LastModified, Availability: availabilityOnSale}},
+			scraped:          []EdmEvent{{Id: "fresh-guid", ClubName: stored.ClubName, ArtistName: stored.ArtistName, EventDate: stored.EventDate, TicketUrl: stored.TicketUrl, Availability: availabilitySoldOut}},
+			expectedMerged:   1,
+			expectedUpdated:  1,
+			expectedSoldOut:  1,
+			expectedId:       "stored-id",
+			expectedSequence: 3,
+			expectedModified: "2026-10-19T12:00:00Z",
+		},
+		{
+			name:             "Back on sale isn't a sell-out",
+			existing:         []EdmEvent{{Id: stored.Id, ClubName: stored.ClubName, ArtistName: stored.ArtistName, EventDate: stored.EventDate, TicketUrl: stored.TicketUrl, Sequence: 2, LastModified: stored.LastModified, Availability: availabilitySoldOut}},
+			scraped:          []EdmEvent{{Id: "fresh-guid", ClubName: stored.ClubName, ArtistName: stored.ArtistName, EventDate: stored.EventDate, TicketUrl: stored.TicketUrl, Availability: availabilityOnSale}},
+			expectedMerged:   1,
+			expectedUpdated:  1,
+			expectedId:       "stored-id",
+			expectedSequence: 3,
+			expectedModified: "2026-10-19T12:00:00Z",
+		},
+		{
+			name:             "Unknown availability matches an event stored without one",
+			existing:         []EdmEvent{stored},
+			scraped:          []EdmEvent{{Id: "fresh-guid", ClubName: stored.ClubName, ArtistName: stored.ArtistName, EventDate: stored.EventDate, TicketUrl: stored.TicketUrl, Availability: availabilityUnknown}},
+			expectedMerged:   1,
+			expectedId:       "stored-id",
+			expectedSequence: 2,
+			expectedModified: "2026-10-01T00:00:00Z",
+		},
+		{
+			name:                 "First scraped availability of an event stored without one isn't a change",
+			existing:             []EdmEvent{stored},
+			scraped:              []EdmEvent{{Id: "fresh-guid", ClubName: stored.ClubName, ArtistName: stored.ArtistName, EventDate: stored.EventDate, TicketUrl: stored.TicketUrl, Availability: availabilityOnSale}},
+			expectedMerged:       1,
+			expectedId:           "stored-id",
+			expectedSequence:     2,
+			expectedModified:     "2026-10-01T00:00:00Z",
+			expectedAvailability: availabilityOnSale,
+		},
+		{
+			name:                 "First scraped availability of sold out isn't a sell-out",
+			existing:             []EdmEvent{stored},
+			scraped:              []EdmEvent{{Id: "fresh-guid", ClubName: stored.ClubName, ArtistName: stored.ArtistName, EventDate: stored.EventDate, TicketUrl: stored.TicketUrl, Availability: availabilitySoldOut}},
+			expectedMerged:       1,
+			expectedId:           "stored-id",
+			expectedSequence:     2,
+			expectedModified:     "2026-10-01T00:00:00Z",
+			expectedAvailability: availabilitySoldOut,
+		},
+		{
+			name:             "Availability a scrape missed isn't a change",
+			existing:         []EdmEvent{{Id: stored.Id, ClubName: stored.ClubName, ArtistName: stored.ArtistName, EventDate: stored.EventDate, TicketUrl: stored.TicketUrl, Sequence: 2, LastModified: stored.LastModified, Availability: availabilityLimited}},
+			scraped:          []EdmEvent{{Id: "fresh-guid", ClubName: stored.ClubName, ArtistName: stored.ArtistName, EventDate: stored.EventDate, TicketUrl: stored.TicketUrl, Availability: availabilityUnknown}},
+			expectedMerged:   1,
+			expectedId:       "stored-id",
+			expectedSequence: 2,
+			expectedModified: "2026-10-01T00:00:00Z",
+		},
+		{
+			name:            "Event missing from the scrape is removed",
+			existing:        []EdmEvent{stored},
+			scraped:         []EdmEvent{},
+			expectedMerged:  0,
+			expectedRemoved: 1,
+		},
+		{
+			name:     "Duplicate scraped events are merged once",
+			existing: []EdmEvent{},
+			scraped: []EdmEvent{
+				{Id: "a", ClubName: "omnia", ArtistName: "alesso", EventDate: "2026-11-21T00:00:00Z"},
+				{Id: "b", ClubName: "omnia", ArtistName: "alesso", EventDate: "2026-11-21T00:00:00Z"},
+			},
+			expectedMerged:   1,
+			expectedCreated:  1,
+			expectedId:       "a",
+			expectedModified: "2026-10-19T12:00:00Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, changes := mergeEdmEvents(tt.existing, tt.scraped, now)
+
+			if len(merged) != tt.expectedMerged {
+				t.Fatalf("Expected %d merged events, got %d", tt.expectedMerged, len(merged))
+			}
+			if len(changes.Created) != tt.expectedCreated {
+				t.Errorf("Expected %d created events, got %d", tt.expectedCreated, len(changes.Created))
+			}
+			if len(changes.Updated) != tt.expectedUpdated {
+				t.Errorf("Expected %d updated events, got %d", tt.expectedUpdated, len(changes.Updated))
+			}
+			if len(changes.Removed) != tt.expectedRemoved {
+				t.Errorf("Expected %d removed events, got %d", tt.expectedRemoved, len(changes.Removed))
+			}
+			if len(changes.SoldOut) != tt.expectedSoldOut {
+				t.Errorf("Expected %d sold out events, got %d", tt.expectedSoldOut, len(changes.SoldOut))
+			}
+
+			if len(merged) == 0 {
+				return
+			}
+			if merged[0].Id != tt.expectedId {
+				t.Errorf("Expected id '%s', got '%s'", tt.expectedId, merged[0].Id)
+			}
+			if merged[0].Sequence != tt.expectedSequence {
+				t.Errorf("Expected sequence %d, got %d", tt.expectedSequence, merged[0].Sequence)
+			}
+			if merged[0].LastModified != tt.expectedModified {
+				t.Errorf("Expected last modified '%s', got '%s'", tt.expectedModified, merged[0].LastModified)
+			}
+			if tt.expectedAvailability != "" && merged[0].Availability != tt.expectedAvailability {
+				t.Errorf("Expected availability '%s', got '%s'", tt.expectedAvailability, merged[0].Availability)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// SourceSync records the last successful sync of one scraped source, along with the last run
+// that tried, the last error it ran into, the event counts of its recent syncs and how many
+// runs in a row it has been flagged as an anomaly. The serve command uses it to answer
+// conditional requests and report freshness without reading the events collection.
+type SourceSync struct {
+	Source             string   `json:"source"`
+	LastSuccessfulSync string   `json:"last_successful_sync"`
+	EventCount         int      `json:"event_count"`
+	Venues             []string `json:"venues"`
+	LastAttempt        string   `json:"last_attempt,omitempty"`
+	LastError          string   `json:"last_error,omitempty"`
+	LastErrorAt        string   `json:"last_error_at,omitempty"`
+	CountHistory       []int    `json:"count_history,omitempty"`
+	FlaggedRuns        int      `json:"flagged_runs,omitempty"`
+}
+
+type SyncStateModelInterface interface {
+	GetAll() ([]SourceSync, error)
+	Upsert(sourceSyncs []SourceSync) error
+}
+
+// SyncStateModel stores one document per source, keyed by the source name.
+type SyncStateModel struct {
+	Client     *firestore.Client
+	Collection string
+}
+
+func (m *SyncStateModel) GetAll() ([]SourceSync, error) {
+	ctx := context.Background()
+	sourceSyncs := []SourceSync{}
+
+	iter := m.Client.Collection(m.Collection).Documents(ctx)
+	defer iter.Stop()
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate sync states: %v", err)
+		}
+
+		var sourceSync SourceSync
+		if err := doc.DataTo(&sourceSync); err != nil {
+			return nil, fmt.Errorf("failed to decode sync state %s: %v", doc.Ref.ID, err)
+		}
+		sourceSyncs = append(sourceSyncs, sourceSync)
+	}
+
+	sort.Slice(sourceSyncs, func(i, j int) bool {
+		return sourceSyncs[i].Source < sourceSyncs[j].Source
+	})
+
+	return sourceSyncs, nil
+}
+
+func (m *SyncStateModel) Upsert(sourceSyncs []SourceSync) error {
+	ctx := context.Background()
+	batch := m.Client.BulkWriter(ctx)
+
+	for _, sourceSync := range sourceSyncs {
+		docRef := m.Client.Collection(m.Collection).Doc(sourceSync.Source)
+		_, err := batch.Set(docRef, sourceSync)
+		if err != nil {
+			return fmt.Errorf("failed to queue sync state %s: %v", sourceSync.Source, err)
+		}
+	}
+
+	batch.End()
+	return nil
+}
+
+// sourceSyncsFor summarises the synced events per source.
+func sourceSyncsFor(edmEvents []EdmEvent, sources []string, syncedAt string) []SourceSync {
+	sourceSyncs := make([]SourceSync, 0, len(sources))
+
+	for _, source := range sources {
+		sourceSync := SourceSync{Source: source, LastSuccessfulSync: syncedAt, Venues: []string{}}
+		venues := make(map[string]bool)
+
+		for _, edmEvent := range edmEvents {
+			if edmEvent.Source != source {
+				continue
+			}
+			sourceSync.EventCount++
+			if edmEvent.ClubName != "" && !venues[edmEvent.ClubName] {
+				venues[edmEvent.ClubName] = true
+				sourceSync.Venues = append(sourceSync.Venues, edmEvent.ClubName)
+			}
+		}
+
+		sort.Strings(sourceSync.Venues)
+		sourceSyncs = append(sourceSyncs, sourceSync)
+	}
+
+	return sourceSyncs
+}
+
+// sourceSyncsAfterRun returns the sync states to record after a run. Sources that synced get a
+// fresh summary and add their count to their history, sources that failed to scrape or were
+// flagged as anomalies keep their previous summary with the error added, and both keep the
+// most recent error they ran into. Flagged sources count the runs in a row they were flagged,
+// and a source whose anomaly was accepted starts a new history from its new count.
+func sourceSyncsAfterRun(previous []SourceSync, edmEvents []EdmEvent, scrapeErrors map[string]error, anomalies []sourceAnomaly, sources []string, syncedAt string) []SourceSync {
+	previousBySource := make(map[string]SourceSync)
+	for _, sourceSync := range previous {
+		previousBySource[sourceSync.Source] = sourceSync
+	}
+
+	sourceSyncs := sourceSyncsFor(edmEvents, sources, syncedAt)
+
+	for i, sourceSync := range sourceSyncs {
+		previousSync, ok := previousBySource[sourceSync.Source]
+		if !ok {
+			previousSync = SourceSync{Source: sourceSync.Source, Venues: []string{}}
+		}
+
+		accepted := slices.IndexFunc(anomalies, func(a sourceAnomaly) bool {
+			return a.Source == sourceSync.Source && a.Accepted
+		})
+
+		if err, failed := scrapeErrors[sourceSync.Source]; failed {
+			sourceSync = previousSync
+			sourceSync.LastError = err.Error()
+			sourceSync.LastErrorAt = syncedAt
+			var anomaly sourceAnomaly
+			if errors.As(err, &anomaly) {
+				sourceSync.FlaggedRuns++
+			}
+		} else if accepted >= 0 {
+			sourceSync.LastError = anomalies[accepted].Error()
+			sourceSync.LastErrorAt = syncedAt
+			sourceSync.CountHistory = []int{sourceSync.EventCount}
+		} else {
+			sourceSync.LastError = previousSync.LastError
+			sourceSync.LastErrorAt = previousSync.LastErrorAt
+			sourceSync.CountHistory = withCountHistory(previousSync.CountHistory, sourceSync.EventCount)
+		}
+
+		sourceSync.LastAttempt = syncedAt
+		sourceSyncs[i] = sourceSync
+	}
+
+	return sourceSyncs
+}
+
+// edmEventsFromSources returns the stored events scraped by the given sources.
+func edmEventsFromSources(edmEvents []EdmEvent, sources map[string]bool) []EdmEvent {
+	kept := []EdmEvent{}
+	for _, edmEvent := range edmEvents {
+		if sources[edmEvent.Source] {
+			kept = append(kept, edmEvent)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,510 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultTelegramAPIURL = "https://api.telegram.org"
+
+const (
+	// Seconds a getUpdates call waits for a message before answering with none.
+	telegramPollTimeout = 30
+	// Events listed in a reply, Telegram messages are at most 4096 characters.
+	telegramMaxEvents      = 10
+	telegramMaxArtistChars = 200
+)
+
+// Telegram chats follow artists through a watchlist of their own. The user id of a chat
+// can't be mistaken for an API key id, so the alerts of both kinds of users never mix.
+const (
+	telegramUserPrefix    = "telegram:"
+	telegramWatchlistName = "Telegram"
+)
+
+// telegramHelp is sent as HTML like every reply.
+const telegramHelp = `Las Vegas EDM events:
+/next &lt;artist&gt; - the next shows of an artist
+/tonight - tonight's shows
+/venue &lt;name&gt; - the next shows at a venue
+/follow &lt;artist&gt; - get a message when the artist is announced or sells out
+/unfollow &lt;artist&gt; - stop following an artist, or everyone without a name
+/following - the artists you follow`
+
+// telegramBot talks to the Telegram Bot API. apiURL is https://api.telegram.org unless it's
+// pointed at a stub for testing.
+type telegramBot struct {
+	apiURL     string
+	token      string
+	client     *http.Client
+	retryDelay time.Duration
+}
+
+func newTelegramBot(apiURL string, token string) *telegramBot {
+	return &telegramBot{
+		apiURL: strings.TrimSuffix(apiURL, "/"),
+		token:  token,
+		// getUpdates holds the request open for the poll timeout.
+		client:     &http.Client{Timeout: (telegramPollTimeout + 10) * time.Second},
+		retryDelay: 5 * time.Second,
+	}
+}
+
+type telegramUpdate struct {
+	UpdateID int64            `json:"update_id"`
+	Message  *telegramMessage `json:"message"`
+}
+
+type telegramMessage struct {
+	MessageID int64        `json:"message_id"`
+	Chat      telegramChat `json:"chat"`
+	Text      string       `json:"text"`
+}
+
+type telegramChat struct {
+	ID int64 `json:"id"`
+}
+
+// telegramResponse is the envelope of every Bot API response.
+type telegramResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	Description string          `json:"description"`
+}
+
+// call invokes a Bot API method with params sent as JSON and decodes its result. Errors
+// leave the request URL out, it contains the bot's token.
+func (b *telegramBot) call(ctx context.Context, method string, params any, result any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, b.apiURL+"/bot"+b.token+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%s: invalid Telegram API URL", method)
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := b.client.Do(request)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	defer response.Body.Close()
+
+	var envelope telegramResponse
+	err = json.NewDecoder(response.Body).Decode(&envelope)
+	if err != nil {
+		return fmt.Errorf("%s: status %d: %w", method, response.StatusCode, err)
+	}
+	if !envelope.OK {
+		return fmt.Errorf("%s: status %d: %s", method, response.StatusCode, envelope.Description)
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(envelope.Result, result)
+}
+
+// getUpdates long polls for the messages sent to the bot after offset.
+func (b *telegramBot) getUpdates(ctx context.Context, offset int64) ([]telegramUpdate, error) {
+	var updates []telegramUpdate
+	err := b.call(ctx, "getUpdates", map[string]any{
+		"offset":          offset,
+		"timeout":         telegramPollTimeout,
+		"allowed_updates": []string{"message"},
+	}, &updates)
+	return updates, err
+}
+
+func (b *telegramBot) sendMessage(ctx context.Context, chatID int64, text string) error {
+	return b.call(ctx, "sendMessage", map[string]any{
+		"chat_id":              chatID,
+		"text":                 text,
+		"parse_mode":           "HTML",
+		"link_preview_options": map[string]bool{"is_disabled": true},
+	}, nil)
+}
+
+// runTelegramBot answers the commands sent to the bot until ctx is done. Updates are
+// acknowledged by asking for those after them, so a command is answered at most once.
+func (app *application) runTelegramBot(ctx context.Context) error {
+	var offset int64
+
+	for {
+		updates, err := app.telegram.getUpdates(ctx, offset)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			app.logger.Printf("failed to get Telegram updates, retrying in %s: %v", app.telegram.retryDelay, err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(app.telegram.retryDelay):
+			}
+			continue
+		}
+
+		for _, update := range updates {
+			offset = update.UpdateID + 1
+			if update.Message == nil {
+				continue
+			}
+
+			reply := app.telegramReply(update.Message.Chat.ID, update.Message.Text, time.Now())
+			if reply == "" {
+				continue
+			}
+
+			err := app.telegram.sendMessage(ctx, update.Message.Chat.ID, reply)
+			if err != nil {
+				app.logger.Printf("failed to answer Telegram chat %d: %v", update.Message.Chat.ID, err)
+			}
+		}
+	}
+}
+
+// parseTelegramCommand splits a message such as "/next@EdmEventsBot Tiësto" into the command
+// and its argument. Messages that aren't commands have an empty command.
+func parseTelegramCommand(text string) (command string, argument string) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "/") {
+		return "", ""
+	}
+
+	command, argument, _ = strings.Cut(text, " ")
+	command, _, _ = strings.Cut(command, "@")
+	return strings.ToLower(command), strings.TrimSpace(argument)
+}
+
+// telegramReply answers a message sent to the bot, an empty reply meaning the message isn't
+// answered.
+func (app *application) telegramReply(chatID int64, text string, now time.Time) string {
+	command, argument := parseTelegramCommand(text)
+
+	var reply string
+	var err error
+
+	switch command {
+	case "":
+		return ""
+	case "/start", "/help":
+		return telegramHelp
+	case "/next":
+		if argument == "" {
+			return "Send the artist too, such as /next Tiësto"
+		}
+		reply, err = app.telegramNext(argument, now)
+	case "/tonight":
+		reply, err = app.telegramTonight(now)
+	case "/venue":
+		if argument == "" {
+			return "Send the venue too, such as /venue XS"
+		}
+		reply, err = app.telegramVenue(argument, now)
+	case "/follow":
+		if argument == "" {
+			return "Send the artist too, such as /follow Tiësto"
+		}
+		reply, err = app.telegramFollow(chatID, argument)
+	case "/unfollow":
+		reply, err = app.telegramUnfollow(chatID, argument)
+	case "/following":
+		reply, err = app.telegramFollowing(chatID)
+	default:
+		return "Unknown command, send /help for the ones I know."
+	}
+
+	if err != nil {
+		app.logger.Printf("failed to answer %s in Telegram chat %d: %v", command, chatID, err)
+		return "Something went wrong, please try again later."
+	}
+	return reply
+}
+
+// upcomingEdmEvents returns the stored events from tonight on, sorted by date.
+func (app *application) upcomingEdmEvents(now time.Time) ([]EdmEvent, error) {
+	return app.queryEdmEvents(EventFilters{From: lasVegasToday(now)})
+}
+
+// nameMatches reports whether the name contains the query as whole words, ignoring case,
+// accents and punctuation, like watchlists match artists.
+func nameMatches(query string, name string) bool {
+	return artistMatches(strings.Fields(normalizeSearchText(query)), strings.Fields(normalizeSearchText(name)))
+}
+
+// sameName reports whether two names are the same ignoring case, accents and punctuation.
+func sameName(a string, b string) bool {
+	return strings.Join(strings.Fields(normalizeSearchText(a)), " ") == strings.Join(strings.Fields(normalizeSearchText(b)), " ")
+}
+
+func (app *application) telegramNext(artist string, now time.Time) (string, error) {
+	edmEvents, err := app.upcomingEdmEvents(now)
+	if err != nil {
+		return "", err
+	}
+
+	matching := []EdmEvent{}
+	for _, edmEvent := range edmEvents {
+		if nameMatches(artist, edmEvent.ArtistName) {
+			matching = append(matching, edmEvent)
+		}
+	}
+	if len(matching) == 0 {
+		return fmt.Sprintf("No upcoming shows of %s in Las Vegas.", html.EscapeString(artist)), nil
+	}
+
+	return fmt.Sprintf("Next shows of %s:\n\n%s", html.EscapeString(artist), telegramEventList(matching)), nil
+}
+
+func (app *application) telegramTonight(now time.Time) (string, error) {
+	today := lasVegasToday(now)
+	tonight, err := time.Parse(queryDateFormat, today)
+	if err != nil {
+		return "", err
+	}
+
+	// Sets after midnight are stored on the next day but belong to tonight.
+	edmEvents, err := app.queryEdmEvents(EventFilters{From: today, To: tonight.AddDate(0, 0, 1).Format(queryDateFormat)})
+	if err != nil {
+		return "", err
+	}
+
+	for _, n := range groupByNight(edmEvents) {
+		if n.Date.Format(queryDateFormat) == today {
+			return fmt.Sprintf("Tonight in Las Vegas:\n\n%s", telegramEventList(n.Events)), nil
+		}
+	}
+	return "No shows are listed for tonight.", nil
+}
+
+func (app *application) telegramVenue(venue string, now time.Time) (string, error) {
+	edmEvents, err := app.upcomingEdmEvents(now)
+	if err != nil {
+		return "", err
+	}
+
+	matching := []EdmEvent{}
+	for _, edmEvent := range edmEvents {
+		if nameMatches(venue, edmEvent.ClubName) {
+			matching = append(matching, edmEvent)
+		}
+	}
+	if len(matching) == 0 {
+		return fmt.Sprintf("No upcoming shows at %s.", html.EscapeString(venue)), nil
+	}
+
+	return fmt.Sprintf("Next shows at %s:\n\n%s", html.EscapeString(venue), telegramEventList(matching)), nil
+}
+
+// telegramEventList formats the first events as HTML, one paragraph each, and counts the
+// rest.
+func telegramEventList(edmEvents []EdmEvent) string {
+	var b strings.Builder
+
+	for i, edmEvent := range edmEvents {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		if i == telegramMaxEvents {
+			fmt.Fprintf(&b, "…and %d more", len(edmEvents)-telegramMaxEvents)
+			break
+		}
+
+		fmt.Fprintf(&b, "<b>%s</b>\n%s, %s",
+			html.EscapeString(truncate(edmEvent.ArtistName, telegramMaxArtistChars)),
+			html.EscapeString(eventWhen(edmEvent)),
+			html.EscapeString(edmEvent.ClubName),
+		)
+		if eventAvailability(edmEvent) == availabilitySoldOut {
+			b.WriteString(" - sold out")
+		}
+		if edmEvent.TicketUrl != "" {
+			fmt.Fprintf(&b, "\n<a href=\"%s\">Tickets</a>", html.EscapeString(edmEvent.TicketUrl))
+		}
+	}
+
+	return b.String()
+}
+
+func telegramUserID(chatID int64) string {
+	return telegramUserPrefix + strconv.FormatInt(chatID, 10)
+}
+
+// telegramWatchlist returns the watchlist of a chat, errRecordNotFound before it follows
+// anyone.
+func (app *application) telegramWatchlist(chatID int64) (Watchlist, error) {
+	watchlists, err := app.dbWatchlists.GetForUser(telegramUserID(chatID))
+	if err != nil {
+		return Watchlist{}, err
+	}
+	if len(watchlists) == 0 {
+		return Watchlist{}, errRecordNotFound
+	}
+	return watchlists[0], nil
+}
+
+func (app *application) telegramFollow(chatID int64, artist string) (string, error) {
+	watchlist, err := app.telegramWatchlist(chatID)
+	isNew := errors.Is(err, errRecordNotFound)
+	if err != nil && !isNew {
+		return "", err
+	}
+
+	for _, followed := range watchlist.Artists {
+		if sameName(followed, artist) {
+			return fmt.Sprintf("You already follow %s.", html.EscapeString(followed)), nil
+		}
+	}
+
+	v := newValidator()
+	_, artists := readWatchlistInput(v, telegramWatchlistName, append(slices.Clip(watchlist.Artists), artist))
+	if !v.Valid() {
+		return fmt.Sprintf("Can't follow %s, the artists %s.", html.EscapeString(artist), v.Errors["artists"]), nil
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	watchlist.Artists = artists
+	watchlist.UpdatedAt = now
+
+	if isNew {
+		watchlist.ID = getGUID()
+		watchlist.UserID = telegramUserID(chatID)
+		watchlist.Name = telegramWatchlistName
+		watchlist.CreatedAt = now
+		err = app.dbWatchlists.Insert(watchlist)
+	} else {
+		err = app.dbWatchlists.Update(watchlist)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Following %s. You'll get a message when they're announced in Las Vegas or a show sells out.", html.EscapeString(strings.TrimSpace(artist))), nil
+}
+
+// telegramUnfollow stops following an artist, or every artist without one. The watchlist is
+// deleted once it's empty.
+func (app *application) telegramUnfollow(chatID int64, artist string) (string, error) {
+	watchlist, err := app.telegramWatchlist(chatID)
+	if errors.Is(err, errRecordNotFound) {
+		return "You don't follow anyone.", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	kept := []string{}
+	for _, followed := range watchlist.Artists {
+		if artist != "" && !sameName(followed, artist) {
+			kept = append(kept, followed)
+		}
+	}
+	if len(kept) == len(watchlist.Artists) {
+		return fmt.Sprintf("You don't follow %s.", html.EscapeString(artist)), nil
+	}
+
+	if len(kept) == 0 {
+		err = app.dbWatchlists.Delete(watchlist.ID)
+	} else {
+		watchlist.Artists = kept
+		watchlist.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+		err = app.dbWatchlists.Update(watchlist)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if artist == "" {
+		return "You no longer follow anyone.", nil
+	}
+	return fmt.Sprintf("You no longer follow %s.", html.EscapeString(artist)), nil
+}
+
+func (app *application) telegramFollowing(chatID int64) (string, error) {
+	watchlist, err := app.telegramWatchlist(chatID)
+	if errors.Is(err, errRecordNotFound) {
+		return "You don't follow anyone yet, try /follow Tiësto", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return "You follow " + html.EscapeString(strings.Join(watchlist.Artists, ", ")) + ".", nil
+}
+
+// notifyTelegram messages the chats whose followed artists were alerted, one message per chat
+// and type of alert. Each message is recorded in the send log.
+func (app *application) notifyTelegram(alerts []Alert) {
+	if app.telegram == nil {
+		return
+	}
+
+	for _, alertType := range alertTypes {
+		chatAlerts := make(map[string][]Alert)
+		chats := []string{}
+		for _, alert := range alerts {
+			if alert.Type != alertType || !strings.HasPrefix(alert.UserID, telegramUserPrefix) {
+				continue
+			}
+			if _, ok := chatAlerts[alert.UserID]; !ok {
+				chats = append(chats, alert.UserID)
+			}
+			chatAlerts[alert.UserID] = append(chatAlerts[alert.UserID], alert)
+		}
+
+		for _, userID := range chats {
+			chatID, err := strconv.ParseInt(strings.TrimPrefix(userID, telegramUserPrefix), 10, 64)
+			if err != nil {
+				continue
+			}
+
+			edmEvents := []EdmEvent{}
+			entry := SendLogEntry{
+				ID:        getGUID(),
+				Channel:   channelTelegram,
+				Recipient: userID,
+				Status:    sendStatusSent,
+				Attempts:  1,
+				CreatedAt: time.Now().UTC().Format(time.RFC3339),
+			}
+			for _, alert := range chatAlerts[userID] {
+				edmEvents = append(edmEvents, alert.EdmEvent)
+				entry.AlertIDs = append(entry.AlertIDs, alert.ID)
+			}
+
+			title := "Just announced in Las Vegas:"
+			if alertType == alertTypeSoldOut {
+				title = "Sold out:"
+			}
+
+			err = app.telegram.sendMessage(context.Background(), chatID, title+"\n\n"+telegramEventList(edmEvents))
+			if err != nil {
+				app.logger.Printf("failed to message %d alerts to Telegram chat %d: %v", len(edmEvents), chatID, err)
+				entry.Status = sendStatusFailed
+				entry.Error = err.Error()
+			}
+
+			err = app.dbSendLog.Insert(entry)
+			if err != nil {
+				app.logger.Printf("failed to record Telegram message to %d: %v", chatID, err)
+			}
+		}
+	}
+}
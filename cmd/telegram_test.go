@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// telegramTestNow is Friday, October 23, 2026 at 8pm in Las Vegas.
+var telegramTestNow = time.Date(2026, 10, 24, 3, 0, 0, 0, time.UTC)
+
+var telegramTestEvents = []EdmEvent{
+	{Id: "past", ClubName: "hakkasan", ArtistName: "tiësto", EventDate: "2026-10-20T00:00:00Z"},
+	{Id: "tonight", ClubName: "xs nightclub", ArtistName: "tiësto", EventDate: "2026-10-23T22:30:00Z", TicketUrl: "https://tickets.example.com/tonight"},
+	{Id: "after-midnight", ClubName: "omnia", ArtistName: "john summit & friends", EventDate: "2026-10-24T01:00:00Z"},
+	{Id: "saturday", ClubName: "omnia", ArtistName: "kygo", EventDate: "2026-10-24T23:00:00Z"},
+	{Id: "next-week", ClubName: "omnia", ArtistName: "tiësto b2b afrojack", EventDate: "2026-10-30T00:00:00Z", Availability: availabilitySoldOut},
+}
+
+// TestParseTelegramCommand tests splitting messages into a command and its argument
+func TestParseTelegramCommand(t *testing.T) {
+	tests := []struct {
+		text             string
+		expectedCommand  string
+		expectedArgument string
+	}{
+		{text: "/next Tiësto", expectedCommand: "/next", expectedArgument: "Tiësto"},
+		{text: "  /NEXT@EdmEventsBot  John Summit ", expectedCommand: "/next", expectedArgument: "John Summit"},
+		{text: "/tonight", expectedCommand: "/tonight"},
+		{text: "what's on tonight?", expectedCommand: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			command, argument := parseTelegramCommand(tt.text)
+			if command != tt.expectedCommand || argument != tt.expectedArgument {
+				t.Errorf("Expected '%s' '%s', got '%s' '%s'", tt.expectedCommand, tt.expectedArgument, command, argument)
+			}
+		})
+	}
+}
+
+// TestTelegramReply tests the answers to the commands that look events up
+func TestTelegramReply(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		expected    []string
+		notExpected []string
+	}{
+		{name: "Help", text: "/start", expected: []string{"/next &lt;artist&gt;", "/follow"}},
+		{name: "Next", text: "/next Tiesto", expected: []string{"Next shows of Tiesto:", "<b>tiësto</b>\nFriday, October 23, 2026 at 10:30 PM, xs nightclub\n<a href=\"https://tickets.example.com/tonight\">Tickets</a>", "tiësto b2b afrojack", "xs nightclub", "omnia - sold out"}, notExpected: []string{"hakkasan"}},
+		{name: "Next without a match", text: "/next Tiest", expected: []string{"No upcoming shows of Tiest in Las Vegas."}},
+		{name: "Next without an artist", text: "/next", expected: []string{"Send the artist too"}},
+		{name: "Tonight", text: "/tonight", expected: []string{"Tonight in Las Vegas:", "<b>tiësto</b>", "<b>john summit &amp; friends</b>"}, notExpected: []string{"kygo"}},
+		{name: "Venue", text: "/venue Omnia", expected: []string{"Next shows at Omnia:", "kygo", "john summit"}, notExpected: []string{"xs nightclub"}},
+		{name: "Venue is escaped", text: "/venue <b>", expected: []string{"No upcoming shows at &lt;b&gt;."}},
+		{name: "Unknown command", text: "/weather", expected: []string{"Unknown command"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, telegramTestEvents)
+
+			reply := app.telegramReply(42, tt.text, telegramTestNow)
+			for _, expected := range tt.expected {
+				if !strings.Contains(reply, expected) {
+					t.Errorf("Expected the reply to contain %q, got:\n%s", expected, reply)
+				}
+			}
+			for _, notExpected := range tt.notExpected {
+				if strings.Contains(reply, notExpected) {
+					t.Errorf("Expected the reply not to contain %q, got:\n%s", notExpected, reply)
+				}
+			}
+		})
+	}
+
+	app := newTestApplication(t, telegramTestEvents)
+	if reply := app.telegramReply(42, "hello", telegramTestNow); reply != "" {
+		t.Errorf("Expected messages that aren't commands to be ignored, got '%s'", reply)
+	}
+}
+
+// TestTelegramEventList tests that lists longer than telegramMaxEvents end with a separate
+// line counting the events left out
+func TestTelegramEventList(t *testing.T) {
+	edmEvents := []EdmEvent{}
+	for i := range telegramMaxEvents + 2 {
+		edmEvents = append(edmEvents, EdmEvent{
+			Id:         fmt.Sprintf("e%d", i),
+			ClubName:   "omnia",
+			ArtistName: fmt.Sprintf("artist %d", i),
+			EventDate:  "2026-10-24T23:00:00Z",
+			TicketUrl:  fmt.Sprintf("https://tickets.example.com/%d", i),
+		})
+	}
+
+	list := telegramEventList(edmEvents)
+	if count := strings.Count(list, "<b>"); count != telegramMaxEvents {
+		t.Errorf("Expected %d events, got %d", telegramMaxEvents, count)
+	}
+	if !strings.HasSuffix(list, "Tickets</a>\n\n…and 2 more") {
+		t.Errorf("Expected the list to end with a separate '…and 2 more' line, got:\n%s", list)
+	}
+}
+
+// TestTelegramFollow tests following and unfollowing artists through the chat's watchlist
+func TestTelegramFollow(t *testing.T) {
+	app := newTestApplication(t, nil)
+	watchlists := app.dbWatchlists.(*mockWatchlistModel)
+
+	steps := []struct {
+		text            string
+		expectedReply   string
+		expectedArtists []string
+	}{
+		{text: "/following", expectedReply: "You don't follow anyone yet", expectedArtists: nil},
+		{text: "/follow Tiësto", expectedReply: "Following Tiësto.", expectedArtists: []string{"Tiësto"}},
+		{text: "/follow TIESTO", expectedReply: "You already follow Tiësto.", expectedArtists: []string{"Tiësto"}},
+		{text: "/follow John Summit", expectedReply: "Following John Summit.", expectedArtists: []string{"Tiësto", "John Summit"}},
+		{text: "/following", expectedReply: "You follow Tiësto, John Summit.", expectedArtists: []string{"Tiësto", "John Summit"}},
+		{text: "/unfollow tiesto", expectedReply: "You no longer follow tiesto.", expectedArtists: []string{"John Summit"}},
+		{text: "/unfollow Kygo", expectedReply: "You don't follow Kygo.", expectedArtists: []string{"John Summit"}},
+		{text: "/unfollow", expectedReply: "You no longer follow anyone.", expectedArtists: nil},
+		{text: "/unfollow", expectedReply: "You don't follow anyone.", expectedArtists: nil},
+	}
+
+	for _, step := range steps {
+		reply := app.telegramReply(42, step.text, telegramTestNow)
+		if !strings.HasPrefix(reply, step.expectedReply) {
+			t.Errorf("%s: expected a reply starting with '%s', got '%s'", step.text, step.expectedReply, reply)
+		}
+
+		if step.expectedArtists == nil {
+			if len(watchlists.watchlists) != 0 {
+				t.Errorf("%s: expected no watchlist, got %+v", step.text, watchlists.watchlists)
+			}
+			continue
+		}
+		if len(watchlists.watchlists) != 1 {
+			t.Fatalf("%s: expected 1 watchlist, got %d", step.text, len(watchlists.watchlists))
+		}
+		watchlist := watchlists.watchlists[0]
+		if watchlist.UserID != "telegram:42" || strings.Join(watchlist.Artists, ",") != strings.Join(step.expectedArtists, ",") {
+			t.Errorf("%s: expected telegram:42 to follow %v, got %s following %v", step.text, step.expectedArtists, watchlist.UserID, watchlist.Artists)
+		}
+	}
+}
+
+// telegramStub is a local stand-in for the Bot API. getUpdates answers with the queued
+// updates once, then cancels the bot's context.
+type telegramStub struct {
+	mu       sync.Mutex
+	updates  []telegramUpdate
+	offsets  []int64
+	messages []map[string]any
+	cancel   context.CancelFunc
+}
+
+func (s *telegramStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var params map[string]any
+	json.NewDecoder(r.Body).Decode(&params)
+
+	var result any = true
+	switch r.URL.Path {
+	case "/bottest-token/getUpdates":
+		s.offsets = append(s.offsets, int64(params["offset"].(float64)))
+		result = s.updates
+		if s.updates == nil && s.cancel != nil {
+			s.cancel()
+		}
+		s.updates = nil
+	case "/bottest-token/sendMessage":
+		s.messages = append(s.messages, params)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"ok": false, "description": "Not Found"}`)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+}
+
+// TestRunTelegramBot tests answering commands polled from the Bot API
+func TestRunTelegramBot(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stub := &telegramStub{cancel: cancel, updates: []telegramUpdate{
+		{UpdateID: 7, Message: &telegramMessage{Chat: telegramChat{ID: 42}, Text: "/help"}},
+		{UpdateID: 8, Message: &telegramMessage{Chat: telegramChat{ID: 43}, Text: "just chatting"}},
+		{UpdateID: 9},
+	}}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	app := newTestApplication(t, nil)
+	app.telegram = newTelegramBot(server.URL+"/", "test-token")
+
+	err := app.runTelegramBot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stub.offsets) != 2 || stub.offsets[0] != 0 || stub.offsets[1] != 10 {
+		t.Errorf("Expected to poll from offset 0 then 10, got %v", stub.offsets)
+	}
+	if len(stub.messages) != 1 {
+		t.Fatalf("Expected 1 reply, got %d", len(stub.messages))
+	}
+	message := stub.messages[0]
+	if message["chat_id"] != float64(42) || message["parse_mode"] != "HTML" || message["text"] != telegramHelp {
+		t.Errorf("Expected the help as HTML to chat 42, got %+v", message)
+	}
+
+	// The token is part of the URL and mustn't end up in errors.
+	app.telegram = newTelegramBot(server.URL+"/unknown", "test-token")
+	err = app.telegram.sendMessage(context.Background(), 42, "hi")
+	if err == nil || strings.Contains(err.Error(), "test-token") {
+		t.Errorf("Expected an error without the token, got %v", err)
+	}
+}
+
+// TestNotifyTelegram tests messaging the alerts of the artists a chat follows
+func TestNotifyTelegram(t *testing.T) {
+	stub := &telegramStub{}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	app := newTestApplication(t, nil)
+	app.telegram = newTelegramBot(server.URL, "test-token")
+	app.dbWatchlists = &mockWatchlistModel{watchlists: []Watchlist{
+		{ID: "w1", UserID: "telegram:42", Name: telegramWatchlistName, Artists: []string{"Tiësto"}},
+		{ID: "w2", UserID: "api-key", Name: "Favourites", Artists: []string{"Tiësto"}},
+	}}
+
+	created := []EdmEvent{{Id: "e1", ClubName: "xs nightclub", ArtistName: "tiësto", EventDate: "2026-11-20T00:00:00Z"}}
+	soldOut := []EdmEvent{{Id: "e2", ClubName: "omnia", ArtistName: "tiësto", EventDate: "2026-11-21T00:00:00Z", Availability: availabilitySoldOut}}
+
+	alerts, err := app.alertWatchlists(created, soldOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 4 {
+		t.Fatalf("Expected 4 alerts, got %d", len(alerts))
+	}
+
+	if len(stub.messages) != 2 {
+		t.Fatalf("Expected an announcement and a sold out message, got %d", len(stub.messages))
+	}
+	for i, expected := range []string{"Just announced in Las Vegas:", "Sold out:"} {
+		text, _ := stub.messages[i]["text"].(string)
+		if stub.messages[i]["chat_id"] != float64(42) || !strings.HasPrefix(text, expected) {
+			t.Errorf("Expected '%s' to chat 42, got %+v", expected, stub.messages[i])
+		}
+	}
+
+	entries := app.dbSendLog.(*mockSendLogModel).entries
+	if len(entries) != 2 || entries[0].Channel != channelTelegram || entries[0].Recipient != "telegram:42" || entries[0].Status != sendStatusSent {
+		t.Errorf("Expected the 2 messages in the send log, got %+v", entries)
+	}
+}
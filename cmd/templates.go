@@ -0,0 +1,130 @@
+package main
+
+import (
+	"html/template"
+	"io/fs"
+	"net/url"
+	"path/filepath"
+	"time"
+
+	"edmEventsScraperApiGo.christiangabrielsson.net/ui"
+)
+
+// templateData holds the dynamic data passed to the HTML templates.
+type templateData struct {
+	CurrentYear int
+	Query       string
+	Heading     string
+	Nights      []night
+	Metadata    Metadata
+	Pagination  *pagination
+}
+
+// pagination links to the neighbouring pages of a listing, an empty URL hides the link.
+type pagination struct {
+	PreviousURL string
+	NextURL     string
+}
+
+// night is a date together with the events happening on it, in the query layer's order.
+type night struct {
+	Date   time.Time
+	Events []EdmEvent
+}
+
+// groupByNight groups events that are already sorted by date into nights. Events after
+// midnight sort after the date-only events of the next day but still join their night.
+func groupByNight(edmEvents []EdmEvent) []night {
+	nights := []night{}
+	nightIndex := make(map[string]int)
+
+	for _, edmEvent := range edmEvents {
+		key := eventNight(edmEvent)
+		date, err := time.Parse(queryDateFormat, key)
+		if err != nil {
+			continue
+		}
+
+		i, ok := nightIndex[key]
+		if !ok {
+			i = len(nights)
+			nightIndex[key] = i
+			nights = append(nights, night{Date: date})
+		}
+		nights[i].Events = append(nights[i].Events, edmEvent)
+	}
+
+	return nights
+}
+
+// Events starting before this hour are part of the previous night out.
+const nightCutoffHour = 6
+
+// eventNight returns the YYYY-MM-DD night an event belongs to. Event dates are Las Vegas
+// wall-clock times, those stored at exactly midnight only carry a date and belong to that
+// night, while a show starting at 2am belongs to the night before.
+func eventNight(edmEvent EdmEvent) string {
+	t, err := time.Parse(time.RFC3339, edmEvent.EventDate)
+	if err != nil {
+		if len(edmEvent.EventDate) < len(queryDateFormat) {
+			return ""
+		}
+		return edmEvent.EventDate[:len(queryDateFormat)]
+	}
+
+	isDateOnly := t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0
+	if !isDateOnly && t.Hour() < nightCutoffHour {
+		t = t.AddDate(0, 0, -1)
+	}
+	return t.Format(queryDateFormat)
+}
+
+func humanNight(t time.Time) string {
+	return t.Format("Monday, January 2, 2006")
+}
+
+// startTime returns the time of day of an event, or an empty string for events stored at
+// midnight since the scrapers only know their date.
+func startTime(eventDate string) string {
+	t, err := time.Parse(time.RFC3339, eventDate)
+	if err != nil || (t.Hour() == 0 && t.Minute() == 0) {
+		return ""
+	}
+	return t.Format("3:04 PM")
+}
+
+var functions = template.FuncMap{
+	"humanNight": humanNight,
+	"startTime":  startTime,
+	"pathEscape": url.PathEscape,
+}
+
+// newTemplateCache parses every page together with the base layout and the partials, once
+// at startup.
+func newTemplateCache() (map[string]*template.Template, error) {
+	cache := map[string]*template.Template{}
+
+	pages, err := fs.Glob(ui.Files, "html/pages/*.html")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, page := range pages {
+		name := filepath.Base(page)
+
+		patterns := []string{
+			"html/base.html",
+			"html/partials/*.html",
+			page,
+		}
+
+		ts, err := template.New(name).Funcs(functions).ParseFS(ui.Files, patterns...)
+		if err != nil {
+			return nil, err
+		}
+
+		cache[name] = ts
+	}
+
+	return cache, nil
+}
@@ -0,0 +1,576 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockSnippetModel is an in-memory stand-in for the Firestore SnippetModel.
+type mockSnippetModel struct {
+	edmEvents   []EdmEvent
+	err         error
+	getAllCalls int
+}
+
+func (m *mockSnippetModel) InsertMany(edmEvents []EdmEvent) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.edmEvents = append(m.edmEvents, edmEvents...)
+	return nil
+}
+
+func (m *mockSnippetModel) DeleteMany(edmEvents []EdmEvent) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.edmEvents = nil
+	return nil
+}
+
+func (m *mockSnippetModel) GetAll() ([]EdmEvent, error) {
+	m.getAllCalls++
+	if m.err != nil {
+		return nil, m.err
+	}
+	return append([]EdmEvent{}, m.edmEvents...), nil
+}
+
+// mockSyncStateModel is an in-memory stand-in for the Firestore SyncStateModel.
+type mockSyncStateModel struct {
+	sourceSyncs []SourceSync
+	err         error
+}
+
+func (m *mockSyncStateModel) GetAll() ([]SourceSync, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return append([]SourceSync{}, m.sourceSyncs...), nil
+}
+
+func (m *mockSyncStateModel) Upsert(sourceSyncs []SourceSync) error {
+	if m.err != nil {
+		return m.err
+	}
+	for _, sourceSync := range sourceSyncs {
+		replaced := false
+		for i := range m.sourceSyncs {
+			if m.sourceSyncs[i].Source == sourceSync.Source {
+				m.sourceSyncs[i] = sourceSync
+				replaced = true
+			}
+		}
+		if !replaced {
+			m.sourceSyncs = append(m.sourceSyncs, sourceSync)
+		}
+	}
+	return nil
+}
+
+// mockAPIKeyModel is an in-memory stand-in for the Firestore APIKeyModel.
+type mockAPIKeyModel struct {
+	apiKeys        []APIKey
+	err            error
+	getByHashCalls int
+}
+
+func (m *mockAPIKeyModel) Insert(apiKey APIKey) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.apiKeys = append(m.apiKeys, apiKey)
+	return nil
+}
+
+func (m *mockAPIKeyModel) GetByHash(hash string) (APIKey, error) {
+	m.getByHashCalls++
+	if m.err != nil {
+		return APIKey{}, m.err
+	}
+	for _, apiKey := range m.apiKeys {
+		if apiKey.Hash == hash {
+			return apiKey, nil
+		}
+	}
+	return APIKey{}, errRecordNotFound
+}
+
+func (m *mockAPIKeyModel) GetAll() ([]APIKey, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return append([]APIKey{}, m.apiKeys...), nil
+}
+
+func (m *mockAPIKeyModel) Revoke(id string, revokedAt string) error {
+	if m.err != nil {
+		return m.err
+	}
+	for i := range m.apiKeys {
+		if m.apiKeys[i].ID == id {
+			m.apiKeys[i].RevokedAt = revokedAt
+			return nil
+		}
+	}
+	return errRecordNotFound
+}
+
+func (m *mockAPIKeyModel) AddUsage(requestCounts map[string]int64, lastUsedAt string) error {
+	if m.err != nil {
+		return m.err
+	}
+	for i := range m.apiKeys {
+		if count, ok := requestCounts[m.apiKeys[i].ID]; ok {
+			m.apiKeys[i].RequestCount += count
+			m.apiKeys[i].LastUsedAt = lastUsedAt
+		}
+	}
+	return nil
+}
+
+// mockWatchlistModel is an in-memory stand-in for the Firestore WatchlistModel.
+type mockWatchlistModel struct {
+	watchlists []Watchlist
+	err        error
+}
+
+func (m *mockWatchlistModel) Insert(watchlist Watchlist) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.watchlists = append(m.watchlists, watchlist)
+	return nil
+}
+
+func (m *mockWatchlistModel) Get(id string) (Watchlist, error) {
+	if m.err != nil {
+		return Watchlist{}, m.err
+	}
+	for _, watchlist := range m.watchlists {
+		if watchlist.ID == id {
+			return watchlist, nil
+		}
+	}
+	return Watchlist{}, errRecordNotFound
+}
+
+func (m *mockWatchlistModel) GetAll() ([]Watchlist, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return append([]Watchlist{}, m.watchlists...), nil
+}
+
+func (m *mockWatchlistModel) GetForUser(userID string) ([]Watchlist, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	watchlists := []Watchlist{}
+	for _, watchlist := range m.watchlists {
+		if watchlist.UserID == userID {
+			watchlists = append(watchlists, watchlist)
+		}
+	}
+	return watchlists, nil
+}
+
+func (m *mockWatchlistModel) Update(watchlist Watchlist) error {
+	if m.err != nil {
+		return m.err
+	}
+	for i := range m.watchlists {
+		if m.watchlists[i].ID == watchlist.ID {
+			m.watchlists[i] = watchlist
+			return nil
+		}
+	}
+	return errRecordNotFound
+}
+
+func (m *mockWatchlistModel) Delete(id string) error {
+	if m.err != nil {
+		return m.err
+	}
+	for i := range m.watchlists {
+		if m.watchlists[i].ID == id {
+			m.watchlists = append(m.watchlists[:i], m.watchlists[i+1:]...)
+			return nil
+		}
+	}
+	return errRecordNotFound
+}
+
+// mockAlertModel is an in-memory stand-in for the Firestore AlertModel.
+type mockAlertModel struct {
+	alerts []Alert
+	err    error
+	// insertErr fails InsertNew once it stored failAfter alerts, like a write failing partway.
+	insertErr error
+	failAfter int
+}
+
+func (m *mockAlertModel) InsertNew(alerts []Alert) ([]Alert, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	inserted := []Alert{}
+	for _, alert := range alerts {
+		if m.insertErr != nil && len(inserted) == m.failAfter {
+			return inserted, m.insertErr
+		}
+		exists := false
+		for _, stored := range m.alerts {
+			if stored.ID == alert.ID {
+				exists = true
+			}
+		}
+		if !exists {
+			m.alerts = append(m.alerts, alert)
+			inserted = append(inserted, alert)
+		}
+	}
+	return inserted, nil
+}
+
+func (m *mockAlertModel) GetForUser(userID string) ([]Alert, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	alerts := []Alert{}
+	for _, alert := range m.alerts {
+		if alert.UserID == userID {
+			alerts = append(alerts, alert)
+		}
+	}
+	sortAlerts(alerts)
+	return alerts, nil
+}
+
+// mockSendLogModel is an in-memory stand-in for the Firestore SendLogModel.
+type mockSendLogModel struct {
+	entries []SendLogEntry
+	err     error
+}
+
+func (m *mockSendLogModel) Insert(entry SendLogEntry) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+// mockDigestModel is an in-memory stand-in for the Firestore DigestModel.
+type mockDigestModel struct {
+	records []DigestRecord
+	err     error
+}
+
+func (m *mockDigestModel) Insert(record DigestRecord) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.records = append(m.records, record)
+	return nil
+}
+
+func (m *mockDigestModel) Latest() (DigestRecord, error) {
+	if m.err != nil {
+		return DigestRecord{}, m.err
+	}
+	latest := DigestRecord{}
+	for _, record := range m.records {
+		if record.GeneratedAt > latest.GeneratedAt {
+			latest = record
+		}
+	}
+	if latest.ID == "" {
+		return DigestRecord{}, errRecordNotFound
+	}
+	return latest, nil
+}
+
+// mockWebhookModel is an in-memory stand-in for the Firestore WebhookModel.
+type mockWebhookModel struct {
+	webhooks []Webhook
+	err      error
+}
+
+func (m *mockWebhookModel) Insert(webhook Webhook) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.webhooks = append(m.webhooks, webhook)
+	return nil
+}
+
+func (m *mockWebhookModel) Get(id string) (Webhook, error) {
+	if m.err != nil {
+		return Webhook{}, m.err
+	}
+	for _, webhook := range m.webhooks {
+		if webhook.ID == id {
+			return webhook, nil
+		}
+	}
+	return Webhook{}, errRecordNotFound
+}
+
+func (m *mockWebhookModel) GetAll() ([]Webhook, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return append([]Webhook{}, m.webhooks...), nil
+}
+
+func (m *mockWebhookModel) GetForUser(userID string) ([]Webhook, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	webhooks := []Webhook{}
+	for _, webhook := range m.webhooks {
+		if webhook.UserID == userID {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	return webhooks, nil
+}
+
+func (m *mockWebhookModel) Delete(id string) error {
+	if m.err != nil {
+		return m.err
+	}
+	for i := range m.webhooks {
+		if m.webhooks[i].ID == id {
+			m.webhooks = append(m.webhooks[:i], m.webhooks[i+1:]...)
+			return nil
+		}
+	}
+	return errRecordNotFound
+}
+
+// mockWebhookDeliveryModel is an in-memory stand-in for the Firestore WebhookDeliveryModel.
+// Webhooks are called concurrently, so unlike the other mocks it's safe for concurrent use.
+type mockWebhookDeliveryModel struct {
+	mu         sync.Mutex
+	deliveries []WebhookDelivery
+	err        error
+	// insertErrs fails the insertion of the given webhooks' deliveries.
+	insertErrs map[string]error
+}
+
+func (m *mockWebhookDeliveryModel) Insert(delivery WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	if err := m.insertErrs[delivery.WebhookID]; err != nil {
+		return err
+	}
+	m.deliveries = append(m.deliveries, delivery)
+	return nil
+}
+
+func (m *mockWebhookDeliveryModel) Get(id string) (WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return WebhookDelivery{}, m.err
+	}
+	for _, delivery := range m.deliveries {
+		if delivery.ID == id {
+			return delivery, nil
+		}
+	}
+	return WebhookDelivery{}, errRecordNotFound
+}
+
+func (m *mockWebhookDeliveryModel) GetForWebhook(webhookID string) ([]WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return nil, m.err
+	}
+	deliveries := []WebhookDelivery{}
+	for _, delivery := range m.deliveries {
+		if delivery.WebhookID == webhookID {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sortDeliveries(deliveries)
+	return deliveries, nil
+}
+
+func (m *mockWebhookDeliveryModel) Update(delivery WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	for i := range m.deliveries {
+		if m.deliveries[i].ID == delivery.ID {
+			m.deliveries[i] = delivery
+			return nil
+		}
+	}
+	return errRecordNotFound
+}
+
+// mockRunModel is an in-memory stand-in for the Firestore RunModel.
+type mockRunModel struct {
+	mu   sync.Mutex
+	runs []scrapeRun
+	err  error
+}
+
+func (m *mockRunModel) Insert(run scrapeRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.runs = append(m.runs, run)
+	return nil
+}
+
+func (m *mockRunModel) Get(id string) (scrapeRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return scrapeRun{}, m.err
+	}
+	for _, run := range m.runs {
+		if run.ID == id {
+			return run, nil
+		}
+	}
+	return scrapeRun{}, errRecordNotFound
+}
+
+func (m *mockRunModel) Latest(limit int) ([]scrapeRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return nil, m.err
+	}
+	runs := slices.Clone(m.runs)
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].StartedAt > runs[j].StartedAt })
+	if len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs, nil
+}
+
+// mockPushSubscriptionModel is an in-memory stand-in for the Firestore PushSubscriptionModel.
+type mockPushSubscriptionModel struct {
+	subscriptions []PushSubscription
+	err           error
+}
+
+func (m *mockPushSubscriptionModel) Insert(subscription PushSubscription) error {
+	if m.err != nil {
+		return m.err
+	}
+	for i := range m.subscriptions {
+		if m.subscriptions[i].ID == subscription.ID {
+			m.subscriptions[i] = subscription
+			return nil
+		}
+	}
+	m.subscriptions = append(m.subscriptions, subscription)
+	return nil
+}
+
+func (m *mockPushSubscriptionModel) Get(id string) (PushSubscription, error) {
+	if m.err != nil {
+		return PushSubscription{}, m.err
+	}
+	for _, subscription := range m.subscriptions {
+		if subscription.ID == id {
+			return subscription, nil
+		}
+	}
+	return PushSubscription{}, errRecordNotFound
+}
+
+func (m *mockPushSubscriptionModel) GetForUser(userID string) ([]PushSubscription, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	subscriptions := []PushSubscription{}
+	for _, subscription := range m.subscriptions {
+		if subscription.UserID == userID {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	return subscriptions, nil
+}
+
+func (m *mockPushSubscriptionModel) Delete(id string) error {
+	if m.err != nil {
+		return m.err
+	}
+	for i := range m.subscriptions {
+		if m.subscriptions[i].ID == id {
+			m.subscriptions = append(m.subscriptions[:i], m.subscriptions[i+1:]...)
+			return nil
+		}
+	}
+	return errRecordNotFound
+}
+
+// newTestApplication returns an application backed by the given events, with logging
+// discarded.
+func newTestApplication(t *testing.T, edmEvents []EdmEvent) *application {
+	t.Helper()
+
+	templateCache, err := newTemplateCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &application{
+		config:              config{env: "testing"},
+		logger:              log.New(io.Discard, "", 0),
+		dbSnippets:          &mockSnippetModel{edmEvents: edmEvents},
+		dbSyncStates:        &mockSyncStateModel{},
+		dbAPIKeys:           &mockAPIKeyModel{},
+		dbWatchlists:        &mockWatchlistModel{},
+		dbAlerts:            &mockAlertModel{},
+		dbSendLog:           &mockSendLogModel{},
+		dbDigests:           &mockDigestModel{},
+		dbWebhooks:          &mockWebhookModel{},
+		dbWebhookDeliveries: &mockWebhookDeliveryModel{},
+		dbRuns:              &mockRunModel{},
+		dbPushSubscriptions: &mockPushSubscriptionModel{},
+		apiKeyAuth:          newAPIKeyAuth(),
+		responseCache:       newResponseCache(),
+		templateCache:       templateCache,
+		runs:                newRunManager(),
+		changes:             newChangeHub(time.Now()),
+		syncNotify:          make(chan struct{}, 1),
+		searchIndex:         newSearchIndex(),
+		webhooks:            &webhookSender{client: http.DefaultClient, maxAttempts: 3},
+	}
+}
+
+// get sends a GET request for the given path through the application's routes and returns
+// the recorded response.
+func get(t *testing.T, app *application, path string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	app.routes().ServeHTTP(rr, r)
+
+	return rr
+}
@@ -7,4 +7,9 @@ type EdmEvent struct {
 	EventDate      string `json:"eventdate,omitempty"`
 	TicketUrl      string `json:"ticketurl,omitempty"`
 	ArtistImageUrl string `json:"artistimageurl,omitempty"`
+	Availability   string `json:"availability,omitempty"`
+	Sequence       int    `json:"sequence,omitempty"`
+	LastModified   string `json:"lastmodified,omitempty"`
+	FirstSeen      string `json:"firstseen,omitempty"`
+	Source         string `json:"source,omitempty"`
 }
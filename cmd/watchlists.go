@@ -0,0 +1,597 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	maxWatchlistsPerUser   = 20
+	maxArtistsPerWatchlist = 100
+	maxWatchlistNameLength = 100
+	maxWatchedArtistLength = 100
+)
+
+// Watchlist is a named list of artists a user wants to hear about. Users are identified by
+// their API key, so every key has its own watchlists and alerts. Alerts are also emailed to
+// Email when it's set.
+type Watchlist struct {
+	ID        string   `json:"id"`
+	UserID    string   `json:"-"`
+	Name      string   `json:"name"`
+	Artists   []string `json:"artists"`
+	Email     string   `json:"email,omitempty"`
+	CreatedAt string   `json:"created_at"`
+	UpdatedAt string   `json:"updated_at"`
+}
+
+// The kinds of alerts: an event was announced, or an event that was on sale sold out.
+const (
+	alertTypeAnnounced = "announced"
+	alertTypeSoldOut   = "sold_out"
+)
+
+var alertTypes = []string{alertTypeAnnounced, alertTypeSoldOut}
+
+// Alert tells a user about an event of an artist on one of their watchlists, when it's
+// announced or when it sells out. Its id is derived from the user, the event and the type, so
+// that an event is alerted once per user and type however many of their watchlists match it
+// and however often it's matched.
+type Alert struct {
+	ID             string   `json:"id"`
+	UserID         string   `json:"-"`
+	Type           string   `json:"type"`
+	WatchlistIDs   []string `json:"watchlist_ids"`
+	MatchedArtists []string `json:"matched_artists"`
+	EdmEvent       EdmEvent `json:"event"`
+	CreatedAt      string   `json:"created_at"`
+}
+
+type WatchlistModelInterface interface {
+	Insert(watchlist Watchlist) error
+	Get(id string) (Watchlist, error)
+	GetAll() ([]Watchlist, error)
+	GetForUser(userID string) ([]Watchlist, error)
+	Update(watchlist Watchlist) error
+	Delete(id string) error
+}
+
+type AlertModelInterface interface {
+	InsertNew(alerts []Alert) ([]Alert, error)
+	GetForUser(userID string) ([]Alert, error)
+}
+
+// WatchlistModel stores one document per watchlist, keyed by the watchlist's id.
+type WatchlistModel struct {
+	Client     *firestore.Client
+	Collection string
+}
+
+func (m *WatchlistModel) Insert(watchlist Watchlist) error {
+	ctx := context.Background()
+	_, err := m.Client.Collection(m.Collection).Doc(watchlist.ID).Create(ctx, watchlist)
+	if err != nil {
+		return fmt.Errorf("failed to insert watchlist: %v", err)
+	}
+	return nil
+}
+
+func (m *WatchlistModel) Get(id string) (Watchlist, error) {
+	ctx := context.Background()
+
+	doc, err := m.Client.Collection(m.Collection).Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return Watchlist{}, errRecordNotFound
+	}
+	if err != nil {
+		return Watchlist{}, fmt.Errorf("failed to get watchlist %s: %v", id, err)
+	}
+
+	var watchlist Watchlist
+	if err := doc.DataTo(&watchlist); err != nil {
+		return Watchlist{}, fmt.Errorf("failed to decode watchlist %s: %v", id, err)
+	}
+	return watchlist, nil
+}
+
+func (m *WatchlistModel) GetAll() ([]Watchlist, error) {
+	return m.query(m.Client.Collection(m.Collection).Query)
+}
+
+func (m *WatchlistModel) GetForUser(userID string) ([]Watchlist, error) {
+	return m.query(m.Client.Collection(m.Collection).Where("UserID", "==", userID))
+}
+
+func (m *WatchlistModel) query(q firestore.Query) ([]Watchlist, error) {
+	ctx := context.Background()
+	watchlists := []Watchlist{}
+
+	iter := q.Documents(ctx)
+	defer iter.Stop()
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate watchlists: %v", err)
+		}
+
+		var watchlist Watchlist
+		if err := doc.DataTo(&watchlist); err != nil {
+			return nil, fmt.Errorf("failed to decode watchlist %s: %v", doc.Ref.ID, err)
+		}
+		watchlists = append(watchlists, watchlist)
+	}
+
+	sort.Slice(watchlists, func(i, j int) bool {
+		return watchlists[i].CreatedAt < watchlists[j].CreatedAt
+	})
+
+	return watchlists, nil
+}
+
+func (m *WatchlistModel) Update(watchlist Watchlist) error {
+	ctx := context.Background()
+	_, err := m.Client.Collection(m.Collection).Doc(watchlist.ID).Set(ctx, watchlist)
+	if err != nil {
+		return fmt.Errorf("failed to update watchlist %s: %v", watchlist.ID, err)
+	}
+	return nil
+}
+
+func (m *WatchlistModel) Delete(id string) error {
+	ctx := context.Background()
+	_, err := m.Client.Collection(m.Collection).Doc(id).Delete(ctx, firestore.Exists)
+	if status.Code(err) == codes.NotFound {
+		return errRecordNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete watchlist %s: %v", id, err)
+	}
+	return nil
+}
+
+// AlertModel stores one document per alert, keyed by the alert's id.
+type AlertModel struct {
+	Client     *firestore.Client
+	Collection string
+}
+
+// InsertNew creates the alerts that don't exist yet and returns them. Creating a document
+// fails if its id is taken, which is what keeps a user from being alerted twice.
+func (m *AlertModel) InsertNew(alerts []Alert) ([]Alert, error) {
+	ctx := context.Background()
+	inserted := []Alert{}
+
+	for _, alert := range alerts {
+		_, err := m.Client.Collection(m.Collection).Doc(alert.ID).Create(ctx, alert)
+		if status.Code(err) == codes.AlreadyExists {
+			continue
+		}
+		if err != nil {
+			return inserted, fmt.Errorf("failed to insert alert %s: %v", alert.ID, err)
+		}
+		inserted = append(inserted, alert)
+	}
+
+	return inserted, nil
+}
+
+func (m *AlertModel) GetForUser(userID string) ([]Alert, error) {
+	ctx := context.Background()
+	alerts := []Alert{}
+
+	iter := m.Client.Collection(m.Collection).Where("UserID", "==", userID).Documents(ctx)
+	defer iter.Stop()
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate alerts: %v", err)
+		}
+
+		var alert Alert
+		if err := doc.DataTo(&alert); err != nil {
+			return nil, fmt.Errorf("failed to decode alert %s: %v", doc.Ref.ID, err)
+		}
+		// Alerts raised before they had a type were all announcements.
+		if alert.Type == "" {
+			alert.Type = alertTypeAnnounced
+		}
+		alerts = append(alerts, alert)
+	}
+
+	sortAlerts(alerts)
+
+	return alerts, nil
+}
+
+// sortAlerts puts the most recent alerts first, and the alerts raised together by event date.
+func sortAlerts(alerts []Alert) {
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].CreatedAt != alerts[j].CreatedAt {
+			return alerts[i].CreatedAt > alerts[j].CreatedAt
+		}
+		return alerts[i].EdmEvent.EventDate < alerts[j].EdmEvent.EventDate
+	})
+}
+
+// artistMatches reports whether the watched artist appears in the event's artist name as
+// whole words, ignoring case, accents and punctuation. "Tiësto" matches "TIESTO b2b Afrojack"
+// but "Kygo" doesn't match "Kygori".
+func artistMatches(watched []string, artistTokens []string) bool {
+	if len(watched) == 0 {
+		return false
+	}
+	for i := 0; i+len(watched) <= len(artistTokens); i++ {
+		if slices.Equal(artistTokens[i:i+len(watched)], watched) {
+			return true
+		}
+	}
+	return false
+}
+
+// alertID identifies the alert of a given type of a user for an event. Announcements keep the
+// ids they had before alerts had a type.
+func alertID(userID string, eventID string, alertType string) string {
+	key := userID + "\x00" + eventID
+	if alertType != alertTypeAnnounced {
+		key += "\x00" + alertType
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:16])
+}
+
+// matchWatchlists returns one alert of the given type per user and event for the events whose
+// artist is on one of the user's watchlists.
+func matchWatchlists(watchlists []Watchlist, edmEvents []EdmEvent, alertType string, now time.Time) []Alert {
+	alerts := []Alert{}
+	alertIndex := make(map[string]int)
+
+	for _, edmEvent := range edmEvents {
+		artistTokens := strings.Fields(normalizeSearchText(edmEvent.ArtistName))
+
+		for _, watchlist := range watchlists {
+			for _, artist := range watchlist.Artists {
+				if !artistMatches(strings.Fields(normalizeSearchText(artist)), artistTokens) {
+					continue
+				}
+
+				id := alertID(watchlist.UserID, edmEvent.Id, alertType)
+				i, ok := alertIndex[id]
+				if !ok {
+					i = len(alerts)
+					alertIndex[id] = i
+					alerts = append(alerts, Alert{
+						ID:        id,
+						UserID:    watchlist.UserID,
+						Type:      alertType,
+						EdmEvent:  edmEvent,
+						CreatedAt: now.UTC().Format(time.RFC3339),
+					})
+				}
+
+				alert := &alerts[i]
+				if !slices.Contains(alert.WatchlistIDs, watchlist.ID) {
+					alert.WatchlistIDs = append(alert.WatchlistIDs, watchlist.ID)
+				}
+				if !slices.Contains(alert.MatchedArtists, artist) {
+					alert.MatchedArtists = append(alert.MatchedArtists, artist)
+				}
+			}
+		}
+	}
+
+	return alerts
+}
+
+// alertWatchlists raises the alerts for the events a sync added and those that sold out, sends
+// them out and returns those that are new, events already alerted in an earlier run are skipped.
+// The alerts stored before an insert fails are still sent, since later runs skip them as
+// already alerted.
+func (app *application) alertWatchlists(created []EdmEvent, soldOut []EdmEvent) ([]Alert, error) {
+	if len(created) == 0 && len(soldOut) == 0 {
+		return nil, nil
+	}
+
+	watchlists, err := app.dbWatchlists.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	matched := matchWatchlists(watchlists, created, alertTypeAnnounced, now)
+	matched = append(matched, matchWatchlists(watchlists, soldOut, alertTypeSoldOut, now)...)
+
+	alerts, err := app.dbAlerts.InsertNew(matched)
+
+	app.emailAlerts(alerts, watchlists)
+	app.notifyTelegram(alerts)
+	app.notifyPush(alerts)
+
+	return alerts, err
+}
+
+func validateWatchlistEmail(v *validator, email string) {
+	if email != "" {
+		v.Check(len(email) <= 254, "email", "must not be more than 254 bytes long")
+		v.Check(Matches(email, EmailRX), "email", "must be a valid email address")
+	}
+}
+
+// readWatchlistInput validates a watchlist sent by a client, trimming the artists and dropping
+// those that are the same once normalized.
+func readWatchlistInput(v *validator, name string, artists []string) (string, []string) {
+	name = strings.TrimSpace(name)
+	v.Check(name != "", "name", "must be provided")
+	v.Check(len(name) <= maxWatchlistNameLength, "name", fmt.Sprintf("must not be more than %d bytes long", maxWatchlistNameLength))
+
+	v.Check(len(artists) > 0, "artists", "must contain at least one artist")
+	v.Check(len(artists) <= maxArtistsPerWatchlist, "artists", fmt.Sprintf("must not contain more than %d artists", maxArtistsPerWatchlist))
+
+	unique := []string{}
+	seen := make(map[string]bool)
+	for _, artist := range artists {
+		artist = strings.TrimSpace(artist)
+		normalized := strings.Join(strings.Fields(normalizeSearchText(artist)), " ")
+
+		v.Check(normalized != "", "artists", "must not contain names without a letter or digit")
+		v.Check(len(artist) <= maxWatchedArtistLength, "artists", fmt.Sprintf("must not contain names more than %d bytes long", maxWatchedArtistLength))
+
+		if normalized != "" && !seen[normalized] {
+			seen[normalized] = true
+			unique = append(unique, artist)
+		}
+	}
+
+	return name, unique
+}
+
+// userWatchlist returns the watchlist with the id of the request path if it belongs to the
+// user. Other users' watchlists are reported as not found.
+func (app *application) userWatchlist(r *http.Request, userID string) (Watchlist, error) {
+	watchlist, err := app.dbWatchlists.Get(r.PathValue("id"))
+	if err != nil {
+		return Watchlist{}, err
+	}
+	if watchlist.UserID != userID {
+		return Watchlist{}, errRecordNotFound
+	}
+	return watchlist, nil
+}
+
+func (app *application) listWatchlistsHandler(w http.ResponseWriter, r *http.Request) {
+	apiKey, _ := app.contextGetAPIKey(r)
+
+	watchlists, err := app.dbWatchlists.GetForUser(apiKey.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"watchlists": watchlists}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createWatchlistHandler(w http.ResponseWriter, r *http.Request) {
+	apiKey, _ := app.contextGetAPIKey(r)
+
+	var input struct {
+		Name    string   `json:"name"`
+		Artists []string `json:"artists"`
+		Email   string   `json:"email"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := newValidator()
+	name, artists := readWatchlistInput(v, input.Name, input.Artists)
+	email := strings.TrimSpace(input.Email)
+	validateWatchlistEmail(v, email)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	existing, err := app.dbWatchlists.GetForUser(apiKey.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if len(existing) >= maxWatchlistsPerUser {
+		v.AddError("watchlists", fmt.Sprintf("must not be more than %d per user", maxWatchlistsPerUser))
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	watchlist := Watchlist{
+		ID:        getGUID(),
+		UserID:    apiKey.ID,
+		Name:      name,
+		Artists:   artists,
+		Email:     email,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	err = app.dbWatchlists.Insert(watchlist)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", "/v1/watchlists/"+watchlist.ID)
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"watchlist": watchlist}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showWatchlistHandler(w http.ResponseWriter, r *http.Request) {
+	apiKey, _ := app.contextGetAPIKey(r)
+
+	watchlist, err := app.userWatchlist(r, apiKey.ID)
+	if err != nil {
+		if errors.Is(err, errRecordNotFound) {
+			app.notFoundResponse(w, r)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"watchlist": watchlist}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateWatchlistHandler replaces the name, artists or email of a watchlist. Those left out
+// keep their current value, an empty email stops the emails.
+func (app *application) updateWatchlistHandler(w http.ResponseWriter, r *http.Request) {
+	apiKey, _ := app.contextGetAPIKey(r)
+
+	watchlist, err := app.userWatchlist(r, apiKey.ID)
+	if err != nil {
+		if errors.Is(err, errRecordNotFound) {
+			app.notFoundResponse(w, r)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var input struct {
+		Name    *string  `json:"name"`
+		Artists []string `json:"artists"`
+		Email   *string  `json:"email"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	name, artists := watchlist.Name, watchlist.Artists
+	if input.Name != nil {
+		name = *input.Name
+	}
+	if input.Artists != nil {
+		artists = input.Artists
+	}
+
+	if input.Email != nil {
+		watchlist.Email = strings.TrimSpace(*input.Email)
+	}
+
+	v := newValidator()
+	watchlist.Name, watchlist.Artists = readWatchlistInput(v, name, artists)
+	validateWatchlistEmail(v, watchlist.Email)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	watchlist.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+
+	err = app.dbWatchlists.Update(watchlist)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"watchlist": watchlist}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteWatchlistHandler(w http.ResponseWriter, r *http.Request) {
+	apiKey, _ := app.contextGetAPIKey(r)
+
+	watchlist, err := app.userWatchlist(r, apiKey.ID)
+	if err == nil {
+		err = app.dbWatchlists.Delete(watchlist.ID)
+	}
+	if err != nil {
+		if errors.Is(err, errRecordNotFound) {
+			app.notFoundResponse(w, r)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "watchlist successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listAlertsHandler returns the user's alerts, the most recent first.
+func (app *application) listAlertsHandler(w http.ResponseWriter, r *http.Request) {
+	apiKey, _ := app.contextGetAPIKey(r)
+
+	qs := r.URL.Query()
+	v := newValidator()
+
+	filters := EventFilters{
+		Page:     app.readInt(qs, "page", 1, v),
+		PageSize: app.readInt(qs, "page_size", 50, v),
+	}
+	validateEventFilters(v, filters)
+
+	alertType := app.readString(qs, "type", "")
+	if alertType != "" {
+		v.Check(slices.Contains(alertTypes, alertType), "type", "must be one of "+strings.Join(alertTypes, ", "))
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	alerts, err := app.dbAlerts.GetForUser(apiKey.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if alertType != "" {
+		alerts = slices.DeleteFunc(alerts, func(alert Alert) bool { return alert.Type != alertType })
+	}
+
+	alerts, metadata := paginateItems(filters, alerts)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"alerts": alerts, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newWatchlistsTestApplication returns a test application with a single key, along with the
+// user id its watchlists and alerts are kept under.
+func newWatchlistsTestApplication(t *testing.T) (app *application, plaintext string, userID string) {
+	t.Helper()
+
+	plaintext, apiKey, err := generateAPIKey("tester", 100, 100, false, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app = newTestApplication(t, nil)
+	app.dbAPIKeys = &mockAPIKeyModel{apiKeys: []APIKey{apiKey}}
+
+	return app, plaintext, apiKey.ID
+}
+
+// TestArtistMatches tests the normalized whole word matching of watched artists
+func TestArtistMatches(t *testing.T) {
+	tests := []struct {
+		watched  string
+		artist   string
+		expected bool
+	}{
+		{watched: "Tiësto", artist: "TIESTO", expected: true},
+		{watched: "tiesto", artist: "Tiësto b2b Afrojack", expected: true},
+		{watched: "John Summit", artist: "John Summit & Friends", expected: true},
+		{watched: "Above & Beyond", artist: "Above and Beyond", expected: false},
+		{watched: "Above & Beyond", artist: "ABOVE & BEYOND", expected: true},
+		{watched: "Kygo", artist: "Kygori", expected: false},
+		{watched: "Summit John", artist: "John Summit", expected: false},
+		{watched: "DJ Snake", artist: "dj-snake", expected: true},
+		{watched: "", artist: "Tiësto", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.watched+"/"+tt.artist, func(t *testing.T) {
+			matched := artistMatches(strings.Fields(normalizeSearchText(tt.watched)), strings.Fields(normalizeSearchText(tt.artist)))
+			if matched != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, matched)
+			}
+		})
+	}
+}
+
+// TestMatchWatchlists tests that each user gets one alert per matching event
+func TestMatchWatchlists(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	watchlists := []Watchlist{
+		{ID: "w1", UserID: "alice", Artists: []string{"Tiësto", "Afrojack"}},
+		{ID: "w2", UserID: "alice", Artists: []string{"tiesto"}},
+		{ID: "w3", UserID: "bob", Artists: []string{"Afrojack"}},
+		{ID: "w4", UserID: "carol", Artists: []string{"Kygo"}},
+	}
+	edmEvents := []EdmEvent{
+		{Id: "e1", ArtistName: "Tiësto b2b Afrojack", ClubName: "XS"},
+		{Id: "e2", ArtistName: "John Summit", ClubName: "Omnia"},
+	}
+
+	alerts := matchWatchlists(watchlists, edmEvents, alertTypeAnnounced, now)
+	if len(alerts) != 2 {
+		t.Fatalf("Expected 2 alerts, got %d", len(alerts))
+	}
+
+	alice, bob := alerts[0], alerts[1]
+	if alice.UserID != "alice" || bob.UserID != "bob" {
+		t.Fatalf("Expected alerts for alice and bob, got %s and %s", alice.UserID, bob.UserID)
+	}
+	if alice.EdmEvent.Id != "e1" || bob.EdmEvent.Id != "e1" {
+		t.Errorf("Expected both alerts for e1, got %s and %s", alice.EdmEvent.Id, bob.EdmEvent.Id)
+	}
+	if strings.Join(alice.WatchlistIDs, ",") != "w1,w2" {
+		t.Errorf("Expected alice's alert to list watchlists w1,w2, got %v", alice.WatchlistIDs)
+	}
+	if strings.Join(alice.MatchedArtists, ",") != "Tiësto,Afrojack,tiesto" {
+		t.Errorf("Expected alice's alert to list the matched artists, got %v", alice.MatchedArtists)
+	}
+	if alice.ID != alertID("alice", "e1", alertTypeAnnounced) || alice.ID == bob.ID {
+		t.Errorf("Expected alert ids derived from the user and the event, got %s and %s", alice.ID, bob.ID)
+	}
+	if alice.CreatedAt != "2026-10-19T12:00:00Z" {
+		t.Errorf("Expected created at '2026-10-19T12:00:00Z', got '%s'", alice.CreatedAt)
+	}
+}
+
+// TestAlertWatchlists tests that matching the same events again doesn't alert twice
+func TestAlertWatchlists(t *testing.T) {
+	app := newTestApplication(t, nil)
+	app.dbWatchlists = &mockWatchlistModel{watchlists: []Watchlist{
+		{ID: "w1", UserID: "alice", Artists: []string{"Tiësto"}},
+	}}
+
+	created := []EdmEvent{{Id: "e1", ArtistName: "Tiesto", ClubName: "XS"}}
+
+	alerts, err := app.alertWatchlists(created, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 1 {
+		t.Fatalf("Expected 1 new alert, got %d", len(alerts))
+	}
+
+	alerts, err = app.alertWatchlists(created, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 0 {
+		t.Errorf("Expected no new alerts the second time, got %d", len(alerts))
+	}
+
+	stored, _ := app.dbAlerts.GetForUser("alice")
+	if len(stored) != 1 {
+		t.Errorf("Expected 1 stored alert, got %d", len(stored))
+	}
+
+	// Selling out is alerted once more, separately from the announcement.
+	soldOut := []EdmEvent{{Id: "e1", ArtistName: "Tiesto", ClubName: "XS", Availability: availabilitySoldOut}}
+	alerts, err = app.alertWatchlists(nil, soldOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 1 || alerts[0].Type != alertTypeSoldOut {
+		t.Fatalf("Expected 1 new sold out alert, got %+v", alerts)
+	}
+	if alerts[0].ID == alertID("alice", "e1", alertTypeAnnounced) {
+		t.Errorf("Expected the sold out alert to have its own id")
+	}
+
+	alerts, err = app.alertWatchlists(nil, soldOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 0 {
+		t.Errorf("Expected no new alerts when the event is found sold out again, got %d", len(alerts))
+	}
+}
+
+// TestAlertWatchlists_InsertFails tests that the alerts stored before an insert fails are
+// still sent, since later runs won't raise them again
+func TestAlertWatchlists_InsertFails(t *testing.T) {
+	stub := &telegramStub{}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	app := newTestApplication(t, nil)
+	app.telegram = newTelegramBot(server.URL, "test-token")
+	app.dbWatchlists = &mockWatchlistModel{watchlists: []Watchlist{
+		{ID: "w1", UserID: "telegram:42", Name: telegramWatchlistName, Artists: []string{"Tiësto"}},
+	}}
+	insertErr := errors.New("firestore unavailable")
+	app.dbAlerts = &mockAlertModel{insertErr: insertErr, failAfter: 1}
+
+	created := []EdmEvent{
+		{Id: "e1", ClubName: "xs nightclub", ArtistName: "tiësto", EventDate: "2026-11-20T00:00:00Z"},
+		{Id: "e2", ClubName: "omnia", ArtistName: "tiësto", EventDate: "2026-11-21T00:00:00Z"},
+	}
+
+	alerts, err := app.alertWatchlists(created, nil)
+	if !errors.Is(err, insertErr) {
+		t.Errorf("Expected the insert error, got %v", err)
+	}
+	if len(alerts) != 1 {
+		t.Fatalf("Expected the 1 stored alert, got %d", len(alerts))
+	}
+
+	if len(stub.messages) != 1 {
+		t.Fatalf("Expected the stored alert to be sent, got %d messages", len(stub.messages))
+	}
+	text, _ := stub.messages[0]["text"].(string)
+	if !strings.Contains(text, "xs nightclub") || strings.Contains(text, "omnia") {
+		t.Errorf("Expected the message to only list the stored alert's event, got '%s'", text)
+	}
+}
+
+// TestWatchlists_CRUD tests creating, reading, updating and deleting a watchlist
+func TestWatchlists_CRUD(t *testing.T) {
+	app, apiKey, _ := newWatchlistsTestApplication(t)
+
+	rr := adminRequest(t, app, http.MethodPost, "/v1/watchlists", apiKey, `{"name": "Favourites", "artists": ["Tiësto", " tiesto ", "John Summit"], "email": " fan@example.com "}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	var created struct {
+		Watchlist Watchlist `json:"watchlist"`
+	}
+	err := json.Unmarshal(rr.Body.Bytes(), &created)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(created.Watchlist.Artists, ",") != "Tiësto,John Summit" {
+		t.Errorf("Expected duplicate artists to be dropped, got %v", created.Watchlist.Artists)
+	}
+	if created.Watchlist.Email != "fan@example.com" {
+		t.Errorf("Expected email 'fan@example.com', got '%s'", created.Watchlist.Email)
+	}
+	location := rr.Header().Get("Location")
+	if location != "/v1/watchlists/"+created.Watchlist.ID {
+		t.Errorf("Expected Location '/v1/watchlists/%s', got '%s'", created.Watchlist.ID, location)
+	}
+
+	rr = adminRequest(t, app, http.MethodGet, "/v1/watchlists", apiKey, "")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), created.Watchlist.ID) {
+		t.Errorf("Expected the watchlist to be listed, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = adminRequest(t, app, http.MethodPatch, location, apiKey, `{"artists": ["Kygo"]}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	rr = adminRequest(t, app, http.MethodGet, location, apiKey, "")
+	if !strings.Contains(rr.Body.String(), `"name":"Favourites"`) || !strings.Contains(rr.Body.String(), `"artists":["Kygo"]`) {
+		t.Errorf("Expected the name to be kept and the artists replaced, got %s", rr.Body.String())
+	}
+
+	rr = adminRequest(t, app, http.MethodDelete, location, apiKey, "")
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	rr = adminRequest(t, app, http.MethodGet, location, apiKey, "")
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d after deleting, got %d", http.StatusNotFound, rr.Code)
+	}
+}
+
+// TestWatchlists_Negative tests requests that are refused
+func TestWatchlists_Negative(t *testing.T) {
+	app, apiKey, _ := newWatchlistsTestApplication(t)
+	app.dbWatchlists = &mockWatchlistModel{watchlists: []Watchlist{
+		{ID: "others", UserID: "someone-else", Name: "Theirs", Artists: []string{"Kygo"}},
+	}}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		apiKey         string
+		body           string
+		expectedStatus int
+	}{
+		{name: "No API key", method: http.MethodGet, path: "/v1/watchlists", expectedStatus: http.StatusUnauthorized},
+		{name: "No API key for alerts", method: http.MethodGet, path: "/v1/alerts", expectedStatus: http.StatusUnauthorized},
+		{name: "Missing name", method: http.MethodPost, path: "/v1/watchlists", apiKey: apiKey, body: `{"artists": ["Kygo"]}`, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "No artists", method: http.MethodPost, path: "/v1/watchlists", apiKey: apiKey, body: `{"name": "Empty", "artists": []}`, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Punctuation only artist", method: http.MethodPost, path: "/v1/watchlists", apiKey: apiKey, body: `{"name": "Odd", "artists": ["!!!"]}`, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Invalid email", method: http.MethodPost, path: "/v1/watchlists", apiKey: apiKey, body: `{"name": "Mail me", "artists": ["Kygo"], "email": "not-an-email"}`, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Malformed JSON", method: http.MethodPost, path: "/v1/watchlists", apiKey: apiKey, body: `{"name": `, expectedStatus: http.StatusBadRequest},
+		{name: "Someone else's watchlist", method: http.MethodGet, path: "/v1/watchlists/others", apiKey: apiKey, expectedStatus: http.StatusNotFound},
+		{name: "Deleting someone else's watchlist", method: http.MethodDelete, path: "/v1/watchlists/others", apiKey: apiKey, expectedStatus: http.StatusNotFound},
+		{name: "Unknown watchlist", method: http.MethodPatch, path: "/v1/watchlists/unknown", apiKey: apiKey, body: `{}`, expectedStatus: http.StatusNotFound},
+		{name: "Invalid page", method: http.MethodGet, path: "/v1/alerts?page=0", apiKey: apiKey, expectedStatus: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := adminRequest(t, app, tt.method, tt.path, tt.apiKey, tt.body)
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+// TestListAlertsHandler tests that users only see their own alerts
+func TestListAlertsHandler(t *testing.T) {
+	app, apiKey, userID := newWatchlistsTestApplication(t)
+
+	app.dbAlerts = &mockAlertModel{alerts: []Alert{
+		{ID: "a1", UserID: userID, Type: alertTypeAnnounced, EdmEvent: EdmEvent{Id: "e1", ArtistName: "Tiësto"}, CreatedAt: "2026-10-18T06:00:00Z"},
+		{ID: "a2", UserID: userID, Type: alertTypeSoldOut, EdmEvent: EdmEvent{Id: "e2", ArtistName: "Kygo"}, CreatedAt: "2026-10-19T06:00:00Z"},
+		{ID: "a3", UserID: "someone-else", Type: alertTypeAnnounced, EdmEvent: EdmEvent{Id: "e3", ArtistName: "Kygo"}, CreatedAt: "2026-10-19T06:00:00Z"},
+	}}
+
+	rr := adminRequest(t, app, http.MethodGet, "/v1/alerts", apiKey, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var response struct {
+		Alerts   []Alert  `json:"alerts"`
+		Metadata Metadata `json:"metadata"`
+	}
+	err := json.Unmarshal(rr.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(response.Alerts) != 2 {
+		t.Fatalf("Expected 2 alerts, got %d", len(response.Alerts))
+	}
+	if response.Alerts[0].ID != "a2" || response.Alerts[1].ID != "a1" {
+		t.Errorf("Expected the most recent alert first, got %s, %s", response.Alerts[0].ID, response.Alerts[1].ID)
+	}
+	if response.Metadata.TotalRecords != 2 {
+		t.Errorf("Expected 2 total records, got %d", response.Metadata.TotalRecords)
+	}
+
+	rr = adminRequest(t, app, http.MethodGet, "/v1/alerts?type=sold_out", apiKey, "")
+	response.Alerts = nil
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Alerts) != 1 || response.Alerts[0].ID != "a2" {
+		t.Errorf("Expected only the sold out alert, got %+v", response.Alerts)
+	}
+
+	rr = adminRequest(t, app, http.MethodGet, "/v1/alerts?type=cancelled", apiKey, "")
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status %d for an unknown type, got %d", http.StatusUnprocessableEntity, rr.Code)
+	}
+}
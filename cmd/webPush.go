@@ -0,0 +1,698 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The push services browsers subscribe with: Chrome and Edge through FCM, Firefox, Edge on
+// Windows and Safari. Subscriptions to any other endpoint are refused so that the API can't
+// be used to make requests to arbitrary URLs.
+var defaultPushServices = []string{
+	"https://fcm.googleapis.com",
+	"https://updates.push.services.mozilla.com",
+	"https://*.notify.windows.com",
+	"https://web.push.apple.com",
+}
+
+const (
+	maxPushSubscriptionsPerUser = 10
+	maxPushEndpointLength       = 2048
+	// Seconds the push service keeps a message for a browser that's offline.
+	pushTTL = 24 * 60 * 60
+	// The VAPID token is valid for this long, push services refuse more than a day.
+	vapidTokenLifetime = 12 * time.Hour
+	// Every message is a single record of the aes128gcm content coding, push services are
+	// only required to accept 4096 bytes once encrypted.
+	pushRecordSize       = 4096
+	maxPushPayloadLength = 3993
+	// Events listed by name in a notification about several of them.
+	pushMaxArtists = 3
+)
+
+// The lengths of the keys of a subscription, an uncompressed P-256 point and the auth secret.
+const (
+	pushP256dhLength = 65
+	pushAuthLength   = 16
+)
+
+var (
+	errPushNotConfigured    = errors.New("web push is not configured on this server")
+	errPushSubscriptionGone = errors.New("push subscription has expired or was unsubscribed")
+)
+
+// PushSubscription is a browser subscribed to the alerts of a user's watchlists, as returned by
+// the browser's PushManager.subscribe. The keys encrypt the messages so that only the browser
+// can read them.
+type PushSubscription struct {
+	ID        string               `json:"id"`
+	UserID    string               `json:"-"`
+	Endpoint  string               `json:"endpoint"`
+	Keys      PushSubscriptionKeys `json:"-"`
+	CreatedAt string               `json:"created_at"`
+}
+
+// PushSubscriptionKeys are the base64url encoded keys of a subscription.
+type PushSubscriptionKeys struct {
+	P256dh string `json:"p256dh"`
+	Auth   string `json:"auth"`
+}
+
+// pushNotification is the payload a subscription receives, for the service worker to show.
+type pushNotification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	URL   string `json:"url"`
+	Tag   string `json:"tag"`
+}
+
+type PushSubscriptionModelInterface interface {
+	Insert(subscription PushSubscription) error
+	Get(id string) (PushSubscription, error)
+	GetForUser(userID string) ([]PushSubscription, error)
+	Delete(id string) error
+}
+
+// PushSubscriptionModel stores one document per subscription, keyed by the subscription's id.
+type PushSubscriptionModel struct {
+	Client     *firestore.Client
+	Collection string
+}
+
+// Insert stores the subscription, replacing the one with the same id.
+func (m *PushSubscriptionModel) Insert(subscription PushSubscription) error {
+	ctx := context.Background()
+	_, err := m.Client.Collection(m.Collection).Doc(subscription.ID).Set(ctx, subscription)
+	if err != nil {
+		return fmt.Errorf("failed to insert push subscription: %v", err)
+	}
+	return nil
+}
+
+func (m *PushSubscriptionModel) Get(id string) (PushSubscription, error) {
+	ctx := context.Background()
+
+	doc, err := m.Client.Collection(m.Collection).Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return PushSubscription{}, errRecordNotFound
+	}
+	if err != nil {
+		return PushSubscription{}, fmt.Errorf("failed to get push subscription %s: %v", id, err)
+	}
+
+	var subscription PushSubscription
+	if err := doc.DataTo(&subscription); err != nil {
+		return PushSubscription{}, fmt.Errorf("failed to decode push subscription %s: %v", id, err)
+	}
+	return subscription, nil
+}
+
+func (m *PushSubscriptionModel) GetForUser(userID string) ([]PushSubscription, error) {
+	ctx := context.Background()
+	subscriptions := []PushSubscription{}
+
+	iter := m.Client.Collection(m.Collection).Where("UserID", "==", userID).Documents(ctx)
+	defer iter.Stop()
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate push subscriptions: %v", err)
+		}
+
+		var subscription PushSubscription
+		if err := doc.DataTo(&subscription); err != nil {
+			return nil, fmt.Errorf("failed to decode push subscription %s: %v", doc.Ref.ID, err)
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return subscriptions, nil
+}
+
+func (m *PushSubscriptionModel) Delete(id string) error {
+	ctx := context.Background()
+	_, err := m.Client.Collection(m.Collection).Doc(id).Delete(ctx, firestore.Exists)
+	if status.Code(err) == codes.NotFound {
+		return errRecordNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete push subscription %s: %v", id, err)
+	}
+	return nil
+}
+
+// webPusher sends encrypted Web Push messages (RFC 8291), identifying us to the push services
+// with a VAPID key (RFC 8292).
+type webPusher struct {
+	client     *http.Client
+	privateKey *ecdsa.PrivateKey
+	// publicKey is the uncompressed point browsers subscribe with as applicationServerKey.
+	publicKey []byte
+	subject   string
+	services  []corsOrigin
+}
+
+// newWebPusher parses the base64url encoded VAPID private key, a P-256 scalar, and the push
+// services subscriptions may point at, which use the syntax of CORS origins.
+func newWebPusher(privateKey string, subject string, services []string) (*webPusher, error) {
+	key, err := parseVAPIDPrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(subject, "mailto:") && !strings.HasPrefix(subject, "https://") {
+		return nil, fmt.Errorf("invalid VAPID subject %q, expected a mailto: or https:// URL push services can reach us at", subject)
+	}
+
+	pusher := &webPusher{
+		client:    &http.Client{Timeout: 10 * time.Second},
+		publicKey: key.PublicKey().Bytes(),
+		subject:   subject,
+	}
+
+	pusher.privateKey = &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(pusher.publicKey[1:33]),
+			Y:     new(big.Int).SetBytes(pusher.publicKey[33:]),
+		},
+		D: new(big.Int).SetBytes(key.Bytes()),
+	}
+
+	for _, service := range services {
+		origin, err := parseCORSOrigin(service)
+		if err != nil {
+			return nil, fmt.Errorf("invalid push service %q, expected scheme://host[:port]", service)
+		}
+		pusher.services = append(pusher.services, origin)
+	}
+
+	return pusher, nil
+}
+
+func parseVAPIDPrivateKey(privateKey string) (*ecdh.PrivateKey, error) {
+	scalar, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(privateKey, "="))
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key, expected base64url: %v", err)
+	}
+	key, err := ecdh.P256().NewPrivateKey(scalar)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %v", err)
+	}
+	return key, nil
+}
+
+// allows reports whether a subscription's endpoint is on one of the push services.
+func (p *webPusher) allows(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return false
+	}
+	for _, service := range p.services {
+		if service.matches(u.Scheme + "://" + u.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+// vapidAuthorization returns the Authorization header of a request to the push service of the
+// endpoint: a JWT signed with the VAPID key for the service's origin, along with the public key.
+func (p *webPusher) vapidAuthorization(endpoint string, now time.Time) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	header, err := json.Marshal(map[string]string{"typ": "JWT", "alg": "ES256"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": now.Add(vapidTokenLifetime).Unix(),
+		"sub": p.subject,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, p.privateKey, hash[:])
+	if err != nil {
+		return "", err
+	}
+
+	// ES256 signatures are r and s as two 32 byte big-endian numbers, not ASN.1.
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	token := unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)
+	return fmt.Sprintf("vapid t=%s, k=%s", token, base64.RawURLEncoding.EncodeToString(p.publicKey)), nil
+}
+
+// send encrypts the payload for the subscription and POSTs it to its push service. A
+// subscription the push service no longer knows is reported with errPushSubscriptionGone.
+func (p *webPusher) send(subscription PushSubscription, payload []byte, now time.Time) error {
+	uaPublic, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(subscription.Keys.P256dh, "="))
+	if err != nil {
+		return fmt.Errorf("invalid p256dh key: %v", err)
+	}
+	authSecret, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(subscription.Keys.Auth, "="))
+	if err != nil {
+		return fmt.Errorf("invalid auth secret: %v", err)
+	}
+
+	// Every message is encrypted with a key pair and salt of its own.
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	salt := make([]byte, 16)
+	_, err = rand.Read(salt)
+	if err != nil {
+		return err
+	}
+
+	body, err := encryptPushPayload(payload, uaPublic, authSecret, asPrivate, salt)
+	if err != nil {
+		return err
+	}
+
+	authorization, err := p.vapidAuthorization(subscription.Endpoint, now)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, subscription.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", fmt.Sprint(pushTTL))
+	req.Header.Set("Urgency", "normal")
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	switch {
+	case res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusGone:
+		return errPushSubscriptionGone
+	case res.StatusCode < 200 || res.StatusCode > 299:
+		return fmt.Errorf("push service responded with %s", res.Status)
+	}
+	return nil
+}
+
+// encryptPushPayload encrypts a message for the browser whose public key and auth secret are
+// given (RFC 8291), as a single record of the aes128gcm content coding (RFC 8188). The
+// header carries the salt and our public key, from which the browser derives the same key.
+func encryptPushPayload(payload []byte, uaPublic []byte, authSecret []byte, asPrivate *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+	if len(payload) > maxPushPayloadLength {
+		return nil, fmt.Errorf("push payload is %d bytes, must not be more than %d", len(payload), maxPushPayloadLength)
+	}
+
+	uaKey, err := ecdh.P256().NewPublicKey(uaPublic)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %v", err)
+	}
+	ecdhSecret, err := asPrivate.ECDH(uaKey)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asPrivate.PublicKey().Bytes()
+
+	keyInfo := append([]byte("WebPush: info\x00"), uaPublic...)
+	keyInfo = append(keyInfo, asPublic...)
+	ikm := hkdfExpand(hkdfExtract(authSecret, ecdhSecret), keyInfo, 32)
+
+	prk := hkdfExtract(salt, ikm)
+	cek := hkdfExpand(prk, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdfExpand(prk, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, 16+4+1+len(asPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, pushRecordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+
+	// The 0x02 delimiter marks the last, and here only, record.
+	record := append(append([]byte{}, payload...), 0x02)
+	return gcm.Seal(header, nonce, record, nil), nil
+}
+
+func hkdfExtract(salt []byte, ikm []byte) []byte {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(ikm)
+	return mac.Sum(nil)
+}
+
+// hkdfExpand only derives keys up to the 32 bytes of a single SHA-256 block, which is all
+// Web Push needs.
+func hkdfExpand(prk []byte, info []byte, length int) []byte {
+	mac := hmac.New(sha256.New, prk)
+	mac.Write(info)
+	mac.Write([]byte{0x01})
+	return mac.Sum(nil)[:length]
+}
+
+// pushAlertNotification tells a user about the dates newly announced by the artists they follow.
+func pushAlertNotification(alerts []Alert) pushNotification {
+	if len(alerts) == 1 {
+		edmEvent := alerts[0].EdmEvent
+		return pushNotification{
+			Title: truncate(edmEvent.ArtistName, 100) + " is coming to Las Vegas",
+			Body:  edmEvent.ClubName + ", " + eventWhen(edmEvent),
+			URL:   "/artists/" + url.PathEscape(strings.ToLower(edmEvent.ArtistName)),
+			Tag:   alerts[0].ID,
+		}
+	}
+
+	artists := []string{}
+	for i, alert := range alerts {
+		if i == pushMaxArtists {
+			artists = append(artists, fmt.Sprintf("and %d more", len(alerts)-pushMaxArtists))
+			break
+		}
+		artists = append(artists, truncate(alert.EdmEvent.ArtistName, 100))
+	}
+
+	return pushNotification{
+		Title: fmt.Sprintf("%d new Las Vegas dates from artists you follow", len(alerts)),
+		Body:  strings.Join(artists, ", "),
+		URL:   "/",
+		Tag:   "announced",
+	}
+}
+
+// notifyPush sends a push message to every browser subscribed by a user with newly announced
+// alerts, a single one listing all of them. Subscriptions their push service no longer knows
+// are deleted. Each message is recorded in the send log whether it went out or not.
+func (app *application) notifyPush(alerts []Alert) {
+	if app.push == nil {
+		return
+	}
+
+	userAlerts := make(map[string][]Alert)
+	users := []string{}
+	for _, alert := range alerts {
+		if alert.Type != alertTypeAnnounced {
+			continue
+		}
+		if _, ok := userAlerts[alert.UserID]; !ok {
+			users = append(users, alert.UserID)
+		}
+		userAlerts[alert.UserID] = append(userAlerts[alert.UserID], alert)
+	}
+
+	for _, userID := range users {
+		subscriptions, err := app.dbPushSubscriptions.GetForUser(userID)
+		if err != nil {
+			app.logger.Printf("failed to get the push subscriptions of %s: %v", userID, err)
+			continue
+		}
+		if len(subscriptions) == 0 {
+			continue
+		}
+
+		payload, err := json.Marshal(pushAlertNotification(userAlerts[userID]))
+		if err != nil {
+			app.logger.Printf("failed to encode push notification: %v", err)
+			continue
+		}
+
+		alertIDs := []string{}
+		for _, alert := range userAlerts[userID] {
+			alertIDs = append(alertIDs, alert.ID)
+		}
+
+		for _, subscription := range subscriptions {
+			entry := SendLogEntry{
+				ID:        getGUID(),
+				Channel:   channelPush,
+				Recipient: subscription.ID,
+				AlertIDs:  alertIDs,
+				Status:    sendStatusSent,
+				Attempts:  1,
+				CreatedAt: time.Now().UTC().Format(time.RFC3339),
+			}
+
+			err := app.push.send(subscription, payload, time.Now())
+			if err != nil {
+				app.logger.Printf("failed to push %d alerts to subscription %s: %v", len(alertIDs), subscription.ID, err)
+				entry.Status = sendStatusFailed
+				entry.Error = err.Error()
+			}
+
+			if errors.Is(err, errPushSubscriptionGone) {
+				err := app.dbPushSubscriptions.Delete(subscription.ID)
+				if err != nil && !errors.Is(err, errRecordNotFound) {
+					app.logger.Printf("failed to delete push subscription %s: %v", subscription.ID, err)
+				}
+			}
+
+			err = app.dbSendLog.Insert(entry)
+			if err != nil {
+				app.logger.Printf("failed to record push to subscription %s: %v", subscription.ID, err)
+			}
+		}
+	}
+}
+
+// validatePushSubscription checks the endpoint is on a push service we send to and that the
+// keys are those of a browser's subscription.
+func (app *application) validatePushSubscription(v *validator, endpoint string, keys PushSubscriptionKeys) {
+	v.Check(endpoint != "", "endpoint", "must be provided")
+	v.Check(len(endpoint) <= maxPushEndpointLength, "endpoint", fmt.Sprintf("must not be more than %d bytes long", maxPushEndpointLength))
+	if endpoint != "" {
+		u, err := url.Parse(endpoint)
+		v.Check(err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != "" && u.User == nil, "endpoint", "must be an absolute http or https URL")
+		v.Check(app.push.allows(endpoint), "endpoint", "must be on a supported push service")
+	}
+
+	p256dh, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(keys.P256dh, "="))
+	v.Check(err == nil && len(p256dh) == pushP256dhLength, "keys.p256dh", fmt.Sprintf("must be a base64url encoded %d byte key", pushP256dhLength))
+	if err == nil && len(p256dh) == pushP256dhLength {
+		_, err = ecdh.P256().NewPublicKey(p256dh)
+		v.Check(err == nil, "keys.p256dh", "must be a point on the P-256 curve")
+	}
+
+	auth, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(keys.Auth, "="))
+	v.Check(err == nil && len(auth) == pushAuthLength, "keys.auth", fmt.Sprintf("must be a base64url encoded %d byte secret", pushAuthLength))
+}
+
+// vapidPublicKeyHandler returns the key browsers pass to PushManager.subscribe as the
+// applicationServerKey.
+func (app *application) vapidPublicKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if app.push == nil {
+		app.errorResponse(w, r, http.StatusNotFound, errPushNotConfigured.Error())
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"public_key": base64.RawURLEncoding.EncodeToString(app.push.publicKey)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// userPushSubscription returns the subscription with the id of the request path if it belongs
+// to the user. Other users' subscriptions are reported as not found.
+func (app *application) userPushSubscription(r *http.Request, userID string) (PushSubscription, error) {
+	subscription, err := app.dbPushSubscriptions.Get(r.PathValue("id"))
+	if err != nil {
+		return PushSubscription{}, err
+	}
+	if subscription.UserID != userID {
+		return PushSubscription{}, errRecordNotFound
+	}
+	return subscription, nil
+}
+
+func (app *application) listPushSubscriptionsHandler(w http.ResponseWriter, r *http.Request) {
+	apiKey, _ := app.contextGetAPIKey(r)
+
+	subscriptions, err := app.dbPushSubscriptions.GetForUser(apiKey.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"subscriptions": subscriptions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createPushSubscriptionHandler registers a browser for the alerts of the user's watchlists.
+// The body is the browser's PushSubscription as JSON, a browser that subscribes again with the
+// same endpoint replaces its keys.
+func (app *application) createPushSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	apiKey, _ := app.contextGetAPIKey(r)
+
+	if app.push == nil {
+		app.errorResponse(w, r, http.StatusNotFound, errPushNotConfigured.Error())
+		return
+	}
+
+	var input struct {
+		Endpoint       string               `json:"endpoint"`
+		ExpirationTime *int64               `json:"expirationTime"`
+		Keys           PushSubscriptionKeys `json:"keys"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	input.Endpoint = strings.TrimSpace(input.Endpoint)
+
+	v := newValidator()
+	app.validatePushSubscription(v, input.Endpoint, input.Keys)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	existing, err := app.dbPushSubscriptions.GetForUser(apiKey.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	subscription := PushSubscription{
+		ID:        getGUID(),
+		UserID:    apiKey.ID,
+		Endpoint:  input.Endpoint,
+		Keys:      input.Keys,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	responseStatus := http.StatusCreated
+	for _, s := range existing {
+		if s.Endpoint == input.Endpoint {
+			subscription.ID = s.ID
+			responseStatus = http.StatusOK
+		}
+	}
+	if responseStatus == http.StatusCreated && len(existing) >= maxPushSubscriptionsPerUser {
+		v.AddError("subscriptions", fmt.Sprintf("must not be more than %d per user", maxPushSubscriptionsPerUser))
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.dbPushSubscriptions.Insert(subscription)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", "/v1/push/subscriptions/"+subscription.ID)
+
+	err = app.writeJSON(w, responseStatus, envelope{"subscription": subscription}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deletePushSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	apiKey, _ := app.contextGetAPIKey(r)
+
+	subscription, err := app.userPushSubscription(r, apiKey.ID)
+	if err == nil {
+		err = app.dbPushSubscriptions.Delete(subscription.ID)
+	}
+	if err != nil {
+		if errors.Is(err, errRecordNotFound) {
+			app.notFoundResponse(w, r)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "push subscription successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+const vapidUsage = "usage: vapid generate | vapid public"
+
+// vapidCommand generates the key pair push messages are sent with, or prints the public key of
+// the configured one for the PWA to subscribe with.
+func vapidCommand(args []string, privateKey string, out io.Writer) error {
+	if len(args) != 1 {
+		return errors.New(vapidUsage)
+	}
+
+	switch args[0] {
+	case "generate":
+		key, err := ecdh.P256().GenerateKey(rand.Reader)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "VAPID_PRIVATE_KEY=%s\n", base64.RawURLEncoding.EncodeToString(key.Bytes()))
+		fmt.Fprintf(out, "VAPID_PUBLIC_KEY=%s\n", base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()))
+		return nil
+	case "public":
+		if privateKey == "" {
+			return errors.New("no VAPID private key, set -vapid-private-key or $VAPID_PRIVATE_KEY")
+		}
+		key, err := parseVAPIDPrivateKey(privateKey)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()))
+		return nil
+	default:
+		return fmt.Errorf("unknown vapid command %q, %s", args[0], vapidUsage)
+	}
+}
@@ -0,0 +1,457 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func decodeBase64URL(t *testing.T, s string) []byte {
+	t.Helper()
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// pushBrowser is the receiving end of a subscription, holding the keys a browser would.
+type pushBrowser struct {
+	private    *ecdh.PrivateKey
+	authSecret []byte
+}
+
+func newPushBrowser(t *testing.T) pushBrowser {
+	t.Helper()
+
+	private, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authSecret := make([]byte, pushAuthLength)
+	rand.Read(authSecret)
+
+	return pushBrowser{private: private, authSecret: authSecret}
+}
+
+func (b pushBrowser) keys() PushSubscriptionKeys {
+	return PushSubscriptionKeys{
+		P256dh: base64.RawURLEncoding.EncodeToString(b.private.PublicKey().Bytes()),
+		Auth:   base64.RawURLEncoding.EncodeToString(b.authSecret),
+	}
+}
+
+// decrypt reverses encryptPushPayload the way a browser does, from the salt and the public
+// key in the header of the message.
+func (b pushBrowser) decrypt(t *testing.T, body []byte) []byte {
+	t.Helper()
+
+	if len(body) < 21 || len(body) < 21+int(body[20]) {
+		t.Fatalf("Expected an aes128gcm header, got %d bytes", len(body))
+	}
+	salt := body[:16]
+	if rs := binary.BigEndian.Uint32(body[16:20]); rs != pushRecordSize {
+		t.Errorf("Expected a record size of %d, got %d", pushRecordSize, rs)
+	}
+	asPublic := body[21 : 21+int(body[20])]
+	ciphertext := body[21+int(body[20]):]
+
+	asKey, err := ecdh.P256().NewPublicKey(asPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdhSecret, err := b.private.ECDH(asKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyInfo := append([]byte("WebPush: info\x00"), b.private.PublicKey().Bytes()...)
+	keyInfo = append(keyInfo, asPublic...)
+	ikm := hkdfExpand(hkdfExtract(b.authSecret, ecdhSecret), keyInfo, 32)
+	prk := hkdfExtract(salt, ikm)
+
+	block, err := aes.NewCipher(hkdfExpand(prk, []byte("Content-Encoding: aes128gcm\x00"), 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := gcm.Open(nil, hkdfExpand(prk, []byte("Content-Encoding: nonce\x00"), 12), ciphertext, nil)
+	if err != nil {
+		t.Fatalf("Expected the message to decrypt, got %v", err)
+	}
+	if len(record) == 0 || record[len(record)-1] != 0x02 {
+		t.Fatalf("Expected the last record delimiter, got %x", record)
+	}
+	return record[:len(record)-1]
+}
+
+// verifyVAPID checks the Authorization header of a push request is signed with the public key
+// it carries, for the origin of the push service, and returns that key.
+func verifyVAPID(t *testing.T, authorization string, origin string) string {
+	t.Helper()
+
+	params, ok := strings.CutPrefix(authorization, "vapid ")
+	if !ok {
+		t.Fatalf("Expected a vapid Authorization header, got '%s'", authorization)
+	}
+	var token, publicKey string
+	for _, param := range strings.Split(params, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		switch name {
+		case "t":
+			token = value
+		case "k":
+			publicKey = value
+		}
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("Expected a JWT, got '%s'", token)
+	}
+
+	key := decodeBase64URL(t, publicKey)
+	verifier := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(key[1:33]), Y: new(big.Int).SetBytes(key[33:])}
+	signature := decodeBase64URL(t, parts[2])
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if len(signature) != 64 || !ecdsa.Verify(verifier, hash[:], new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])) {
+		t.Errorf("Expected a valid ES256 signature")
+	}
+
+	var claims struct {
+		Aud string `json:"aud"`
+		Exp int64  `json:"exp"`
+		Sub string `json:"sub"`
+	}
+	if err := json.Unmarshal(decodeBase64URL(t, parts[1]), &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.Aud != origin || claims.Sub != "mailto:ops@example.com" || claims.Exp <= time.Now().Unix() || claims.Exp > time.Now().Add(24*time.Hour).Unix() {
+		t.Errorf("Expected claims for %s expiring within a day, got %+v", origin, claims)
+	}
+	return publicKey
+}
+
+// pushServiceStub is a test push service decrypting the messages sent to the subscriptions of
+// its browsers. Subscriptions without a browser are answered with 410 Gone.
+type pushServiceStub struct {
+	t        *testing.T
+	origin   string
+	browsers map[string]pushBrowser
+
+	mu       sync.Mutex
+	received map[string][]pushNotification
+}
+
+func (s *pushServiceStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	browser, ok := s.browsers[r.URL.Path]
+	if !ok {
+		w.WriteHeader(http.StatusGone)
+		return
+	}
+
+	verifyVAPID(s.t, r.Header.Get("Authorization"), s.origin)
+	if r.Header.Get("Content-Encoding") != "aes128gcm" || r.Header.Get("TTL") == "" {
+		s.t.Errorf("Expected aes128gcm with a TTL, got %v", r.Header)
+	}
+
+	var notification pushNotification
+	if err := json.Unmarshal(browser.decrypt(s.t, body), &notification); err != nil {
+		s.t.Error(err)
+	}
+
+	s.mu.Lock()
+	s.received[r.URL.Path] = append(s.received[r.URL.Path], notification)
+	s.mu.Unlock()
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func newTestWebPusher(t *testing.T, services ...string) *webPusher {
+	t.Helper()
+
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pusher, err := newWebPusher(base64.RawURLEncoding.EncodeToString(key.Bytes()), "mailto:ops@example.com", services)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pusher
+}
+
+// TestEncryptPushPayload tests the encryption against the example of RFC 8291, Appendix A
+func TestEncryptPushPayload(t *testing.T) {
+	asPrivate, err := ecdh.P256().NewPrivateKey(decodeBase64URL(t, "yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uaPublic := decodeBase64URL(t, "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4")
+	authSecret := decodeBase64URL(t, "BTBZMqHH6r4Tts7J_aSIgg")
+	salt := decodeBase64URL(t, "DGv6ra1nlYgDCS1FRnbzlw")
+
+	body, err := encryptPushPayload([]byte("When I grow up, I want to be a watermelon"), uaPublic, authSecret, asPrivate, salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"
+	if got := base64.RawURLEncoding.EncodeToString(body); got != expected {
+		t.Errorf("Expected the message of RFC 8291, got '%s'", got)
+	}
+
+	_, err = encryptPushPayload(bytes.Repeat([]byte("a"), maxPushPayloadLength+1), uaPublic, authSecret, asPrivate, salt)
+	if err == nil {
+		t.Errorf("Expected an error for a payload too long for a single record")
+	}
+}
+
+// TestNotifyPush tests that followers' browsers get an encrypted message about announced
+// dates, and that subscriptions the push service no longer knows are dropped
+func TestNotifyPush(t *testing.T) {
+	stub := &pushServiceStub{t: t, browsers: map[string]pushBrowser{}, received: map[string][]pushNotification{}}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+	stub.origin = server.URL
+
+	laptop, phone := newPushBrowser(t), newPushBrowser(t)
+	stub.browsers["/push/laptop"] = laptop
+	stub.browsers["/push/phone"] = phone
+
+	app := newTestApplication(t, nil)
+	app.push = newTestWebPusher(t, server.URL)
+	subscriptions := &mockPushSubscriptionModel{subscriptions: []PushSubscription{
+		{ID: "laptop", UserID: "user-1", Endpoint: server.URL + "/push/laptop", Keys: laptop.keys()},
+		{ID: "expired", UserID: "user-1", Endpoint: server.URL + "/push/expired", Keys: laptop.keys()},
+		{ID: "phone", UserID: "user-2", Endpoint: server.URL + "/push/phone", Keys: phone.keys()},
+	}}
+	app.dbPushSubscriptions = subscriptions
+
+	alerts := []Alert{
+		{ID: "a1", UserID: "user-1", Type: alertTypeAnnounced, EdmEvent: EdmEvent{ArtistName: "John Summit", ClubName: "xs nightclub", EventDate: "2026-11-06T22:00:00Z"}},
+		{ID: "a2", UserID: "user-2", Type: alertTypeAnnounced, EdmEvent: EdmEvent{ArtistName: "Tiësto", ClubName: "zouk nightclub", EventDate: "2026-11-07T22:00:00Z"}},
+		{ID: "a3", UserID: "user-2", Type: alertTypeAnnounced, EdmEvent: EdmEvent{ArtistName: "Kaskade", ClubName: "xs nightclub", EventDate: "2026-11-08T22:00:00Z"}},
+		{ID: "a4", UserID: "user-1", Type: alertTypeSoldOut, EdmEvent: EdmEvent{ArtistName: "Gryffin", ClubName: "omnia", EventDate: "2026-11-09T22:00:00Z"}},
+	}
+	app.notifyPush(alerts)
+
+	laptopReceived := stub.received["/push/laptop"]
+	if len(laptopReceived) != 1 {
+		t.Fatalf("Expected 1 message on the laptop, got %d", len(laptopReceived))
+	}
+	expected := pushNotification{Title: "John Summit is coming to Las Vegas", Body: "xs nightclub, Friday, November 6, 2026 at 10:00 PM", URL: "/artists/john%20summit", Tag: "a1"}
+	if laptopReceived[0] != expected {
+		t.Errorf("Expected %+v, got %+v", expected, laptopReceived[0])
+	}
+
+	phoneReceived := stub.received["/push/phone"]
+	if len(phoneReceived) != 1 || phoneReceived[0].Title != "2 new Las Vegas dates from artists you follow" || phoneReceived[0].Body != "Tiësto, Kaskade" {
+		t.Errorf("Expected a single message about both dates on the phone, got %+v", phoneReceived)
+	}
+
+	if _, err := subscriptions.Get("expired"); err != errRecordNotFound {
+		t.Errorf("Expected the gone subscription to be deleted, got %v", err)
+	}
+
+	entries := app.dbSendLog.(*mockSendLogModel).entries
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 send log entries, got %+v", entries)
+	}
+	for _, entry := range entries {
+		if entry.Channel != channelPush {
+			t.Errorf("Expected the push channel, got '%s'", entry.Channel)
+		}
+		failed := entry.Recipient == "expired"
+		if failed != (entry.Status == sendStatusFailed) {
+			t.Errorf("Expected only the gone subscription to fail, got %+v", entry)
+		}
+	}
+}
+
+// TestPushSubscriptions_CRUD tests registering a browser, registering it again with new keys,
+// listing and deleting it
+func TestPushSubscriptions_CRUD(t *testing.T) {
+	app, apiKey, userID := newWatchlistsTestApplication(t)
+	app.push = newTestWebPusher(t, defaultPushServices...)
+
+	rr := adminRequest(t, app, http.MethodGet, "/v1/push/vapid-public-key", "", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	var vapid struct {
+		PublicKey string `json:"public_key"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &vapid); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decodeBase64URL(t, vapid.PublicKey), app.push.publicKey) {
+		t.Errorf("Expected the VAPID public key, got '%s'", vapid.PublicKey)
+	}
+
+	keys := newPushBrowser(t).keys()
+	body := `{"endpoint": "https://fcm.googleapis.com/fcm/send/abc", "expirationTime": null, "keys": {"p256dh": "` + keys.P256dh + `", "auth": "` + keys.Auth + `"}}`
+	rr = adminRequest(t, app, http.MethodPost, "/v1/push/subscriptions", apiKey, body)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	var created struct {
+		Subscription PushSubscription `json:"subscription"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if location := rr.Header().Get("Location"); location != "/v1/push/subscriptions/"+created.Subscription.ID {
+		t.Errorf("Expected Location '/v1/push/subscriptions/%s', got '%s'", created.Subscription.ID, location)
+	}
+	if strings.Contains(rr.Body.String(), keys.Auth) {
+		t.Errorf("Expected the keys not to be returned, got %s", rr.Body.String())
+	}
+
+	stored, _ := app.dbPushSubscriptions.Get(created.Subscription.ID)
+	if stored.UserID != userID || stored.Keys != keys {
+		t.Errorf("Expected the subscription stored for the key with its keys, got %+v", stored)
+	}
+
+	// The browser subscribing again replaces the keys of the same subscription.
+	keys = newPushBrowser(t).keys()
+	body = `{"endpoint": "https://fcm.googleapis.com/fcm/send/abc", "keys": {"p256dh": "` + keys.P256dh + `", "auth": "` + keys.Auth + `"}}`
+	rr = adminRequest(t, app, http.MethodPost, "/v1/push/subscriptions", apiKey, body)
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	rr = adminRequest(t, app, http.MethodGet, "/v1/push/subscriptions", apiKey, "")
+	var list struct {
+		Subscriptions []PushSubscription `json:"subscriptions"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Subscriptions) != 1 || list.Subscriptions[0].ID != created.Subscription.ID {
+		t.Errorf("Expected the one subscription, got %+v", list.Subscriptions)
+	}
+	if stored, _ := app.dbPushSubscriptions.Get(created.Subscription.ID); stored.Keys != keys {
+		t.Errorf("Expected the new keys, got %+v", stored.Keys)
+	}
+
+	rr = adminRequest(t, app, http.MethodDelete, "/v1/push/subscriptions/"+created.Subscription.ID, apiKey, "")
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	rr = adminRequest(t, app, http.MethodDelete, "/v1/push/subscriptions/"+created.Subscription.ID, apiKey, "")
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d after deleting, got %d", http.StatusNotFound, rr.Code)
+	}
+}
+
+// TestPushSubscriptions_Negative tests the rejected push subscription requests
+func TestPushSubscriptions_Negative(t *testing.T) {
+	app, apiKey, _ := newWatchlistsTestApplication(t)
+	app.push = newTestWebPusher(t, defaultPushServices...)
+	app.dbPushSubscriptions = &mockPushSubscriptionModel{subscriptions: []PushSubscription{
+		{ID: "other", UserID: "someone-else", Endpoint: "https://fcm.googleapis.com/fcm/send/other"},
+	}}
+
+	keys := newPushBrowser(t).keys()
+	subscription := func(endpoint, p256dh, auth string) string {
+		return `{"endpoint": "` + endpoint + `", "keys": {"p256dh": "` + p256dh + `", "auth": "` + auth + `"}}`
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		apiKey         string
+		body           string
+		expectedStatus int
+	}{
+		{name: "No API key", method: http.MethodGet, path: "/v1/push/subscriptions", expectedStatus: http.StatusUnauthorized},
+		{name: "Missing endpoint", method: http.MethodPost, path: "/v1/push/subscriptions", apiKey: apiKey, body: subscription("", keys.P256dh, keys.Auth), expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Other push service", method: http.MethodPost, path: "/v1/push/subscriptions", apiKey: apiKey, body: subscription("https://internal.example.com/push", keys.P256dh, keys.Auth), expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Wildcard service domain itself", method: http.MethodPost, path: "/v1/push/subscriptions", apiKey: apiKey, body: subscription("https://notify.windows.com/w", keys.P256dh, keys.Auth), expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Short p256dh", method: http.MethodPost, path: "/v1/push/subscriptions", apiKey: apiKey, body: subscription("https://fcm.googleapis.com/fcm/send/abc", keys.Auth, keys.Auth), expectedStatus: http.StatusUnprocessableEntity},
+		{name: "p256dh off the curve", method: http.MethodPost, path: "/v1/push/subscriptions", apiKey: apiKey, body: subscription("https://fcm.googleapis.com/fcm/send/abc", "B"+strings.Repeat("A", 86), keys.Auth), expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Invalid auth", method: http.MethodPost, path: "/v1/push/subscriptions", apiKey: apiKey, body: subscription("https://fcm.googleapis.com/fcm/send/abc", keys.P256dh, "not base64!"), expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Delete someone else's subscription", method: http.MethodDelete, path: "/v1/push/subscriptions/other", apiKey: apiKey, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := adminRequest(t, app, tt.method, tt.path, tt.apiKey, tt.body)
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	// Without a VAPID key there's nothing to subscribe with.
+	app.push = nil
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		path := "/v1/push/subscriptions"
+		if method == http.MethodGet {
+			path = "/v1/push/vapid-public-key"
+		}
+		rr := adminRequest(t, app, method, path, apiKey, subscription("https://fcm.googleapis.com/fcm/send/abc", keys.P256dh, keys.Auth))
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d for %s %s without push configured, got %d", http.StatusNotFound, method, path, rr.Code)
+		}
+	}
+}
+
+// TestVapidCommand tests generating a key pair and printing the public key of the configured one
+func TestVapidCommand(t *testing.T) {
+	var out bytes.Buffer
+	err := vapidCommand([]string{"generate"}, "", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "VAPID_PRIVATE_KEY=") || !strings.HasPrefix(lines[1], "VAPID_PUBLIC_KEY=") {
+		t.Fatalf("Expected both keys, got:\n%s", out.String())
+	}
+	privateKey := strings.TrimPrefix(lines[0], "VAPID_PRIVATE_KEY=")
+
+	out.Reset()
+	err = vapidCommand([]string{"public"}, privateKey, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(out.String()) != strings.TrimPrefix(lines[1], "VAPID_PUBLIC_KEY=") {
+		t.Errorf("Expected the public key of the generated pair, got '%s'", out.String())
+	}
+
+	for _, args := range [][]string{nil, {"rotate"}, {"public"}} {
+		if err := vapidCommand(args, "", &out); err == nil {
+			t.Errorf("Expected an error for %v", args)
+		}
+	}
+	if _, err := newWebPusher(privateKey, "ops@example.com", defaultPushServices); err == nil {
+		t.Errorf("Expected a subject that isn't a URL to be refused")
+	}
+}
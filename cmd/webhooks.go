@@ -0,0 +1,780 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The changes a webhook can subscribe to. Upcoming events that disappear from their venue's
+// listing are reported as cancelled, while the past ones the scrapers drop once their night
+// is over aren't reported at all.
+const (
+	webhookEventCreated   = "created"
+	webhookEventUpdated   = "updated"
+	webhookEventCancelled = "cancelled"
+)
+
+var webhookEventTypes = []string{webhookEventCreated, webhookEventUpdated, webhookEventCancelled}
+
+// The states of a delivery. A delivery that still fails after its last attempt is failed,
+// and stays in the webhook's dead-letter list until it's replayed successfully.
+const (
+	deliveryStatusPending   = "pending"
+	deliveryStatusDelivered = "delivered"
+	deliveryStatusFailed    = "failed"
+)
+
+var deliveryStatuses = []string{deliveryStatusPending, deliveryStatusDelivered, deliveryStatusFailed}
+
+const (
+	maxWebhooksPerUser     = 10
+	minWebhookSecretLength = 16
+	maxWebhookURLLength    = 2048
+	webhookSecretPrefix    = "whsec_"
+	webhookSignatureHeader = "X-Webhook-Signature"
+)
+
+var errDeliveryNotFailed = errors.New("only failed deliveries can be replayed")
+
+// Webhook subscribes a URL to the changes of a sync. Each delivery is signed with the secret,
+// which is only shown when the webhook is created.
+type Webhook struct {
+	ID        string   `json:"id"`
+	UserID    string   `json:"-"`
+	URL       string   `json:"url"`
+	Secret    string   `json:"-"`
+	Events    []string `json:"events"`
+	CreatedAt string   `json:"created_at"`
+}
+
+// WebhookDelivery is one payload sent, or being sent, to a webhook.
+type WebhookDelivery struct {
+	ID             string `json:"id"`
+	WebhookID      string `json:"webhook_id"`
+	UserID         string `json:"-"`
+	Payload        string `json:"-"`
+	Status         string `json:"status"`
+	Attempts       int    `json:"attempts"`
+	ResponseStatus int    `json:"response_status,omitempty"`
+	Error          string `json:"error,omitempty"`
+	CreatedAt      string `json:"created_at"`
+	UpdatedAt      string `json:"updated_at"`
+}
+
+// MarshalJSON includes the payload as JSON rather than as the string it's stored as.
+func (d WebhookDelivery) MarshalJSON() ([]byte, error) {
+	type delivery WebhookDelivery
+	return json.Marshal(struct {
+		delivery
+		Payload json.RawMessage `json:"payload"`
+	}{delivery(d), json.RawMessage(d.Payload)})
+}
+
+// webhookChange is a single change in a delivery's payload.
+type webhookChange struct {
+	Type     string   `json:"type"`
+	EdmEvent EdmEvent `json:"event"`
+}
+
+// webhookPayload is the body POSTed to a webhook.
+type webhookPayload struct {
+	ID        string          `json:"id"`
+	CreatedAt string          `json:"created_at"`
+	Changes   []webhookChange `json:"changes"`
+}
+
+type WebhookModelInterface interface {
+	Insert(webhook Webhook) error
+	Get(id string) (Webhook, error)
+	GetAll() ([]Webhook, error)
+	GetForUser(userID string) ([]Webhook, error)
+	Delete(id string) error
+}
+
+type WebhookDeliveryModelInterface interface {
+	Insert(delivery WebhookDelivery) error
+	Get(id string) (WebhookDelivery, error)
+	GetForWebhook(webhookID string) ([]WebhookDelivery, error)
+	Update(delivery WebhookDelivery) error
+}
+
+// WebhookModel stores one document per webhook, keyed by the webhook's id.
+type WebhookModel struct {
+	Client     *firestore.Client
+	Collection string
+}
+
+func (m *WebhookModel) Insert(webhook Webhook) error {
+	ctx := context.Background()
+	_, err := m.Client.Collection(m.Collection).Doc(webhook.ID).Create(ctx, webhook)
+	if err != nil {
+		return fmt.Errorf("failed to insert webhook: %v", err)
+	}
+	return nil
+}
+
+func (m *WebhookModel) Get(id string) (Webhook, error) {
+	ctx := context.Background()
+
+	doc, err := m.Client.Collection(m.Collection).Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return Webhook{}, errRecordNotFound
+	}
+	if err != nil {
+		return Webhook{}, fmt.Errorf("failed to get webhook %s: %v", id, err)
+	}
+
+	var webhook Webhook
+	if err := doc.DataTo(&webhook); err != nil {
+		return Webhook{}, fmt.Errorf("failed to decode webhook %s: %v", id, err)
+	}
+	return webhook, nil
+}
+
+func (m *WebhookModel) GetAll() ([]Webhook, error) {
+	return m.query(m.Client.Collection(m.Collection).Query)
+}
+
+func (m *WebhookModel) GetForUser(userID string) ([]Webhook, error) {
+	return m.query(m.Client.Collection(m.Collection).Where("UserID", "==", userID))
+}
+
+func (m *WebhookModel) query(q firestore.Query) ([]Webhook, error) {
+	ctx := context.Background()
+	webhooks := []Webhook{}
+
+	iter := q.Documents(ctx)
+	defer iter.Stop()
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate webhooks: %v", err)
+		}
+
+		var webhook Webhook
+		if err := doc.DataTo(&webhook); err != nil {
+			return nil, fmt.Errorf("failed to decode webhook %s: %v", doc.Ref.ID, err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].CreatedAt < webhooks[j].CreatedAt
+	})
+
+	return webhooks, nil
+}
+
+func (m *WebhookModel) Delete(id string) error {
+	ctx := context.Background()
+	_, err := m.Client.Collection(m.Collection).Doc(id).Delete(ctx, firestore.Exists)
+	if status.Code(err) == codes.NotFound {
+		return errRecordNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook %s: %v", id, err)
+	}
+	return nil
+}
+
+// WebhookDeliveryModel stores one document per delivery, keyed by the delivery's id.
+type WebhookDeliveryModel struct {
+	Client     *firestore.Client
+	Collection string
+}
+
+func (m *WebhookDeliveryModel) Insert(delivery WebhookDelivery) error {
+	ctx := context.Background()
+	_, err := m.Client.Collection(m.Collection).Doc(delivery.ID).Create(ctx, delivery)
+	if err != nil {
+		return fmt.Errorf("failed to insert webhook delivery: %v", err)
+	}
+	return nil
+}
+
+func (m *WebhookDeliveryModel) Get(id string) (WebhookDelivery, error) {
+	ctx := context.Background()
+
+	doc, err := m.Client.Collection(m.Collection).Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return WebhookDelivery{}, errRecordNotFound
+	}
+	if err != nil {
+		return WebhookDelivery{}, fmt.Errorf("failed to get webhook delivery %s: %v", id, err)
+	}
+
+	var delivery WebhookDelivery
+	if err := doc.DataTo(&delivery); err != nil {
+		return WebhookDelivery{}, fmt.Errorf("failed to decode webhook delivery %s: %v", id, err)
+	}
+	return delivery, nil
+}
+
+// GetForWebhook returns the deliveries of a webhook, the most recent first.
+func (m *WebhookDeliveryModel) GetForWebhook(webhookID string) ([]WebhookDelivery, error) {
+	ctx := context.Background()
+	deliveries := []WebhookDelivery{}
+
+	iter := m.Client.Collection(m.Collection).Where("WebhookID", "==", webhookID).Documents(ctx)
+	defer iter.Stop()
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate webhook deliveries: %v", err)
+		}
+
+		var delivery WebhookDelivery
+		if err := doc.DataTo(&delivery); err != nil {
+			return nil, fmt.Errorf("failed to decode webhook delivery %s: %v", doc.Ref.ID, err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	sortDeliveries(deliveries)
+
+	return deliveries, nil
+}
+
+func (m *WebhookDeliveryModel) Update(delivery WebhookDelivery) error {
+	ctx := context.Background()
+	_, err := m.Client.Collection(m.Collection).Doc(delivery.ID).Set(ctx, delivery)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery %s: %v", delivery.ID, err)
+	}
+	return nil
+}
+
+func sortDeliveries(deliveries []WebhookDelivery) {
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt > deliveries[j].CreatedAt
+	})
+}
+
+// webhookSender POSTs payloads to webhooks, retrying failed attempts with exponential
+// backoff.
+type webhookSender struct {
+	client      *http.Client
+	maxAttempts int
+	// retryDelay is doubled after every failed attempt.
+	retryDelay time.Duration
+}
+
+// newWebhookSender returns a sender that only connects to public addresses and doesn't follow
+// redirects, so that a webhook can't be used to reach the server's own network. The address is
+// checked when dialing rather than when the webhook is created, since its host can resolve to
+// another address by the time it's called.
+func newWebhookSender() *webhookSender {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: publicAddressesOnly}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &webhookSender{
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		maxAttempts: 5,
+		retryDelay:  time.Second,
+	}
+}
+
+var errPrivateWebhookAddress = errors.New("webhook address is not public")
+
+// isPublicAddress reports whether ip can be reached on the internet, as opposed to the
+// loopback, private and link-local addresses of the server's own network.
+func isPublicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsValid() &&
+		!ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified()
+}
+
+// publicAddressesOnly is a net.Dialer Control hook refusing to connect to addresses that
+// aren't public.
+func publicAddressesOnly(network string, address string, c syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !isPublicAddress(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", errPrivateWebhookAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+// signWebhookPayload returns the signature of a payload sent at the given unix time. The
+// timestamp is signed along with the body so that a captured request can't be replayed later
+// with a new timestamp.
+func signWebhookPayload(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// attempt POSTs the delivery's payload once and returns the status the webhook answered with.
+// Anything but a 2xx response is an error.
+func (s *webhookSender) attempt(webhook Webhook, delivery WebhookDelivery, now time.Time) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "edm-events-webhooks/"+version)
+	req.Header.Set("X-Webhook-ID", delivery.ID)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set(webhookSignatureHeader, signWebhookPayload(webhook.Secret, timestamp, body))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("webhook responded with %s", res.Status)
+	}
+	return res.StatusCode, nil
+}
+
+// deliver makes the given number of attempts at sending the delivery and returns it updated
+// with the outcome.
+func (s *webhookSender) deliver(webhook Webhook, delivery WebhookDelivery, attempts int) WebhookDelivery {
+	delay := s.retryDelay
+	for i := 1; ; i++ {
+		responseStatus, err := s.attempt(webhook, delivery, time.Now())
+
+		delivery.Attempts++
+		delivery.ResponseStatus = responseStatus
+		delivery.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+		if err == nil {
+			delivery.Status = deliveryStatusDelivered
+			delivery.Error = ""
+			return delivery
+		}
+
+		delivery.Status = deliveryStatusFailed
+		delivery.Error = err.Error()
+		if i == attempts {
+			return delivery
+		}
+
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// webhookChanges lists the changes of a sync the webhook subscribed to. Removed events are
+// only cancelled if their night is today or later in Las Vegas, the others took place.
+func webhookChanges(webhook Webhook, changes syncChanges, today string) []webhookChange {
+	webhookChanges := []webhookChange{}
+	for _, group := range []struct {
+		eventType string
+		edmEvents []EdmEvent
+	}{
+		{webhookEventCreated, changes.Created},
+		{webhookEventUpdated, changes.Updated},
+		{webhookEventCancelled, changes.Removed},
+	} {
+		if !slices.Contains(webhook.Events, group.eventType) {
+			continue
+		}
+		for _, edmEvent := range group.edmEvents {
+			if group.eventType == webhookEventCancelled && eventNight(edmEvent) < today {
+				continue
+			}
+			webhookChanges = append(webhookChanges, webhookChange{Type: group.eventType, EdmEvent: edmEvent})
+		}
+	}
+	return webhookChanges
+}
+
+// notifyWebhooks sends every webhook the changes of a sync it subscribed to, in a single
+// delivery. Webhooks are called concurrently so that a slow one doesn't hold up the others,
+// and the deliveries that still fail after their retries are left in the dead-letter list.
+// A delivery that can't be stored is skipped and its error returned once the other webhooks
+// have been called.
+func (app *application) notifyWebhooks(changes syncChanges) error {
+	if len(changes.Created)+len(changes.Updated)+len(changes.Removed) == 0 {
+		return nil
+	}
+
+	webhooks, err := app.dbWebhooks.GetAll()
+	if err != nil {
+		return err
+	}
+
+	today := lasVegasToday(time.Now())
+
+	var errs []error
+	var wg sync.WaitGroup
+	for _, webhook := range webhooks {
+		webhookChanges := webhookChanges(webhook, changes, today)
+		if len(webhookChanges) == 0 {
+			continue
+		}
+
+		now := time.Now().UTC().Format(time.RFC3339)
+		delivery := WebhookDelivery{
+			ID:        getGUID(),
+			WebhookID: webhook.ID,
+			UserID:    webhook.UserID,
+			Status:    deliveryStatusPending,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+
+		payload, err := json.Marshal(webhookPayload{ID: delivery.ID, CreatedAt: now, Changes: webhookChanges})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %w", webhook.ID, err))
+			continue
+		}
+		delivery.Payload = string(payload)
+
+		err = app.dbWebhookDeliveries.Insert(delivery)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %w", webhook.ID, err))
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			delivery := app.webhooks.deliver(webhook, delivery, app.webhooks.maxAttempts)
+			if delivery.Status == deliveryStatusFailed {
+				app.logger.Printf("webhook %s failed after %d attempts, delivery %s dead-lettered: %s", webhook.ID, delivery.Attempts, delivery.ID, delivery.Error)
+			}
+
+			err := app.dbWebhookDeliveries.Update(delivery)
+			if err != nil {
+				app.logger.Printf("failed to record delivery %s: %v", delivery.ID, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// generateWebhookSecret returns a random secret for webhooks created without one.
+func generateWebhookSecret() (string, error) {
+	randomBytes := make([]byte, 24)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + hex.EncodeToString(randomBytes), nil
+}
+
+func validateWebhookURL(v *validator, rawURL string) {
+	v.Check(rawURL != "", "url", "must be provided")
+	v.Check(len(rawURL) <= maxWebhookURLLength, "url", fmt.Sprintf("must not be more than %d bytes long", maxWebhookURLLength))
+
+	if rawURL != "" {
+		u, err := url.Parse(rawURL)
+		v.Check(err == nil && u.Scheme == "https" && u.Host != "", "url", "must be an absolute https URL")
+		if err == nil {
+			ip, err := netip.ParseAddr(u.Hostname())
+			v.Check(err != nil || isPublicAddress(ip), "url", "must not point to a private address")
+			v.Check(!strings.EqualFold(u.Hostname(), "localhost"), "url", "must not point to a private address")
+		}
+	}
+}
+
+// userWebhook returns the webhook with the id of the request path if it belongs to the user.
+// Other users' webhooks are reported as not found.
+func (app *application) userWebhook(r *http.Request, userID string) (Webhook, error) {
+	webhook, err := app.dbWebhooks.Get(r.PathValue("id"))
+	if err != nil {
+		return Webhook{}, err
+	}
+	if webhook.UserID != userID {
+		return Webhook{}, errRecordNotFound
+	}
+	return webhook, nil
+}
+
+func (app *application) listWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	apiKey, _ := app.contextGetAPIKey(r)
+
+	webhooks, err := app.dbWebhooks.GetForUser(apiKey.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"webhooks": webhooks}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createWebhookHandler subscribes a URL to the changes of future syncs. Without a secret one
+// is generated, either way it's only returned in this response.
+func (app *application) createWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	apiKey, _ := app.contextGetAPIKey(r)
+
+	var input struct {
+		URL    string   `json:"url"`
+		Secret string   `json:"secret"`
+		Events []string `json:"events"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	input.URL = strings.TrimSpace(input.URL)
+	if input.Events == nil {
+		input.Events = webhookEventTypes
+	}
+
+	v := newValidator()
+	validateWebhookURL(v, input.URL)
+	v.Check(input.Secret == "" || len(input.Secret) >= minWebhookSecretLength, "secret", fmt.Sprintf("must be at least %d bytes long", minWebhookSecretLength))
+	v.Check(len(input.Events) > 0, "events", "must contain at least one event type")
+	for _, eventType := range input.Events {
+		v.Check(slices.Contains(webhookEventTypes, eventType), "events", "must only contain "+strings.Join(webhookEventTypes, ", "))
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	existing, err := app.dbWebhooks.GetForUser(apiKey.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if len(existing) >= maxWebhooksPerUser {
+		v.AddError("webhooks", fmt.Sprintf("must not be more than %d per user", maxWebhooksPerUser))
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if input.Secret == "" {
+		input.Secret, err = generateWebhookSecret()
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	// Storing the event types in a fixed order also drops duplicates.
+	webhook := Webhook{
+		ID:        getGUID(),
+		UserID:    apiKey.ID,
+		URL:       input.URL,
+		Secret:    input.Secret,
+		Events:    []string{},
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	for _, eventType := range webhookEventTypes {
+		if slices.Contains(input.Events, eventType) {
+			webhook.Events = append(webhook.Events, eventType)
+		}
+	}
+
+	err = app.dbWebhooks.Insert(webhook)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", "/v1/webhooks/"+webhook.ID)
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"webhook": webhook, "secret": webhook.Secret}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	apiKey, _ := app.contextGetAPIKey(r)
+
+	webhook, err := app.userWebhook(r, apiKey.ID)
+	if err != nil {
+		if errors.Is(err, errRecordNotFound) {
+			app.notFoundResponse(w, r)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"webhook": webhook}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	apiKey, _ := app.contextGetAPIKey(r)
+
+	webhook, err := app.userWebhook(r, apiKey.ID)
+	if err == nil {
+		err = app.dbWebhooks.Delete(webhook.ID)
+	}
+	if err != nil {
+		if errors.Is(err, errRecordNotFound) {
+			app.notFoundResponse(w, r)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "webhook successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listWebhookDeliveriesHandler returns the deliveries of a webhook, the most recent first.
+// ?status=failed lists its dead letters.
+func (app *application) listWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	apiKey, _ := app.contextGetAPIKey(r)
+
+	qs := r.URL.Query()
+	v := newValidator()
+
+	deliveryStatus := app.readString(qs, "status", "")
+	v.Check(deliveryStatus == "" || slices.Contains(deliveryStatuses, deliveryStatus), "status", "must be one of "+strings.Join(deliveryStatuses, ", "))
+
+	filters := EventFilters{
+		Page:     app.readInt(qs, "page", 1, v),
+		PageSize: app.readInt(qs, "page_size", 50, v),
+	}
+	validateEventFilters(v, filters)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	webhook, err := app.userWebhook(r, apiKey.ID)
+	if err != nil {
+		if errors.Is(err, errRecordNotFound) {
+			app.notFoundResponse(w, r)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	deliveries, err := app.dbWebhookDeliveries.GetForWebhook(webhook.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if deliveryStatus != "" {
+		deliveries = slices.DeleteFunc(deliveries, func(d WebhookDelivery) bool {
+			return d.Status != deliveryStatus
+		})
+	}
+
+	deliveries, metadata := paginateItems(filters, deliveries)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"deliveries": deliveries, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// replayWebhookDeliveryHandler sends a failed delivery again, once, and returns it with the
+// outcome. The payload is sent as it was first built, signed with a new timestamp.
+func (app *application) replayWebhookDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	apiKey, _ := app.contextGetAPIKey(r)
+
+	webhook, err := app.userWebhook(r, apiKey.ID)
+	if err != nil {
+		if errors.Is(err, errRecordNotFound) {
+			app.notFoundResponse(w, r)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	delivery, err := app.dbWebhookDeliveries.Get(r.PathValue("delivery"))
+	if err == nil && delivery.WebhookID != webhook.ID {
+		err = errRecordNotFound
+	}
+	if err != nil {
+		if errors.Is(err, errRecordNotFound) {
+			app.notFoundResponse(w, r)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if delivery.Status != deliveryStatusFailed {
+		app.errorResponse(w, r, http.StatusConflict, errDeliveryNotFailed.Error())
+		return
+	}
+
+	delivery = app.webhooks.deliver(webhook, delivery, 1)
+
+	err = app.dbWebhookDeliveries.Update(delivery)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"delivery": delivery}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,484 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver is a test endpoint recording the requests it gets. The first failures
+// requests are answered with a 500.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+	failures int
+}
+
+func (wr *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	wr.requests = append(wr.requests, r)
+	wr.bodies = append(wr.bodies, body)
+
+	if len(wr.requests) <= wr.failures {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// TestSignWebhookPayload tests that signatures cover both the timestamp and the body
+func TestSignWebhookPayload(t *testing.T) {
+	signature := signWebhookPayload("whsec_test", "1700000000", []byte(`{"id":"d1"}`))
+
+	mac := hmac.New(sha256.New, []byte("whsec_test"))
+	mac.Write([]byte(`1700000000.{"id":"d1"}`))
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if signature != expected {
+		t.Errorf("Expected signature '%s', got '%s'", expected, signature)
+	}
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      string
+	}{
+		{name: "Other secret", secret: "whsec_other", timestamp: "1700000000", body: `{"id":"d1"}`},
+		{name: "Other timestamp", secret: "whsec_test", timestamp: "1700000001", body: `{"id":"d1"}`},
+		{name: "Other body", secret: "whsec_test", timestamp: "1700000000", body: `{"id":"d2"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := signWebhookPayload(tt.secret, tt.timestamp, []byte(tt.body)); got == signature {
+				t.Errorf("Expected a different signature, got the same '%s'", got)
+			}
+		})
+	}
+}
+
+// TestNotifyWebhooks tests the signed deliveries sent after a sync, filtered by the event
+// types each webhook subscribed to
+func TestNotifyWebhooks(t *testing.T) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	app := newTestApplication(t, nil)
+	app.dbWebhooks = &mockWebhookModel{webhooks: []Webhook{
+		{ID: "w1", UserID: "u1", URL: server.URL + "/all", Secret: "whsec_all", Events: webhookEventTypes},
+		{ID: "w2", UserID: "u2", URL: server.URL + "/cancelled", Secret: "whsec_cancelled", Events: []string{webhookEventCancelled}},
+		{ID: "w3", UserID: "u3", URL: server.URL + "/updated", Secret: "whsec_updated", Events: []string{webhookEventUpdated}},
+	}}
+
+	// Yesterday's show took place rather than being cancelled.
+	today := time.Now()
+	changes := syncChanges{
+		Created: []EdmEvent{{Id: "e1", ArtistName: "Kygo"}},
+		Removed: []EdmEvent{
+			{Id: "e2", ArtistName: "Tiësto", EventDate: today.AddDate(0, 0, 7).Format(queryDateFormat) + "T00:00:00Z"},
+			{Id: "e3", ArtistName: "Alesso", EventDate: today.AddDate(0, 0, -2).Format(queryDateFormat) + "T00:00:00Z"},
+		},
+	}
+
+	err := app.notifyWebhooks(changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(receiver.requests) != 2 {
+		t.Fatalf("Expected 2 requests, the webhook of updates has nothing to receive, got %d", len(receiver.requests))
+	}
+
+	secrets := map[string]string{"/all": "whsec_all", "/cancelled": "whsec_cancelled"}
+	expectedTypes := map[string][]string{"/all": {"created", "cancelled"}, "/cancelled": {"cancelled"}}
+
+	for i, r := range receiver.requests {
+		body := receiver.bodies[i]
+
+		timestamp := r.Header.Get("X-Webhook-Timestamp")
+		if _, err := strconv.ParseInt(timestamp, 10, 64); err != nil {
+			t.Errorf("Expected a unix timestamp, got '%s'", timestamp)
+		}
+		expectedSignature := signWebhookPayload(secrets[r.URL.Path], timestamp, body)
+		if got := r.Header.Get(webhookSignatureHeader); got != expectedSignature {
+			t.Errorf("Expected signature '%s' for %s, got '%s'", expectedSignature, r.URL.Path, got)
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("Expected Content-Type 'application/json', got '%s'", got)
+		}
+
+		var payload webhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Fatal(err)
+		}
+		if payload.ID != r.Header.Get("X-Webhook-ID") {
+			t.Errorf("Expected the payload id to be the X-Webhook-ID header '%s', got '%s'", r.Header.Get("X-Webhook-ID"), payload.ID)
+		}
+
+		types := []string{}
+		for _, change := range payload.Changes {
+			types = append(types, change.Type)
+			if change.EdmEvent.Id == "e3" {
+				t.Errorf("Expected the past event to be left out of %s", r.URL.Path)
+			}
+		}
+		if len(types) != len(expectedTypes[r.URL.Path]) {
+			t.Errorf("Expected changes %v for %s, got %v", expectedTypes[r.URL.Path], r.URL.Path, types)
+		}
+	}
+
+	deliveries := app.dbWebhookDeliveries.(*mockWebhookDeliveryModel).deliveries
+	if len(deliveries) != 2 {
+		t.Fatalf("Expected 2 deliveries recorded, got %d", len(deliveries))
+	}
+	for _, delivery := range deliveries {
+		if delivery.Status != deliveryStatusDelivered || delivery.Attempts != 1 || delivery.ResponseStatus != http.StatusOK {
+			t.Errorf("Expected delivery %s delivered at the first attempt, got status '%s' after %d attempts", delivery.WebhookID, delivery.Status, delivery.Attempts)
+		}
+	}
+
+	// A sync that changed nothing sends nothing.
+	err = app.notifyWebhooks(syncChanges{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(receiver.requests) != 2 {
+		t.Errorf("Expected no more requests, got %d", len(receiver.requests)-2)
+	}
+}
+
+// TestNotifyWebhooks_Retries tests that failed deliveries are retried and dead-lettered once
+// every attempt failed
+func TestNotifyWebhooks_Retries(t *testing.T) {
+	tests := []struct {
+		name             string
+		failures         int
+		expectedStatus   string
+		expectedAttempts int
+	}{
+		{name: "Recovers", failures: 2, expectedStatus: deliveryStatusDelivered, expectedAttempts: 3},
+		{name: "Dead-lettered", failures: 5, expectedStatus: deliveryStatusFailed, expectedAttempts: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := &webhookReceiver{failures: tt.failures}
+			server := httptest.NewServer(receiver)
+			defer server.Close()
+
+			app := newTestApplication(t, nil)
+			app.webhooks.retryDelay = time.Millisecond
+			app.dbWebhooks = &mockWebhookModel{webhooks: []Webhook{
+				{ID: "w1", UserID: "u1", URL: server.URL, Secret: "whsec_test", Events: webhookEventTypes},
+			}}
+
+			err := app.notifyWebhooks(syncChanges{Updated: []EdmEvent{{Id: "e1"}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			delivery := app.dbWebhookDeliveries.(*mockWebhookDeliveryModel).deliveries[0]
+			if delivery.Status != tt.expectedStatus {
+				t.Errorf("Expected status '%s', got '%s'", tt.expectedStatus, delivery.Status)
+			}
+			if delivery.Attempts != tt.expectedAttempts {
+				t.Errorf("Expected %d attempts, got %d", tt.expectedAttempts, delivery.Attempts)
+			}
+			if tt.expectedStatus == deliveryStatusFailed && delivery.Error == "" {
+				t.Errorf("Expected the last error to be recorded")
+			}
+		})
+	}
+}
+
+// TestNotifyWebhooks_InsertFails tests that a delivery that can't be stored doesn't stop the
+// other webhooks from being called, and that its error is returned once they have been
+func TestNotifyWebhooks_InsertFails(t *testing.T) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	app := newTestApplication(t, nil)
+	app.dbWebhooks = &mockWebhookModel{webhooks: []Webhook{
+		{ID: "w1", UserID: "u1", URL: server.URL, Secret: "whsec_test", Events: webhookEventTypes},
+		{ID: "w2", UserID: "u1", URL: server.URL, Secret: "whsec_test", Events: webhookEventTypes},
+	}}
+	insertErr := errors.New("firestore unavailable")
+	app.dbWebhookDeliveries = &mockWebhookDeliveryModel{insertErrs: map[string]error{"w1": insertErr}}
+
+	err := app.notifyWebhooks(syncChanges{Created: []EdmEvent{{Id: "e1"}}})
+	if !errors.Is(err, insertErr) {
+		t.Errorf("Expected the insert error, got %v", err)
+	}
+
+	if len(receiver.requests) != 1 {
+		t.Fatalf("Expected 1 request, got %d", len(receiver.requests))
+	}
+	deliveries := app.dbWebhookDeliveries.(*mockWebhookDeliveryModel).deliveries
+	if len(deliveries) != 1 || deliveries[0].WebhookID != "w2" || deliveries[0].Status != deliveryStatusDelivered {
+		t.Errorf("Expected w2's delivery to be delivered, got %+v", deliveries)
+	}
+}
+
+// TestWebhooks_CRUD tests creating, listing, showing and deleting a webhook
+func TestWebhooks_CRUD(t *testing.T) {
+	app, apiKey, userID := newWatchlistsTestApplication(t)
+
+	rr := adminRequest(t, app, http.MethodPost, "/v1/webhooks", apiKey, `{"url": " https://partner.example.com/hooks ", "events": ["cancelled", "created", "cancelled"]}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	var created struct {
+		Webhook Webhook `json:"webhook"`
+		Secret  string  `json:"secret"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.Webhook.URL != "https://partner.example.com/hooks" {
+		t.Errorf("Expected the trimmed URL, got '%s'", created.Webhook.URL)
+	}
+	if len(created.Webhook.Events) != 2 || created.Webhook.Events[0] != "created" || created.Webhook.Events[1] != "cancelled" {
+		t.Errorf("Expected events [created cancelled], got %v", created.Webhook.Events)
+	}
+	if len(created.Secret) <= len(webhookSecretPrefix) {
+		t.Errorf("Expected a generated secret, got '%s'", created.Secret)
+	}
+	if location := rr.Header().Get("Location"); location != "/v1/webhooks/"+created.Webhook.ID {
+		t.Errorf("Expected Location '/v1/webhooks/%s', got '%s'", created.Webhook.ID, location)
+	}
+
+	stored, _ := app.dbWebhooks.Get(created.Webhook.ID)
+	if stored.UserID != userID || stored.Secret != created.Secret {
+		t.Errorf("Expected the webhook stored for the key with its secret, got %+v", stored)
+	}
+
+	rr = adminRequest(t, app, http.MethodGet, "/v1/webhooks/"+created.Webhook.ID, apiKey, "")
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	if body := rr.Body.String(); strings.Contains(body, created.Secret) {
+		t.Errorf("Expected the secret to only be shown on creation, got %s", body)
+	}
+
+	rr = adminRequest(t, app, http.MethodGet, "/v1/webhooks", apiKey, "")
+	var list struct {
+		Webhooks []Webhook `json:"webhooks"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Webhooks) != 1 {
+		t.Errorf("Expected 1 webhook, got %d", len(list.Webhooks))
+	}
+
+	rr = adminRequest(t, app, http.MethodDelete, "/v1/webhooks/"+created.Webhook.ID, apiKey, "")
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	rr = adminRequest(t, app, http.MethodGet, "/v1/webhooks/"+created.Webhook.ID, apiKey, "")
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d after deleting, got %d", http.StatusNotFound, rr.Code)
+	}
+}
+
+// TestWebhooks_Negative tests the rejected webhook requests
+func TestWebhooks_Negative(t *testing.T) {
+	app, apiKey, _ := newWatchlistsTestApplication(t)
+	app.dbWebhooks = &mockWebhookModel{webhooks: []Webhook{
+		{ID: "other", UserID: "someone-else", URL: "https://other.example.com", Events: webhookEventTypes},
+	}}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		apiKey         string
+		body           string
+		expectedStatus int
+	}{
+		{name: "No API key", method: http.MethodGet, path: "/v1/webhooks", expectedStatus: http.StatusUnauthorized},
+		{name: "Missing URL", method: http.MethodPost, path: "/v1/webhooks", apiKey: apiKey, body: `{}`, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Relative URL", method: http.MethodPost, path: "/v1/webhooks", apiKey: apiKey, body: `{"url": "/hooks"}`, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Other scheme", method: http.MethodPost, path: "/v1/webhooks", apiKey: apiKey, body: `{"url": "ftp://example.com"}`, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Plain http", method: http.MethodPost, path: "/v1/webhooks", apiKey: apiKey, body: `{"url": "http://example.com"}`, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Localhost", method: http.MethodPost, path: "/v1/webhooks", apiKey: apiKey, body: `{"url": "https://localhost:8080/hooks"}`, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Private address", method: http.MethodPost, path: "/v1/webhooks", apiKey: apiKey, body: `{"url": "https://10.0.0.5/hooks"}`, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Metadata address", method: http.MethodPost, path: "/v1/webhooks", apiKey: apiKey, body: `{"url": "https://169.254.169.254/latest/meta-data"}`, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "IPv6 loopback", method: http.MethodPost, path: "/v1/webhooks", apiKey: apiKey, body: `{"url": "https://[::1]/hooks"}`, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Short secret", method: http.MethodPost, path: "/v1/webhooks", apiKey: apiKey, body: `{"url": "https://example.com", "secret": "short"}`, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Unknown event type", method: http.MethodPost, path: "/v1/webhooks", apiKey: apiKey, body: `{"url": "https://example.com", "events": ["deleted"]}`, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "No event types", method: http.MethodPost, path: "/v1/webhooks", apiKey: apiKey, body: `{"url": "https://example.com", "events": []}`, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Someone else's webhook", method: http.MethodGet, path: "/v1/webhooks/other", apiKey: apiKey, expectedStatus: http.StatusNotFound},
+		{name: "Delete someone else's webhook", method: http.MethodDelete, path: "/v1/webhooks/other", apiKey: apiKey, expectedStatus: http.StatusNotFound},
+		{name: "Someone else's deliveries", method: http.MethodGet, path: "/v1/webhooks/other/deliveries", apiKey: apiKey, expectedStatus: http.StatusNotFound},
+		{name: "Invalid delivery status", method: http.MethodGet, path: "/v1/webhooks/other/deliveries?status=lost", apiKey: apiKey, expectedStatus: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := adminRequest(t, app, tt.method, tt.path, tt.apiKey, tt.body)
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+// TestReplayWebhookDeliveryHandler tests listing the dead letters of a webhook and replaying
+// them
+func TestReplayWebhookDeliveryHandler(t *testing.T) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	app, apiKey, userID := newWatchlistsTestApplication(t)
+	app.dbWebhooks = &mockWebhookModel{webhooks: []Webhook{
+		{ID: "w1", UserID: userID, URL: server.URL, Secret: "whsec_test", Events: webhookEventTypes},
+	}}
+	app.dbWebhookDeliveries = &mockWebhookDeliveryModel{deliveries: []WebhookDelivery{
+		{ID: "d1", WebhookID: "w1", UserID: userID, Payload: `{"id":"d1","changes":[]}`, Status: deliveryStatusFailed, Attempts: 5, Error: "webhook responded with 503 Service Unavailable", CreatedAt: "2026-10-01T00:00:00Z"},
+		{ID: "d2", WebhookID: "w1", UserID: userID, Payload: `{"id":"d2","changes":[]}`, Status: deliveryStatusDelivered, Attempts: 1, CreatedAt: "2026-10-02T00:00:00Z"},
+	}}
+
+	rr := adminRequest(t, app, http.MethodGet, "/v1/webhooks/w1/deliveries?status=failed", apiKey, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	var list struct {
+		Deliveries []struct {
+			ID      string          `json:"id"`
+			Payload json.RawMessage `json:"payload"`
+		} `json:"deliveries"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Deliveries) != 1 || list.Deliveries[0].ID != "d1" {
+		t.Fatalf("Expected the dead letter d1, got %+v", list.Deliveries)
+	}
+	if string(list.Deliveries[0].Payload) != `{"id":"d1","changes":[]}` {
+		t.Errorf("Expected the payload as JSON, got %s", list.Deliveries[0].Payload)
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{name: "Replay", path: "/v1/webhooks/w1/deliveries/d1/replay", expectedStatus: http.StatusOK},
+		{name: "Already delivered", path: "/v1/webhooks/w1/deliveries/d2/replay", expectedStatus: http.StatusConflict},
+		{name: "Unknown delivery", path: "/v1/webhooks/w1/deliveries/missing/replay", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := adminRequest(t, app, http.MethodPost, tt.path, apiKey, "")
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	if len(receiver.requests) != 1 {
+		t.Fatalf("Expected 1 replayed request, got %d", len(receiver.requests))
+	}
+	if string(receiver.bodies[0]) != `{"id":"d1","changes":[]}` {
+		t.Errorf("Expected the original payload to be replayed, got %s", receiver.bodies[0])
+	}
+
+	delivery, _ := app.dbWebhookDeliveries.Get("d1")
+	if delivery.Status != deliveryStatusDelivered || delivery.Attempts != 6 || delivery.Error != "" {
+		t.Errorf("Expected d1 delivered at its 6th attempt, got status '%s' after %d attempts with error '%s'", delivery.Status, delivery.Attempts, delivery.Error)
+	}
+}
+
+// TestIsPublicAddress tests which addresses webhooks may connect to
+func TestIsPublicAddress(t *testing.T) {
+	tests := []struct {
+		address  string
+		expected bool
+	}{
+		{address: "93.184.216.34", expected: true},
+		{address: "2606:2800:220:1:248:1893:25c8:1946", expected: true},
+		{address: "127.0.0.1", expected: false},
+		{address: "::1", expected: false},
+		{address: "10.1.2.3", expected: false},
+		{address: "172.16.0.1", expected: false},
+		{address: "192.168.1.1", expected: false},
+		{address: "169.254.169.254", expected: false},
+		{address: "fe80::1", expected: false},
+		{address: "fd00::1", expected: false},
+		{address: "0.0.0.0", expected: false},
+		{address: "::ffff:127.0.0.1", expected: false},
+		{address: "224.0.0.1", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			got := isPublicAddress(netip.MustParseAddr(tt.address))
+			if got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestWebhookSender_RefusesPrivateAddresses tests that the sender doesn't connect to a webhook
+// whose host resolves to a private address
+func TestWebhookSender_RefusesPrivateAddresses(t *testing.T) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	sender := newWebhookSender()
+	_, err := sender.attempt(Webhook{ID: "w1", URL: server.URL, Secret: "whsec_test"}, WebhookDelivery{ID: "d1", Payload: "{}"}, time.Now())
+	if !errors.Is(err, errPrivateWebhookAddress) {
+		t.Errorf("Expected errPrivateWebhookAddress, got %v", err)
+	}
+	if len(receiver.requests) != 0 {
+		t.Errorf("Expected no request, got %d", len(receiver.requests))
+	}
+}
+
+// TestWebhookSender_RefusesRedirects tests that a redirect is a failed attempt rather than
+// followed
+func TestWebhookSender_RefusesRedirects(t *testing.T) {
+	receiver := &webhookReceiver{}
+	target := httptest.NewServer(receiver)
+	defer target.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer redirect.Close()
+
+	// The default transport lets the test reach its loopback servers.
+	sender := newWebhookSender()
+	sender.client.Transport = nil
+
+	responseStatus, err := sender.attempt(Webhook{ID: "w1", URL: redirect.URL, Secret: "whsec_test"}, WebhookDelivery{ID: "d1", Payload: "{}"}, time.Now())
+	if err == nil {
+		t.Errorf("Expected an error")
+	}
+	if responseStatus != http.StatusTemporaryRedirect {
+		t.Errorf("Expected status %d, got %d", http.StatusTemporaryRedirect, responseStatus)
+	}
+	if len(receiver.requests) != 0 {
+		t.Errorf("Expected the redirect not to be followed, got %d requests", len(receiver.requests))
+	}
+}
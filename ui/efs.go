@@ -0,0 +1,9 @@
+package ui
+
+import "embed"
+
+// Files holds the HTML templates, email templates and static assets, so the binary can be
+// deployed on its own.
+//
+//go:embed "html" "email" "static"
+var Files embed.FS